@@ -1,20 +1,17 @@
 package bootstrap
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
-	"github.com/pkg/errors"
 	"github.com/rocketsoftware/open-web-launch/launcher"
-	"github.com/rocketsoftware/open-web-launch/messaging"
 	"github.com/rocketsoftware/open-web-launch/settings"
 	"github.com/rocketsoftware/open-web-launch/utils"
+	"github.com/rocketsoftware/open-web-launch/utils/download"
 	"github.com/rocketsoftware/open-web-launch/utils/log"
 )
 
@@ -25,6 +22,12 @@ var (
 	disableVerificationSameOrigin bool
 	uninstall                     bool
 	showGUI                       bool
+	codebase                      string
+	openFile                      string
+	printFile                     string
+	offline                       bool
+	register                      bool
+	unregister                    bool
 )
 
 var helpOptions = []string{"-help", "--help", "/help", "-?", "/?"}
@@ -51,8 +54,12 @@ func Run(productName, productTitle, productVersion string) {
 	if err := utils.CreateProductWorkDir(userConfigDir); err != nil {
 		log.Fatal(err)
 	}
+	settings.SetConfigDir(userConfigDir)
 
 	productWorkDir := filepath.Join(userConfigDir, "cache")
+	if settings.CacheDir() != "" {
+		productWorkDir = settings.CacheDir()
+	}
 	if err := utils.CreateProductWorkDir(productWorkDir); err != nil {
 		log.Fatal(err)
 	}
@@ -80,18 +87,55 @@ func Run(productName, productTitle, productVersion string) {
 		os.Setenv("HTTPS_PROXY", "")
 		os.Setenv("NO_PROXY", "")
 	}
-	flag.BoolVar(&showConsole, "showconsole", false, "show Java console")
-	flag.BoolVar(&showConsole, "showConsole", false, "show Java console")
-	flag.StringVar(&javaDir, "javadir", "", "Java folder that should be used for starting a Java Web Start application")
-	flag.StringVar(&javaDir, "javaDir", "", "Java folder that should be used for starting a Java Web Start application")
-	flag.BoolVar(&disableVerification, "disableverification", false, "don't verify jar signatures")
-	flag.BoolVar(&disableVerification, "disableVerification", false, "don't verify jar signatures")
-	flag.BoolVar(&disableVerificationSameOrigin, "disableverificationsameorigin", false, "don't verify all jars have same signature")
-	flag.BoolVar(&disableVerificationSameOrigin, "disableVerificationSameOrigin", false, "don't verify all jars have same signature")
+	proxyConfig := &download.ProxyConfig{Mode: download.ProxyMode(settings.ProxyMode()), AutoConfig: settings.ProxyAutoConfig()}
+	proxyConfig.HTTPProxy, proxyConfig.HTTPSProxy, proxyConfig.NoProxy = settings.Proxies()
+	log.Printf("proxy mode is %s, configured proxies are HttpProxy=%s HttpsProxy=%s NoProxy=%s ProxyAutoConfig=%s\n",
+		proxyConfig.Mode, proxyConfig.HTTPProxy, proxyConfig.HTTPSProxy, proxyConfig.NoProxy, proxyConfig.AutoConfig,
+	)
+	if err := download.SetProxy(proxyConfig); err != nil {
+		log.Printf("warning: invalid proxy settings, connecting directly: %v", err)
+		download.SetProxy(&download.ProxyConfig{Mode: download.ProxyNone})
+	}
+	download.SetCredentialsFiles(settings.CredentialsFile(), download.NetrcFile())
+	download.SetInsecureAuthenticationAllowed(settings.AllowInsecureAuthentication())
+	if filename := settings.ClientCertificatesFile(); filename != "" {
+		if err := download.LoadClientCertificates(filename); err != nil {
+			log.Printf("warning: client certificates are not used: %v", err)
+		}
+	}
+	if cmd := findCommand(os.Args[1]); cmd != nil {
+		env := &environment{
+			productTitle:   productTitle,
+			productVersion: productVersion,
+			productWorkDir: productWorkDir,
+			productLogFile: productLogFile,
+		}
+		if err := cmd.run(cmd, env, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if isJavawsCommandLine(os.Args[0], os.Args[1:]) {
+		runJavaws(os.Args[1:], productWorkDir, productTitle, productLogFile)
+		return
+	}
+	defineLaunchFlags(flag.CommandLine)
 	flag.BoolVar(&uninstall, "uninstall", false, "uninstall a specific Java Web Start application")
 	flag.BoolVar(&showGUI, "gui", false, "show GUI")
+	flag.BoolVar(&register, "register", false, "register as a handler of jnlp:// and jnlps:// URLs and JNLP files")
+	flag.BoolVar(&unregister, "unregister", false, "unregister as a handler of jnlp:// and jnlps:// URLs and JNLP files")
 	flag.Usage = usage
 	flag.Parse()
+	if register || unregister {
+		handleRegisterCommand(register)
+		return
+	}
+	if utils.IsURLHandlerRegistered() {
+		// keep the registration pointing to the current executable
+		if err := utils.RegisterURLHandler(utils.Executable()); err != nil {
+			log.Printf("warning: %v\n", err)
+		}
+	}
 	argCount := flag.NArg()
 	flagCount := flag.NFlag()
 	if argCount == 1 && flagCount == 0 && !strings.HasPrefix(flag.Arg(0), "chrome-extension://") {
@@ -102,35 +146,72 @@ func Run(productName, productTitle, productVersion string) {
 		handleUninstallCommand(filenameOrURL, showGUI, productWorkDir, productTitle, productLogFile)
 	} else if argCount == 1 && !strings.HasPrefix(flag.Arg(0), "chrome-extension://") {
 		filenameOrURL := flag.Arg(0)
-		options := &launcher.Options{}
-		if isFlagSet("javadir") || isFlagSet("javaDir") {
-			var err error
-			if javaDir, err = settings.UseJavaDir(javaDir); err != nil {
-				log.Fatal(err)
-			}
-			options.JavaDir = javaDir
-		}
-		if isFlagSet("showconsole") || isFlagSet("showConsole") {
-			settings.ShowConsole()
-			options.ShowConsole = true
-		}
-		if isFlagSet("disableverification") || isFlagSet("disableVerification") {
-			settings.DisableVerification()
-			options.DisableVerification = true
-		}
-		if isFlagSet("disableverificationsameorigin") || isFlagSet("disableVerificationSameOrigin") {
-			settings.DisableVerificationSameOrigin()
-			options.DisableVerificationSameOrigin = true
+		options, err := getLaunchOptions(flag.CommandLine)
+		if err != nil {
+			log.Fatal(err)
 		}
 		handleURLOrFilename(filenameOrURL, options, productWorkDir, productTitle, productLogFile)
 	} else {
 		isRunningFromBrowser := true
 		options := &launcher.Options{IsRunningFromBrowser: isRunningFromBrowser}
 		log.Printf("running from browser: %v", isRunningFromBrowser)
-		listenForMessage(options, productWorkDir, productTitle, productLogFile)
+		listenForMessages(options, productWorkDir, productTitle, productLogFile)
 	}
 }
 
+// defineLaunchFlags defines options of launching an app in flags
+func defineLaunchFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&showConsole, "showconsole", false, "show Java console")
+	flags.BoolVar(&showConsole, "showConsole", false, "show Java console")
+	flags.StringVar(&javaDir, "javadir", "", "Java folder that should be used for starting a Java Web Start application")
+	flags.StringVar(&javaDir, "javaDir", "", "Java folder that should be used for starting a Java Web Start application")
+	flags.BoolVar(&disableVerification, "disableverification", false, "don't verify jar signatures")
+	flags.BoolVar(&disableVerification, "disableVerification", false, "don't verify jar signatures")
+	flags.BoolVar(&disableVerificationSameOrigin, "disableverificationsameorigin", false, "don't verify all jars have same signature")
+	flags.BoolVar(&disableVerificationSameOrigin, "disableVerificationSameOrigin", false, "don't verify all jars have same signature")
+	flags.StringVar(&codebase, "codebase", "", "override codebase of JNLP file")
+	flags.StringVar(&openFile, "open", "", "open a document with the application")
+	flags.StringVar(&printFile, "print", "", "print a document with the application")
+	flags.BoolVar(&offline, "offline", false, "run the application from cache without network access")
+}
+
+// getLaunchOptions returns launcher options for flags defined by defineLaunchFlags and applies them to settings
+func getLaunchOptions(flags *flag.FlagSet) (*launcher.Options, error) {
+	options := &launcher.Options{}
+	if isFlagSet(flags, "javadir") || isFlagSet(flags, "javaDir") {
+		var err error
+		if javaDir, err = settings.UseJavaDir(javaDir); err != nil {
+			return nil, err
+		}
+		options.JavaDir = javaDir
+	}
+	if isFlagSet(flags, "showconsole") || isFlagSet(flags, "showConsole") {
+		settings.ShowConsole()
+		options.ShowConsole = true
+	}
+	if isFlagSet(flags, "disableverification") || isFlagSet(flags, "disableVerification") {
+		settings.DisableVerification()
+		options.DisableVerification = true
+	}
+	if isFlagSet(flags, "disableverificationsameorigin") || isFlagSet(flags, "disableVerificationSameOrigin") {
+		settings.DisableVerificationSameOrigin()
+		options.DisableVerificationSameOrigin = true
+	}
+	if isFlagSet(flags, "codebase") {
+		options.Codebase = codebase
+	}
+	if isFlagSet(flags, "open") {
+		options.Open = openFile
+	}
+	if isFlagSet(flags, "print") {
+		options.Print = printFile
+	}
+	if isFlagSet(flags, "offline") {
+		options.Offline = offline
+	}
+	return options, nil
+}
+
 func handleURLOrFilename(filenameOrURL string, options *launcher.Options, productWorkDir string, productTitle string, productLogFile string) {
 	myLauncher, byURL, err := launcher.FindLauncherForURLOrFilename(filenameOrURL)
 	if err != nil {
@@ -153,46 +234,6 @@ func handleURLOrFilename(filenameOrURL string, options *launcher.Options, produc
 	}
 }
 
-func listenForMessage(options *launcher.Options, productWorkDir string, productTitle string, productLogFile string) {
-	message, err := messaging.GetMessage(os.Stdin)
-	if err != nil {
-		if errors.Cause(err) != io.EOF {
-			log.Fatal(err)
-		}
-		log.Println("exit because stdin has been closed")
-		return
-	}
-	if message.Status != "" {
-		response := fmt.Sprintf(`{"status": "installed"}`)
-		if err := messaging.SendMessage(os.Stdout, response); err != nil {
-			log.Fatal(err)
-		}
-		return
-	}
-	myLauncher, err := launcher.FindLauncherForURL(message.URL)
-	if err != nil {
-		log.Fatal(err)
-	}
-	myLauncher.SetLogFile(productLogFile)
-	myLauncher.SetWorkDir(productWorkDir)
-	myLauncher.SetWindowTitle(productTitle)
-	myLauncher.SetOptions(options)
-	if err := myLauncher.RunByURL(message.URL); err != nil {
-		stringError := fmt.Sprintf("%v", err)
-		jsonError, _ := json.Marshal(stringError)
-		response := fmt.Sprintf(`{"status": %s}`, string(jsonError))
-		log.Println(response)
-		if err := messaging.SendMessage(os.Stdout, response); err != nil {
-			log.Fatal(err)
-		}
-		return
-	}
-	response := fmt.Sprintf(`{"status": "ok"}`)
-	if err := messaging.SendMessage(os.Stdout, response); err != nil {
-		log.Fatal(err)
-	}
-}
-
 func handleUninstallCommand(filenameOrURL string, showGUI bool, productWorkDir string, productTitle string, productLogFile string) {
 	myLauncher, byURL, err := launcher.FindLauncherForURLOrFilename(filenameOrURL)
 	if err != nil {
@@ -214,9 +255,25 @@ func handleUninstallCommand(filenameOrURL string, showGUI bool, productWorkDir s
 	}
 }
 
-func isFlagSet(flagName string) bool {
+func handleRegisterCommand(register bool) {
+	message := "registered as a handler of jnlp:// and jnlps:// URLs and JNLP files"
+	if register {
+		if err := utils.RegisterURLHandler(utils.Executable()); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		if err := utils.UnregisterURLHandler(); err != nil {
+			log.Fatal(err)
+		}
+		message = "un" + message
+	}
+	log.Println(message)
+	fmt.Fprintln(os.Stderr, message)
+}
+
+func isFlagSet(flags *flag.FlagSet, flagName string) bool {
 	found := false
-	flag.Visit(func(f *flag.Flag) {
+	flags.Visit(func(f *flag.Flag) {
 		if f.Name == flagName {
 			found = true
 		}
@@ -231,6 +288,14 @@ func buildUsageText(productTitle, productVersion string) string {
 	text += fmt.Sprintf("\n")
 	text += fmt.Sprintf("Usage:\n")
 	text += fmt.Sprintf("%s [options] <filename | URL>\n", program)
+	text += fmt.Sprintf("%s <command> [options] [arguments]\n", program)
+	text += fmt.Sprintf("\n")
+	text += fmt.Sprintf("Commands:\n")
+	for _, cmd := range commands {
+		text += fmt.Sprintf("  %-12s %s\n", cmd.name, cmd.description)
+	}
+	text += fmt.Sprintf("Use \"%s help <command>\" for more information about a command.\n", program)
+	text += fmt.Sprintf("\n")
 	text += fmt.Sprintf("\n")
 	text += fmt.Sprintf("Options:\n")
 	text += fmt.Sprintf("  -javaDir <java folder>\n")
@@ -241,12 +306,30 @@ func buildUsageText(productTitle, productVersion string) string {
 	text += fmt.Sprintf("      don't verify jar signatures\n")
 	text += fmt.Sprintf("  -disableVerificationSameOrigin\n")
 	text += fmt.Sprintf("      don't verify all jars have same signature\n")
+	text += fmt.Sprintf("  -codebase <URL>\n")
+	text += fmt.Sprintf("      use <URL> as codebase of JNLP file\n")
+	text += fmt.Sprintf("  -open <document>\n")
+	text += fmt.Sprintf("      pass -open <document> to the application instead of its arguments\n")
+	text += fmt.Sprintf("  -print <document>\n")
+	text += fmt.Sprintf("      pass -print <document> to the application instead of its arguments\n")
+	text += fmt.Sprintf("  -offline\n")
+	text += fmt.Sprintf("      run app from cache without network access\n")
 	text += fmt.Sprintf("  -uninstall\n")
 	text += fmt.Sprintf("      uninstall app\n")
 	text += fmt.Sprintf("  -gui\n")
 	text += fmt.Sprintf("      show GUI, uninstall only\n")
+	text += fmt.Sprintf("  -register\n")
+	text += fmt.Sprintf("      handle jnlp:// and jnlps:// URLs and JNLP files, Linux only\n")
+	text += fmt.Sprintf("  -unregister\n")
+	text += fmt.Sprintf("      stop handling jnlp:// and jnlps:// URLs and JNLP files, Linux only\n")
 	text += fmt.Sprintf("  -help\n")
 	text += fmt.Sprintf("      show help\n")
+	text += fmt.Sprintf("\n")
+	text += fmt.Sprintf("javaws compatible usage:\n")
+	text += fmt.Sprintf("%s [-offline] [-wait] [-Xnosplash] [-J<jvm option>] [-open <file> | -print <file>] <filename | URL>\n", program)
+	text += fmt.Sprintf("%s -import [-silent] [-shortcut] [-association] <filename | URL>\n", program)
+	text += fmt.Sprintf("%s -uninstall [-silent] [<filename | URL>]\n", program)
+	text += fmt.Sprintf("%s -clearcache\n", program)
 	return text
 }
 
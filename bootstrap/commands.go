@@ -0,0 +1,519 @@
+package bootstrap
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/launcher"
+	"github.com/rocketsoftware/open-web-launch/launcher/bundle"
+	"github.com/rocketsoftware/open-web-launch/launcher/cache"
+	"github.com/rocketsoftware/open-web-launch/settings"
+	"github.com/rocketsoftware/open-web-launch/utils"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+	"github.com/rocketsoftware/open-web-launch/utils/nativehost"
+	"github.com/rocketsoftware/open-web-launch/verifier"
+)
+
+// environment contains product information and directories shared by commands
+type environment struct {
+	productTitle   string
+	productVersion string
+	productWorkDir string
+	productLogFile string
+}
+
+// command is a subcommand like "launch" or "uninstall"
+type command struct {
+	name        string
+	arguments   string // Arguments shown in usage after options
+	description string
+	defineFlags func(flags *flag.FlagSet) // Defines options of the command, optional
+	run         func(cmd *command, env *environment, args []string) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"launch", "<filename | URL>", "download and run an application", defineLaunchFlags, runLaunchCommand},
+		{"uninstall", "<filename | URL>", "remove shortcuts and cached files of an application", defineGUIFlags, runUninstallCommand},
+		{"export", "<filename | URL> <bundle>", "download and verify an application and write it into a bundle file for machines without network access", defineGUIFlags, runExportCommand},
+		{"import", "<bundle>", "install an application from a bundle file created by export command", defineGUIFlags, runImportCommand},
+		{"list", "", "list cached applications", nil, runListCommand},
+		{"cache", "dir | list | size | prune | clear", "manage cached applications", defineCacheFlags, runCacheCommand},
+		{"verify", "<jar>...", "verify signatures of jar files and show signer fingerprints", nil, runVerifyCommand},
+		{"config", "", "show effective settings", nil, runConfigCommand},
+		{"native-host", "[origin] | install | uninstall | status", "exchange messages with a browser extension using stdin and stdout or manage manifests registering the host in browsers", defineNativeHostFlags, runNativeHostCommand},
+		{"help", "[command]", "show help for a command", nil, runHelpCommand},
+	}
+}
+
+// findCommand returns a command with name or nil, so legacy invocations like "openweblaunch app.jnlp" keep working
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns flags of cmd, -help shows usage of the command
+func (cmd *command) newFlagSet(env *environment) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	if cmd.defineFlags != nil {
+		cmd.defineFlags(flags)
+	}
+	flags.Usage = func() {
+		utils.ShowUsage(env.productTitle, env.productVersion, cmd.usage(env, flags))
+	}
+	return flags
+}
+
+// parseArgs parses args and checks number of positional arguments is in [minArgs, maxArgs], maxArgs < 0 means no limit
+func (cmd *command) parseArgs(flags *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < minArgs || (maxArgs >= 0 && flags.NArg() > maxArgs) {
+		flags.Usage()
+		return errors.Errorf("invalid arguments of %s command: %v", cmd.name, flags.Args())
+	}
+	return nil
+}
+
+func (cmd *command) usage(env *environment, flags *flag.FlagSet) string {
+	program := filepath.Base(os.Args[0])
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "%s %s\n\n", env.productTitle, env.productVersion)
+	fmt.Fprintf(&buffer, "Usage:\n%s %s [options] %s\n\n", program, cmd.name, cmd.arguments)
+	fmt.Fprintf(&buffer, "%s\n", strings.ToUpper(cmd.description[:1])+cmd.description[1:])
+	if flags != nil {
+		var options bytes.Buffer
+		flags.SetOutput(&options)
+		flags.PrintDefaults()
+		if options.Len() > 0 {
+			fmt.Fprintf(&buffer, "\nOptions:\n%s", options.String())
+		}
+	}
+	return buffer.String()
+}
+
+func runLaunchCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if err := cmd.parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	options, err := getLaunchOptions(flags)
+	if err != nil {
+		return err
+	}
+	handleURLOrFilename(flags.Arg(0), options, env.productWorkDir, env.productTitle, env.productLogFile)
+	return nil
+}
+
+func runUninstallCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if err := cmd.parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	handleUninstallCommand(flags.Arg(0), showGUI, env.productWorkDir, env.productTitle, env.productLogFile)
+	return nil
+}
+
+func runExportCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if err := cmd.parseArgs(flags, args, 2, 2); err != nil {
+		return err
+	}
+	bundleFile, err := filepath.Abs(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	observer := &failureObserver{}
+	options := &launcher.Options{Export: bundleFile, Silent: !showGUI, Observer: observer}
+	handleURLOrFilename(flags.Arg(0), options, env.productWorkDir, env.productTitle, env.productLogFile)
+	// in the window errors are shown to the user and aren't returned
+	if err := observer.failure(); err != nil {
+		return errors.Wrapf(err, "unable to export %s", flags.Arg(0))
+	}
+	fmt.Printf("exported %s into %s\n", flags.Arg(0), bundleFile)
+	return nil
+}
+
+func runImportCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if err := cmd.parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	manifest, jnlpFile, err := bundle.Extract(flags.Arg(0), env.productWorkDir)
+	if err != nil {
+		return err
+	}
+	log.Printf("importing %s %s exported %s from %s", manifest.Title, manifest.Version, manifest.Created, manifest.SourceURL)
+	observer := &failureObserver{}
+	options := &launcher.Options{
+		Offline:            true,
+		Import:             true,
+		ImportShortcuts:    true,
+		ImportAssociations: true,
+		Silent:             !showGUI,
+		Observer:           observer,
+	}
+	handleURLOrFilename(jnlpFile, options, env.productWorkDir, env.productTitle, env.productLogFile)
+	if err := observer.failure(); err != nil {
+		return errors.Wrapf(err, "unable to import %s", manifest.Title)
+	}
+	fmt.Printf("imported %s\n", manifest.Title)
+	return nil
+}
+
+// failureObserver records the failure of a run, so commands fail also when the error is only shown in the window
+type failureObserver struct {
+	mutex sync.Mutex
+	err   error
+}
+
+func (observer *failureObserver) Progress(progress *launcher.Progress) {}
+
+func (observer *failureObserver) Prompt(text string, accepted bool) {}
+
+func (observer *failureObserver) Failed(err error) {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	observer.err = err
+}
+
+func (observer *failureObserver) failure() error {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	return observer.err
+}
+
+func runListCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if err := cmd.parseArgs(flags, args, 0, 0); err != nil {
+		return err
+	}
+	entries, err := cache.List(env.productWorkDir)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "TITLE\tLAST USED\tJNLP FILE")
+	for _, entry := range entries {
+		if entry.Title == "" {
+			continue
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", entry.Title, entry.LastUsed.Format(lastUsedFormat), entry.JNLPFile)
+	}
+	return writer.Flush()
+}
+
+const lastUsedFormat = "2006-01-02 15:04"
+
+var (
+	pruneOlderThan time.Duration
+	pruneMaxSize   string
+	pruneOrphaned  bool
+)
+
+func defineCacheFlags(flags *flag.FlagSet) {
+	flags.DurationVar(&pruneOlderThan, "older-than", 0, "prune: remove applications not used for a duration like 720h")
+	flags.StringVar(&pruneMaxSize, "max-size", "", "prune: remove least recently used applications until the cache is not larger than a size like 500MB")
+	flags.BoolVar(&pruneOrphaned, "orphaned", false, "prune: remove directories whose JNLP file doesn't belong to an installed application")
+}
+
+func runCacheCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.New("cache command is not specified")
+	}
+	action := args[0]
+	if err := cmd.parseArgs(flags, args[1:], 0, 0); err != nil {
+		return err
+	}
+	switch action {
+	case "dir":
+		fmt.Println(env.productWorkDir)
+		return nil
+	case "list":
+		entries, err := cache.List(env.productWorkDir)
+		if err != nil {
+			return err
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(writer, "TITLE\tSIZE\tLAST USED\tDIRECTORY")
+		for _, entry := range entries {
+			title := entry.Title
+			if title == "" {
+				title = "-"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", title, cache.FormatSize(entry.Size), entry.LastUsed.Format(lastUsedFormat), entry.Dir)
+		}
+		return writer.Flush()
+	case "size":
+		entries, err := cache.List(env.productWorkDir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s in %d directories\n", cache.FormatSize(cache.TotalSize(entries)), len(entries))
+		return nil
+	case "prune":
+		options := &cache.PruneOptions{OlderThan: pruneOlderThan, Orphaned: pruneOrphaned}
+		if pruneMaxSize != "" {
+			var err error
+			if options.MaxSize, err = cache.ParseSize(pruneMaxSize); err != nil {
+				return err
+			}
+		}
+		if options.OlderThan == 0 && options.MaxSize == 0 && !options.Orphaned {
+			flags.Usage()
+			return errors.New("cache prune requires -older-than, -max-size or -orphaned option")
+		}
+		pruned, err := cache.Prune(env.productWorkDir, options)
+		printRemovedEntries(pruned)
+		return err
+	case "clear":
+		removed, err := cache.Clear(env.productWorkDir, uninstallCacheEntry(env.productWorkDir, env.productTitle, env.productLogFile))
+		printRemovedEntries(removed)
+		return err
+	}
+	flags.Usage()
+	return errors.Errorf("unknown cache command %s", action)
+}
+
+// uninstallCacheEntry returns a function uninstalling the app of a cache entry before the entry is removed
+func uninstallCacheEntry(productWorkDir string, productTitle string, productLogFile string) func(entry *cache.Entry) error {
+	return func(entry *cache.Entry) error {
+		handleUninstallCommand(entry.JNLPFile, false, productWorkDir, productTitle, productLogFile)
+		return nil
+	}
+}
+
+func printRemovedEntries(entries []*cache.Entry) {
+	for _, entry := range entries {
+		log.Printf("removed cache directory %s of %q", entry.Dir, entry.Title)
+	}
+	fmt.Printf("removed %s in %d directories\n", cache.FormatSize(cache.TotalSize(entries)), len(entries))
+}
+
+func runVerifyCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if err := cmd.parseArgs(flags, args, 1, -1); err != nil {
+		return err
+	}
+	if err := settings.EnsureJARSignerAvailability(); err != nil {
+		return err
+	}
+	failed := 0
+	for _, jar := range flags.Args() {
+		if err := verifier.VerifyWithJARSigner(jar, false); err != nil {
+			fmt.Printf("%s: not verified: %v\n", jar, err)
+			failed++
+			continue
+		}
+		fingerprint, err := verifier.GetJARSignerFingerprint(jar)
+		if err != nil {
+			fmt.Printf("%s: verified, unable to get signer: %v\n", jar, err)
+			continue
+		}
+		fmt.Printf("%s: verified, signer SHA-256 fingerprint %s\n", jar, fingerprint)
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d jar files are not verified", failed, flags.NArg())
+	}
+	return nil
+}
+
+func runConfigCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if err := cmd.parseArgs(flags, args, 0, 0); err != nil {
+		return err
+	}
+	javaVersion, err := settings.GetJavaVersionString()
+	if err != nil {
+		javaVersion = err.Error()
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(writer, "Java\t%s\n", settings.Java())
+	fmt.Fprintf(writer, "Java source\t%s\n", settings.JavaSource())
+	fmt.Fprintf(writer, "Java version\t%s\n", strings.TrimSpace(javaVersion))
+	fmt.Fprintf(writer, "jarsigner\t%s\n", settings.JARSigner())
+	fmt.Fprintf(writer, "Verification disabled\t%v\n", settings.IsVerificationDisabled())
+	fmt.Fprintf(writer, "Same origin verification disabled\t%v\n", settings.IsVerificationSameOriginDisabled())
+	fmt.Fprintf(writer, "Add apps to Control Panel\t%v\n", settings.AddAppToControlPanel())
+	fmt.Fprintf(writer, "Use HTTP proxy environment variables\t%v\n", settings.UseHttpProxyEnvironmentVariable())
+	fmt.Fprintf(writer, "Proxy mode\t%s\n", settings.ProxyMode())
+	if settings.ProxyMode() == "pac" {
+		fmt.Fprintf(writer, "Proxy auto-config\t%s\n", settings.ProxyAutoConfig())
+	}
+	fmt.Fprintf(writer, "Locale\t%s\n", settings.Locale())
+	fmt.Fprintf(writer, "Credentials file\t%s\n", settings.CredentialsFile())
+	fmt.Fprintf(writer, "Insecure authentication allowed\t%v\n", settings.AllowInsecureAuthentication())
+	fmt.Fprintf(writer, "Client certificates file\t%s\n", settings.ClientCertificatesFile())
+	fmt.Fprintf(writer, "Settings\t%s\n", strings.Join(settings.ConfigSources(), ", "))
+	fmt.Fprintf(writer, "Policy file\t%s\n", settings.PolicyFile())
+	fmt.Fprintf(writer, "Config directory\t%s\n", settings.ConfigDir())
+	fmt.Fprintf(writer, "Cache directory\t%s\n", env.productWorkDir)
+	fmt.Fprintf(writer, "Log file\t%s\n", env.productLogFile)
+	return writer.Flush()
+}
+
+var (
+	nativeHostScope     string
+	nativeHostBrowsers  string
+	nativeHostName      string
+	nativeHostChromeID  string
+	nativeHostFirefoxID string
+)
+
+func defineNativeHostFlags(flags *flag.FlagSet) {
+	flags.StringVar(&nativeHostScope, "scope", string(nativehost.User), "install, uninstall, status: user or system, system requires administrator rights")
+	flags.StringVar(&nativeHostBrowsers, "browsers", "", "install, uninstall, status: comma separated browsers, all supported browsers by default")
+	flags.StringVar(&nativeHostName, "name", "", "install, uninstall, status: name of the host used by the extension, required")
+	flags.StringVar(&nativeHostChromeID, "chrome-id", nativehost.DefaultChromeExtensionID, "install, status: ID of the extension for Chrome, Chromium and Brave")
+	flags.StringVar(&nativeHostFirefoxID, "firefox-id", "", "install, status: ID of the add-on for Firefox, Firefox is skipped without it")
+}
+
+func runNativeHostCommand(cmd *command, env *environment, args []string) error {
+	flags := cmd.newFlagSet(env)
+	if len(args) > 0 && (args[0] == "install" || args[0] == "uninstall" || args[0] == "status") {
+		return runNativeHostManifestCommand(cmd, flags, args[0], args[1:])
+	}
+	// browsers pass origin of the extension and on Windows a handle of the parent window
+	if err := cmd.parseArgs(flags, args, 0, -1); err != nil {
+		return err
+	}
+	options := &launcher.Options{IsRunningFromBrowser: true}
+	log.Printf("running as native messaging host for %v", flags.Args())
+	listenForMessages(options, env.productWorkDir, env.productTitle, env.productLogFile)
+	return nil
+}
+
+// runNativeHostManifestCommand installs, uninstalls or checks manifests of the native messaging host
+func runNativeHostManifestCommand(cmd *command, flags *flag.FlagSet, action string, args []string) error {
+	if err := cmd.parseArgs(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if nativeHostName == "" {
+		return errors.New("-name is required, it must be the host name used by the extension")
+	}
+	scope := nativehost.Scope(nativeHostScope)
+	if scope != nativehost.User && scope != nativehost.System {
+		return errors.Errorf("invalid scope %q, it must be user or system", nativeHostScope)
+	}
+	browsers, err := nativeHostBrowserList(nativeHostBrowsers)
+	if err != nil {
+		return err
+	}
+	host := &nativehost.Host{
+		Name:               nativeHostName,
+		Description:        nativehost.DefaultDescription,
+		Path:               utils.Executable(),
+		ChromeExtensionID:  nativeHostChromeID,
+		FirefoxExtensionID: nativeHostFirefoxID,
+	}
+	failed, installed := 0, 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "BROWSER\tSCOPE\tSTATUS\tMANIFEST")
+	for _, browser := range browsers {
+		var filename, status string
+		switch action {
+		case "install":
+			filename, err = nativehost.Install(host, browser, scope)
+			switch {
+			case err == nativehost.ErrNoExtensionID:
+				status = "skipped: extension ID is not set"
+			case err != nil:
+				status = "failed: " + err.Error()
+				failed++
+			default:
+				status = "installed"
+			}
+		case "uninstall":
+			var removed bool
+			filename, removed, err = nativehost.Uninstall(host.Name, browser, scope)
+			switch {
+			case err != nil:
+				status = "failed: " + err.Error()
+				failed++
+			case removed:
+				status = "removed"
+			default:
+				status = "not installed"
+			}
+		case "status":
+			filename, err = nativehost.Check(host, browser, scope)
+			switch {
+			case err == nativehost.ErrNoExtensionID:
+				status = "skipped: extension ID is not set"
+			case err == nativehost.ErrNotInstalled:
+				status = "not installed"
+			case err != nil:
+				status = "invalid: " + err.Error()
+				failed++
+			default:
+				status = "ok"
+				installed++
+			}
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", browser.Name, scope, status, filename)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.Errorf("native messaging host %s failed for %d of %d browsers", action, failed, len(browsers))
+	}
+	if action == "status" && installed == 0 {
+		return errors.Errorf("native messaging host %s is not installed in %s scope", host.Name, scope)
+	}
+	return nil
+}
+
+// nativeHostBrowserList returns browsers listed in names separated by commas or all supported browsers
+func nativeHostBrowserList(names string) ([]*nativehost.Browser, error) {
+	if len(nativehost.Browsers()) == 0 {
+		return nil, errors.Errorf("native messaging host manifests can't be managed on %s", runtime.GOOS)
+	}
+	if names == "" {
+		return nativehost.Browsers(), nil
+	}
+	var browsers []*nativehost.Browser
+	for _, name := range strings.Split(names, ",") {
+		browser := nativehost.FindBrowser(strings.TrimSpace(name))
+		if browser == nil {
+			return nil, errors.Errorf("unknown browser %q", name)
+		}
+		browsers = append(browsers, browser)
+	}
+	return browsers, nil
+}
+
+func runHelpCommand(cmd *command, env *environment, args []string) error {
+	if len(args) == 0 {
+		showUsage(env.productTitle, env.productVersion)
+		return nil
+	}
+	helpCmd := findCommand(args[0])
+	if helpCmd == nil {
+		return errors.Errorf("unknown command %s", args[0])
+	}
+	helpCmd.newFlagSet(env).Usage()
+	return nil
+}
+
+func defineGUIFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&showGUI, "gui", false, "show GUI")
+}
@@ -0,0 +1,203 @@
+package bootstrap
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/launcher"
+	"github.com/rocketsoftware/open-web-launch/launcher/cache"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+)
+
+// javawsCommand is a command line of javaws converted into launcher options
+type javawsCommand struct {
+	options    launcher.Options
+	target     string   // JNLP file or URL
+	nativeArgs []string // Open Web Launch flags like -javadir <dir> which are applied by getLaunchOptions
+	uninstall  bool
+	clearCache bool
+}
+
+// javawsOnlyOptions are options which are understood by javaws but not by Open Web Launch flags
+var javawsOnlyOptions = []string{
+	"-online", "-wait", "-Xnosplash", "-import", "-silent", "-shortcut", "-association", "-clearcache",
+}
+
+// isJavawsCommandLine reports whether the program is started as javaws or with javaws specific options
+func isJavawsCommandLine(program string, args []string) bool {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(program), filepath.Ext(program)))
+	if name == "javaws" {
+		return true
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-J") {
+			return true
+		}
+		for _, option := range javawsOnlyOptions {
+			if arg == option {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseJavawsArgs parses javaws command line like
+// javaws [-offline] [-wait] [-Xnosplash] [-J<jvm option>] [-open <file> | -print <file>] <jnlp reference>
+// javaws -import [-silent] [-shortcut] [-association] <jnlp reference>
+// javaws -uninstall [<jnlp reference>]
+// javaws -clearcache
+func parseJavawsArgs(args []string) (*javawsCommand, error) {
+	command := &javawsCommand{}
+	options := &command.options
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		nextArg := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", errors.Errorf("option %s requires an argument", arg)
+			}
+			i++
+			return args[i], nil
+		}
+		var err error
+		switch {
+		case arg == "-offline":
+			options.Offline = true
+		case arg == "-online":
+			options.Offline = false
+		case arg == "-wait":
+			options.Wait = true
+		case arg == "-Xnosplash":
+			options.NoSplash = true
+		case strings.HasPrefix(arg, "-J"):
+			if jvmArg := strings.TrimPrefix(arg, "-J"); jvmArg != "" {
+				options.JVMArgs = append(options.JVMArgs, jvmArg)
+			}
+		case arg == "-open":
+			options.Open, err = nextArg()
+		case arg == "-print":
+			options.Print, err = nextArg()
+		case arg == "-codebase":
+			options.Codebase, err = nextArg()
+		case arg == "-import":
+			options.Import = true
+		case arg == "-silent":
+			options.Silent = true
+		case arg == "-shortcut":
+			options.ImportShortcuts = true
+		case arg == "-association":
+			options.ImportAssociations = true
+		case arg == "-uninstall":
+			command.uninstall = true
+		case arg == "-clearcache":
+			command.clearCache = true
+		case isLaunchFlag(arg):
+			command.nativeArgs = append(command.nativeArgs, arg)
+			if !strings.Contains(arg, "=") && !isBoolLaunchFlag(arg) {
+				var value string
+				value, err = nextArg()
+				command.nativeArgs = append(command.nativeArgs, value)
+			}
+		case strings.HasPrefix(arg, "-"):
+			log.Printf("warning: javaws option %s is not supported and ignored", arg)
+		default:
+			if command.target != "" {
+				return nil, errors.Errorf("unexpected argument %s, only one JNLP file or URL is allowed", arg)
+			}
+			command.target = arg
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if command.target == "" && !command.uninstall && !command.clearCache {
+		return nil, errors.New("JNLP file or URL is not specified")
+	}
+	if command.clearCache && command.target != "" {
+		return nil, errors.New("-clearcache doesn't accept JNLP file or URL")
+	}
+	return command, nil
+}
+
+// lookupLaunchFlag returns a flag defined by defineLaunchFlags for an argument like -javadir or -javadir=<dir> or nil
+func lookupLaunchFlag(arg string) *flag.Flag {
+	if !strings.HasPrefix(arg, "-") {
+		return nil
+	}
+	name := strings.TrimLeft(arg, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	flags := flag.NewFlagSet("javaws", flag.ContinueOnError)
+	defineLaunchFlags(flags)
+	return flags.Lookup(name)
+}
+
+func isLaunchFlag(arg string) bool {
+	return lookupLaunchFlag(arg) != nil
+}
+
+func isBoolLaunchFlag(arg string) bool {
+	boolFlag, ok := lookupLaunchFlag(arg).Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// applyNativeArgs applies Open Web Launch flags of the command to its options
+func (command *javawsCommand) applyNativeArgs() error {
+	if len(command.nativeArgs) == 0 {
+		return nil
+	}
+	flags := flag.NewFlagSet("javaws", flag.ContinueOnError)
+	defineLaunchFlags(flags)
+	if err := flags.Parse(command.nativeArgs); err != nil {
+		return err
+	}
+	nativeOptions, err := getLaunchOptions(flags)
+	if err != nil {
+		return err
+	}
+	options := &command.options
+	if nativeOptions.JavaDir != "" {
+		options.JavaDir = nativeOptions.JavaDir
+	}
+	options.ShowConsole = options.ShowConsole || nativeOptions.ShowConsole
+	options.DisableVerification = options.DisableVerification || nativeOptions.DisableVerification
+	options.DisableVerificationSameOrigin = options.DisableVerificationSameOrigin || nativeOptions.DisableVerificationSameOrigin
+	return nil
+}
+
+// runJavaws runs javaws command line args
+func runJavaws(args []string, productWorkDir string, productTitle string, productLogFile string) {
+	command, err := parseJavawsArgs(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := command.applyNativeArgs(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("javaws command line: %+v", command)
+	switch {
+	case command.clearCache:
+		removed, err := cache.Clear(productWorkDir, uninstallCacheEntry(productWorkDir, productTitle, productLogFile))
+		if err != nil {
+			log.Fatal(err)
+		}
+		printRemovedEntries(removed)
+	case command.uninstall && command.target != "":
+		handleUninstallCommand(command.target, !command.options.Silent, productWorkDir, productTitle, productLogFile)
+	case command.uninstall:
+		entries, err := cache.List(productWorkDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, entry := range entries {
+			if entry.JNLPFile != "" {
+				handleUninstallCommand(entry.JNLPFile, !command.options.Silent, productWorkDir, productTitle, productLogFile)
+			}
+		}
+	default:
+		handleURLOrFilename(command.target, &command.options, productWorkDir, productTitle, productLogFile)
+	}
+}
@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rocketsoftware/open-web-launch/launcher"
+)
+
+func Test_isJavawsCommandLine(t *testing.T) {
+	tests := []struct {
+		program string
+		args    []string
+		want    bool
+	}{
+		{"/usr/bin/javaws", []string{"app.jnlp"}, true},
+		{"javaws.exe", []string{"app.jnlp"}, true},
+		{"openweblaunch", []string{"-Xnosplash", "app.jnlp"}, true},
+		{"openweblaunch", []string{"-J-Xmx1g", "app.jnlp"}, true},
+		{"openweblaunch", []string{"-javaDir", "/opt/java", "app.jnlp"}, false},
+		{"openweblaunch", []string{"app.jnlp"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.program, func(t *testing.T) {
+			if got := isJavawsCommandLine(tt.program, tt.args); got != tt.want {
+				t.Errorf("isJavawsCommandLine(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseJavawsArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    *javawsCommand
+		wantErr bool
+	}{
+		{
+			name: "launch",
+			args: []string{"-offline", "-wait", "-Xnosplash", "-J-Xmx1g", "-J-Dkey=value", "-open", "doc.txt", "app.jnlp"},
+			want: &javawsCommand{
+				options: launcher.Options{
+					Offline:  true,
+					Wait:     true,
+					NoSplash: true,
+					JVMArgs:  []string{"-Xmx1g", "-Dkey=value"},
+					Open:     "doc.txt",
+				},
+				target: "app.jnlp",
+			},
+		},
+		{
+			name: "import",
+			args: []string{"-import", "-silent", "-shortcut", "-association", "https://host/app.jnlp"},
+			want: &javawsCommand{
+				options: launcher.Options{Import: true, Silent: true, ImportShortcuts: true, ImportAssociations: true},
+				target:  "https://host/app.jnlp",
+			},
+		},
+		{
+			name: "uninstall all",
+			args: []string{"-uninstall"},
+			want: &javawsCommand{uninstall: true},
+		},
+		{
+			name: "clear cache",
+			args: []string{"-clearcache"},
+			want: &javawsCommand{clearCache: true},
+		},
+		{
+			name: "Open Web Launch flags",
+			args: []string{"-Xnosplash", "-javadir", "/opt/java", "-showconsole", "-javaDir=/opt/jdk", "app.jnlp"},
+			want: &javawsCommand{
+				options:    launcher.Options{NoSplash: true},
+				target:     "app.jnlp",
+				nativeArgs: []string{"-javadir", "/opt/java", "-showconsole", "-javaDir=/opt/jdk"},
+			},
+		},
+		{
+			name:    "missing Java folder",
+			args:    []string{"-Xnosplash", "app.jnlp", "-javadir"},
+			wantErr: true,
+		},
+		{
+			name:    "missing JNLP",
+			args:    []string{"-offline"},
+			wantErr: true,
+		},
+		{
+			name:    "missing document",
+			args:    []string{"app.jnlp", "-open"},
+			wantErr: true,
+		},
+		{
+			name:    "two JNLP files",
+			args:    []string{"first.jnlp", "second.jnlp"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJavawsArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJavawsArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJavawsArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
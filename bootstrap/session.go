@@ -0,0 +1,211 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/launcher"
+	"github.com/rocketsoftware/open-web-launch/messaging"
+	"github.com/rocketsoftware/open-web-launch/utils/download"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+)
+
+// progressInterval limits how often download progress of a file is sent to the browser
+const progressInterval = 250 * time.Millisecond
+
+// browserConnection sends messages to the browser extension, events of a launch are sent from several goroutines
+type browserConnection struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// listenForMessages handles messages of the browser extension one by one until stdin is closed
+func listenForMessages(options *launcher.Options, productWorkDir string, productTitle string, productLogFile string) {
+	connection := &browserConnection{writer: os.Stdout}
+	for {
+		message, err := messaging.GetMessage(os.Stdin)
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				log.Println("exit because stdin has been closed")
+				return
+			}
+			if _, ok := errors.Cause(err).(*messaging.MalformedMessageError); ok {
+				log.Println(err)
+				connection.sendStatus(fmt.Sprintf("%v", err))
+				continue
+			}
+			// the stream can't be read further after invalid length or a partial message
+			log.Fatal(err)
+		}
+		connection.handleMessage(message, options, productWorkDir, productTitle, productLogFile)
+	}
+}
+
+// handleMessage answers a request of the extension, options aren't changed by runs
+func (connection *browserConnection) handleMessage(message *messaging.Message, options *launcher.Options, productWorkDir string, productTitle string, productLogFile string) {
+	switch message.Type {
+	case "":
+		if message.Status != "" {
+			connection.sendStatus("installed")
+			return
+		}
+		if err := launch(message, options, nil, productWorkDir, productTitle, productLogFile); err != nil {
+			log.Printf("unable to run %s: %v", message.URL, err)
+			connection.sendStatus(fmt.Sprintf("%v", err))
+			return
+		}
+		connection.sendStatus("ok")
+	case messaging.TypeHello:
+		if message.Version < 1 {
+			connection.sendError(message.ID, messaging.ErrorUnsupportedVersion, errors.Errorf("unsupported protocol version %d", message.Version))
+			return
+		}
+		version := message.Version
+		if version > messaging.ProtocolVersion {
+			version = messaging.ProtocolVersion
+		}
+		connection.send(&messaging.Event{Type: messaging.TypeHello, ID: message.ID, Version: version})
+	case messaging.TypeStatus:
+		connection.send(&messaging.Event{Type: messaging.TypeResult, ID: message.ID, Status: messaging.StatusOK, Version: messaging.ProtocolVersion})
+	case messaging.TypeLaunch:
+		observer := &browserObserver{connection: connection, id: message.ID}
+		err := launch(message, options, observer, productWorkDir, productTitle, productLogFile)
+		if err == nil {
+			// in the window errors are shown to the user and aren't returned
+			err = observer.failure()
+		}
+		if err != nil {
+			log.Printf("unable to run %s: %v", message.URL, err)
+			code := launcher.ErrorCode(err)
+			if code == "" {
+				code = launcher.ErrorUnknown
+			}
+			connection.sendError(message.ID, code, err)
+			return
+		}
+		connection.send(&messaging.Event{Type: messaging.TypeResult, ID: message.ID, Status: messaging.StatusOK})
+	default:
+		connection.sendError(message.ID, messaging.ErrorInvalidRequest, errors.Errorf("unknown message type %q", message.Type))
+	}
+}
+
+// launch runs the JNLP file of message with cookies and headers of the browser
+func launch(message *messaging.Message, options *launcher.Options, observer launcher.Observer, productWorkDir string, productTitle string, productLogFile string) error {
+	myLauncher, err := launcher.FindLauncherForURL(message.URL)
+	if err != nil {
+		return launcher.WithCode(err, messaging.ErrorInvalidRequest)
+	}
+	if len(message.Cookies) > 0 || len(message.Headers) > 0 {
+		download.SetBrowserSession(browserSession(message))
+	} else {
+		download.SetBrowserSession(nil)
+	}
+	runOptions := *options
+	runOptions.Observer = observer
+	myLauncher.SetLogFile(productLogFile)
+	myLauncher.SetWorkDir(productWorkDir)
+	myLauncher.SetWindowTitle(productTitle)
+	myLauncher.SetOptions(&runOptions)
+	return myLauncher.RunByURL(message.URL)
+}
+
+// browserSession returns cookies and headers of the browser from message, they are only kept in memory
+func browserSession(message *messaging.Message) *download.BrowserSession {
+	session := &download.BrowserSession{URL: message.URL, Header: make(http.Header)}
+	for _, cookie := range message.Cookies {
+		session.Cookies = append(session.Cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value, Path: cookie.Path, Secure: cookie.Secure})
+	}
+	for name, value := range message.Headers {
+		session.Header.Set(name, value)
+	}
+	return session
+}
+
+// send sends an event of the typed protocol
+func (connection *browserConnection) send(event *messaging.Event) {
+	connection.mutex.Lock()
+	defer connection.mutex.Unlock()
+	if err := messaging.SendEvent(connection.writer, event); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// sendError sends a failed result of the request with id
+func (connection *browserConnection) sendError(id json.RawMessage, code string, err error) {
+	connection.send(&messaging.Event{
+		Type:   messaging.TypeResult,
+		ID:     id,
+		Status: messaging.StatusError,
+		Error:  &messaging.Error{Code: code, Message: fmt.Sprintf("%v", err)},
+	})
+}
+
+// sendStatus sends {"status": status} of the original protocol
+func (connection *browserConnection) sendStatus(status string) {
+	jsonStatus, _ := json.Marshal(status)
+	response := fmt.Sprintf(`{"status": %s}`, string(jsonStatus))
+	connection.mutex.Lock()
+	defer connection.mutex.Unlock()
+	if err := messaging.SendMessage(connection.writer, response); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// browserObserver streams progress and prompts of a launch request to the browser extension
+type browserObserver struct {
+	connection *browserConnection
+	id         json.RawMessage
+	mutex      sync.Mutex
+	last       launcher.Progress // Last sent progress
+	lastSent   time.Time
+	err        error
+}
+
+func (observer *browserObserver) Progress(progress *launcher.Progress) {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	// changes of phase, file or percent are sent at once, bytes of a file at most every progressInterval
+	sameStep := progress.Phase == observer.last.Phase && progress.File == observer.last.File && progress.Percent == observer.last.Percent
+	finished := progress.Total > 0 && progress.Bytes >= progress.Total
+	if sameStep && !finished && time.Since(observer.lastSent) < progressInterval {
+		return
+	}
+	observer.last, observer.lastSent = *progress, time.Now()
+	observer.connection.send(&messaging.Event{
+		Type: messaging.TypeProgress,
+		ID:   observer.id,
+		Progress: &messaging.Progress{
+			Phase:   progress.Phase,
+			File:    progress.File,
+			Bytes:   progress.Bytes,
+			Total:   progress.Total,
+			Percent: progress.Percent,
+		},
+	})
+}
+
+func (observer *browserObserver) Prompt(text string, accepted bool) {
+	observer.connection.send(&messaging.Event{
+		Type:   messaging.TypePrompt,
+		ID:     observer.id,
+		Prompt: &messaging.Prompt{Text: text, Accepted: accepted},
+	})
+}
+
+func (observer *browserObserver) Failed(err error) {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	observer.err = err
+}
+
+func (observer *browserObserver) failure() error {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	return observer.err
+}
@@ -0,0 +1,58 @@
+package bootstrap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rocketsoftware/open-web-launch/launcher"
+	"github.com/rocketsoftware/open-web-launch/messaging"
+)
+
+func Test_handleMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *messaging.Message
+		want    string
+	}{
+		{"original status", &messaging.Message{Status: "get"}, `{"status": "installed"}`},
+		{"hello", &messaging.Message{Type: "hello", ID: []byte(`1`), Version: 3}, `{"type":"hello","id":1,"version":1}`},
+		{"old hello", &messaging.Message{Type: "hello", ID: []byte(`"a"`)},
+			`{"type":"result","id":"a","status":"error","error":{"code":"unsupported_version","message":"unsupported protocol version 0"}}`},
+		{"status", &messaging.Message{Type: "status", ID: []byte(`2`)}, `{"type":"result","id":2,"version":1,"status":"ok"}`},
+		{"unknown type", &messaging.Message{Type: "update", ID: []byte(`3`)},
+			`{"type":"result","id":3,"status":"error","error":{"code":"invalid_request","message":"unknown message type \"update\""}}`},
+		{"unknown launcher", &messaging.Message{Type: "launch", ID: []byte(`4`), URL: "https://example.com/app.zip"},
+			`{"type":"result","id":4,"status":"error","error":{"code":"invalid_request","message":"unable to find launcher for URL https://example.com/app.zip"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			connection := &browserConnection{writer: &buffer}
+			connection.handleMessage(tt.message, &launcher.Options{}, "", "", "")
+			// skip the length of the message
+			if got := buffer.String()[4:]; got != tt.want {
+				t.Errorf("handleMessage() sent %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_browserObserver_Progress(t *testing.T) {
+	var buffer bytes.Buffer
+	observer := &browserObserver{connection: &browserConnection{writer: &buffer}, id: []byte(`1`)}
+	for _, progress := range []*launcher.Progress{
+		{Phase: "download", Percent: 10},
+		{Phase: "download", File: "app.jar", Bytes: 0, Total: 300, Percent: 10},
+		{Phase: "download", File: "app.jar", Bytes: 100, Total: 300, Percent: 10},
+		{Phase: "download", File: "app.jar", Bytes: 200, Total: 300, Percent: 10},
+		{Phase: "download", File: "app.jar", Bytes: 300, Total: 300, Percent: 10},
+		{Phase: "download", Percent: 20},
+	} {
+		observer.Progress(progress)
+	}
+	// bytes within progressInterval are skipped, the completed file is sent
+	if got := strings.Count(buffer.String(), `"type":"progress"`); got != 4 {
+		t.Errorf("sent %d progress events, want 4: %s", got, buffer.String())
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/aarzilli/nucular"
 	"github.com/aarzilli/nucular/label"
@@ -32,6 +33,23 @@ type GUI struct {
 	icon        *image.RGBA
 	err         error
 	logFile     string
+	question    atomic.Value // *confirmQuestion asked by Confirm
+	credentials atomic.Value // *credentialsQuestion asked by AskCredentials
+}
+
+// confirmQuestion is shown instead of progress until the user answers it
+type confirmQuestion struct {
+	text   string
+	action string // Label of the button accepting the question
+	answer chan bool
+}
+
+// credentialsQuestion is shown instead of progress until the user enters credentials or cancels
+type credentialsQuestion struct {
+	text     string
+	username nucular.TextEditor
+	password nucular.TextEditor
+	answer   chan bool
 }
 
 // myThemeTable is modified WhiteTheme
@@ -73,6 +91,8 @@ func New() *GUI {
 	gui.title.Store("")
 	gui.text.Store("")
 	gui.progressMax.Store(0)
+	gui.question.Store((*confirmQuestion)(nil))
+	gui.credentials.Store((*credentialsQuestion)(nil))
 	return gui
 }
 
@@ -172,6 +192,46 @@ func (gui *GUI) updateFn(w *nucular.Window) {
 		}
 		return
 	}
+	if question := gui.question.Load().(*confirmQuestion); question != nil {
+		w.Row(85).Dynamic(1)
+		w.LabelWrap(question.text)
+		w.Row(30).Dynamic(5)
+		w.Spacing(3)
+		if w.Button(label.TA(question.action, "CC"), false) {
+			log.Printf("%s button pressed", question.action)
+			gui.question.Store((*confirmQuestion)(nil))
+			question.answer <- true
+		}
+		if w.Button(label.TA("Cancel", "CC"), false) {
+			log.Println("cancel button pressed")
+			gui.question.Store((*confirmQuestion)(nil))
+			question.answer <- false
+		}
+		return
+	}
+	if question := gui.credentials.Load().(*credentialsQuestion); question != nil {
+		w.Row(40).Dynamic(1)
+		w.LabelWrap(question.text)
+		w.Row(25).Static(100, 320)
+		w.Label("User name:", "LC")
+		question.username.Edit(w)
+		w.Row(25).Static(100, 320)
+		w.Label("Password:", "LC")
+		events := question.password.Edit(w)
+		w.Row(30).Dynamic(5)
+		w.Spacing(3)
+		if w.Button(label.TA("OK", "CC"), false) || events&nucular.EditCommitted != 0 {
+			log.Println("credentials entered")
+			gui.credentials.Store((*credentialsQuestion)(nil))
+			question.answer <- true
+		}
+		if w.Button(label.TA("Cancel", "CC"), false) {
+			log.Println("cancel button pressed")
+			gui.credentials.Store((*credentialsQuestion)(nil))
+			question.answer <- false
+		}
+		return
+	}
 	w.Row(30).Dynamic(1)
 	w.Spacing(1)
 
@@ -254,6 +314,65 @@ func (gui *GUI) SendCloseMessage() error {
 	return nil
 }
 
+// Confirm shows text with Run and Cancel buttons and waits for the user's answer.
+// The answer is false if there is no GUI or the window is closed.
+func (gui *GUI) Confirm(text string) bool {
+	return gui.ConfirmAction(text, "Run")
+}
+
+// ConfirmAction is like Confirm, but the button accepting the question is labeled with action
+func (gui *GUI) ConfirmAction(text string, action string) bool {
+	if gui == nil {
+		return false
+	}
+	answer := make(chan bool, 1)
+	gui.question.Store(&confirmQuestion{text: text, action: action, answer: answer})
+	gui.window.Changed()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case result := <-answer:
+			return result
+		case <-ticker.C:
+			if gui.Closed() {
+				return false
+			}
+		}
+	}
+}
+
+// AskCredentials shows text with user name and password fields and waits until the user enters them.
+// ok is false if there is no GUI, the user cancels or the window is closed.
+func (gui *GUI) AskCredentials(text string) (username string, password string, ok bool) {
+	if gui == nil {
+		return "", "", false
+	}
+	answer := make(chan bool, 1)
+	question := &credentialsQuestion{text: text, answer: answer}
+	question.username.Flags = nucular.EditField
+	question.username.Active = true
+	question.password.Flags = nucular.EditField | nucular.EditSigEnter
+	question.password.PasswordChar = '*'
+	gui.credentials.Store(question)
+	gui.window.Changed()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case result := <-answer:
+			if !result {
+				return "", "", false
+			}
+			return string(question.username.Buffer), string(question.password.Buffer), true
+		case <-ticker.C:
+			if gui.Closed() {
+				return "", "", false
+			}
+		}
+	}
+}
+
 func (gui *GUI) SetTitle(title string) error {
 	if gui == nil {
 		return nil
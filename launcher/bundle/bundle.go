@@ -0,0 +1,223 @@
+// Package bundle packs a resolved app into a single archive which can be installed on machines without access
+// to the JNLP server. The archive is a zip file with a manifest and files of the app's resource directory:
+// the original JNLP file, its source URL, JARs, nativelibs, extensions and icons.
+package bundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	launcher_utils "github.com/rocketsoftware/open-web-launch/launcher/utils"
+)
+
+// FormatVersion is the version of bundle format written by Create
+const FormatVersion = 1
+
+const (
+	manifestName     = "manifest.json"
+	filesDir         = "files/"
+	originalFileName = "original.jnlp"
+)
+
+// Manifest describes the app and files of a bundle
+type Manifest struct {
+	FormatVersion     int       `json:"formatVersion"`
+	Title             string    `json:"title"`
+	Vendor            string    `json:"vendor,omitempty"`
+	Version           string    `json:"version,omitempty"`
+	SourceURL         string    `json:"sourceURL,omitempty"`         // URL the JNLP file was downloaded from
+	SignerFingerprint string    `json:"signerFingerprint,omitempty"` // SHA-256 fingerprint of the certificate the main JAR is signed with
+	Created           time.Time `json:"created"`
+	Files             []*File   `json:"files"`
+}
+
+// File is a file of the app's resource directory stored in a bundle
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Create writes files of resourceDir named in names and manifest into bundle filename.
+// Files of the manifest are filled in. Other files like extracted nativelibs and argument files are skipped
+// because they are recreated when the app is started.
+func Create(filename string, resourceDir string, names []string, manifest *Manifest) (err error) {
+	out, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "unable to create bundle")
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(filename)
+		}
+	}()
+	archive := zip.NewWriter(out)
+	manifest.FormatVersion = FormatVersion
+	manifest.Files = nil
+	added := make(map[string]bool)
+	for _, name := range names {
+		if added[name] {
+			continue
+		}
+		added[name] = true
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			return errors.Errorf("invalid file name %s", name)
+		}
+		entry, err := addFile(archive, filepath.Join(resourceDir, name))
+		if err != nil {
+			return errors.Wrapf(err, "unable to add %s to bundle", name)
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	writer, err := archive.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func addFile(archive *zip.Writer, filename string) (*File, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	writer, err := archive.Create(filesDir + filepath.Base(filename))
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(writer, hash), in)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Name: filepath.Base(filename), Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// Extract verifies bundle filename against its manifest and unpacks it into the resource directory
+// the app would have in workDir if it was downloaded. It returns the manifest and path of the original JNLP file.
+func Extract(filename string, workDir string) (*Manifest, string, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "unable to open bundle")
+	}
+	defer archive.Close()
+	manifest, err := readManifest(&archive.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	archiveFiles := make(map[string]*zip.File)
+	for _, archiveFile := range archive.File {
+		archiveFiles[archiveFile.Name] = archiveFile
+	}
+	original, ok := archiveFiles[filesDir+originalFileName]
+	if !ok {
+		return nil, "", errors.Errorf("bundle doesn't contain %s", originalFileName)
+	}
+	filedata, err := readArchiveFile(original)
+	if err != nil {
+		return nil, "", err
+	}
+	resourceDir := launcher_utils.GenerateResourcesDirName(workDir, filedata)
+	if err := os.MkdirAll(resourceDir, 0755); err != nil {
+		return nil, "", errors.Wrap(err, "unable to create directory for resource files")
+	}
+	for _, file := range manifest.Files {
+		if file.Name == "" || file.Name == "." || file.Name == ".." || strings.ContainsAny(file.Name, `/\`) {
+			return nil, "", errors.Errorf("illegal file name %s in bundle manifest", file.Name)
+		}
+		archiveFile, ok := archiveFiles[filesDir+file.Name]
+		if !ok {
+			return nil, "", errors.Errorf("file %s listed in bundle manifest is missing", file.Name)
+		}
+		if err := extractFile(archiveFile, filepath.Join(resourceDir, file.Name), file); err != nil {
+			return nil, "", err
+		}
+	}
+	return manifest, filepath.Join(resourceDir, originalFileName), nil
+}
+
+// ReadManifest returns the manifest of bundle filename
+func ReadManifest(filename string) (*Manifest, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open bundle")
+	}
+	defer archive.Close()
+	return readManifest(&archive.Reader)
+}
+
+func readManifest(archive *zip.Reader) (*Manifest, error) {
+	for _, archiveFile := range archive.File {
+		if archiveFile.Name != manifestName {
+			continue
+		}
+		data, err := readArchiveFile(archiveFile)
+		if err != nil {
+			return nil, err
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, errors.Wrap(err, "unable to parse bundle manifest")
+		}
+		if manifest.FormatVersion != FormatVersion {
+			return nil, errors.Errorf("unsupported bundle format version %d", manifest.FormatVersion)
+		}
+		return &manifest, nil
+	}
+	return nil, errors.New("bundle doesn't contain manifest")
+}
+
+func readArchiveFile(archiveFile *zip.File) ([]byte, error) {
+	reader, err := archiveFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// extractFile writes archiveFile to filename and checks its size and checksum match file from the manifest
+func extractFile(archiveFile *zip.File, filename string, file *File) error {
+	reader, err := archiveFile.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && (size != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.SHA256) {
+		err = errors.New("checksum mismatch")
+	}
+	if err != nil {
+		os.Remove(filename)
+		return errors.Wrapf(err, "unable to extract %s from bundle", file.Name)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	launcher_utils "github.com/rocketsoftware/open-web-launch/launcher/utils"
+)
+
+func Test_Extract(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	resourceDir := filepath.Join(tempDir, "resources")
+	original := []byte("<jnlp><information><title>App</title><offline-allowed/></information></jnlp>")
+	files := map[string][]byte{
+		"original.jnlp": original,
+		"source.url":    []byte("https://host/app.jnlp"),
+		"app.jar":       []byte("jar"),
+	}
+	for name, data := range files {
+		if err := writeFile(filepath.Join(resourceDir, name), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFile(filepath.Join(resourceDir, "native", "lib.so"), []byte("extracted")); err != nil {
+		t.Fatal(err)
+	}
+	bundleFile := filepath.Join(tempDir, "app.zip")
+	if err := writeFile(filepath.Join(resourceDir, "keystore.args"), []byte("-Djavax.net.ssl.keyStorePassword=secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Create(bundleFile, resourceDir, []string{"original.jnlp", "source.url", "app.jar", "app.jar"}, &Manifest{Title: "App"}); err != nil {
+		t.Fatal(err)
+	}
+	tamperedFile := filepath.Join(tempDir, "tampered.zip")
+	if err := tamper(bundleFile, tamperedFile); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		bundle  string
+		wantErr bool
+	}{
+		{"valid", bundleFile, false},
+		{"tampered", tamperedFile, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := filepath.Join(tempDir, tt.name)
+			manifest, jnlpFile, err := Extract(tt.bundle, workDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if manifest.Title != "App" || len(manifest.Files) != len(files) {
+				t.Errorf("Extract() manifest = %+v, want title App and %d files", manifest, len(files))
+			}
+			wantJNLPFile := filepath.Join(launcher_utils.GenerateResourcesDirName(workDir, original), "original.jnlp")
+			if jnlpFile != wantJNLPFile {
+				t.Errorf("Extract() jnlpFile = %s, want %s", jnlpFile, wantJNLPFile)
+			}
+			for name, data := range files {
+				got, err := ioutil.ReadFile(filepath.Join(filepath.Dir(jnlpFile), name))
+				if err != nil || string(got) != string(data) {
+					t.Errorf("Extract() %s = %q, %v, want %q", name, got, err, data)
+				}
+			}
+		})
+	}
+}
+
+func writeFile(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// tamper copies bundle src to dst replacing app.jar with different content
+func tamper(src, dst string) error {
+	manifest, err := ReadManifest(src)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	archive := zip.NewWriter(out)
+	files := map[string][]byte{
+		"files/original.jnlp": []byte("<jnlp><information><title>App</title><offline-allowed/></information></jnlp>"),
+		"files/source.url":    []byte("https://host/app.jnlp"),
+		"files/app.jar":       []byte("malicious jar"),
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	files[manifestName] = data
+	for name, data := range files {
+		writer, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
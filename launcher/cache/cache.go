@@ -0,0 +1,262 @@
+// Package cache manages resource directories of apps in the launcher's working directory.
+// Every app has a directory named after a hash of its JNLP file, the directory contains the original JNLP file,
+// the URL it was downloaded from, JARs, native libraries and icons.
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/launcher/jnlp"
+	"github.com/rocketsoftware/open-web-launch/utils"
+)
+
+const (
+	originalFileName  = "original.jnlp"
+	sourceURLFileName = "source.url"
+)
+
+// Entry is a resource directory of an app
+type Entry struct {
+	Dir       string
+	JNLPFile  string    // Original JNLP file, empty if the directory doesn't have it
+	Title     string    // Title of the app, empty if the JNLP file is missing or invalid
+	SourceURL string    // URL the JNLP file was downloaded from, empty for local files
+	Size      int64     // Total size of files in bytes
+	LastUsed  time.Time // The original JNLP file is saved on every launch, so its modification time is the time of last use
+	Installed bool      // The JNLP file belongs to an installed app with shortcuts, file associations or a Control Panel entry
+}
+
+// PruneOptions select entries removed by Prune, an entry is removed if it matches any of the options.
+// Entries of installed apps are never pruned.
+type PruneOptions struct {
+	OlderThan time.Duration // Remove entries not used for this duration, 0 disables the check
+	MaxSize   int64         // Remove least recently used entries until total size is not greater than MaxSize, 0 disables the check
+	Orphaned  bool          // Remove entries whose JNLP file doesn't belong to any installed app
+}
+
+// loadInstalledApps returns installed apps, tests replace it
+var loadInstalledApps = utils.LoadInstalledApps
+
+// List returns entries of workDir sorted by time of last use, most recently used first
+func List(workDir string) ([]*Entry, error) {
+	files, err := ioutil.ReadDir(workDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read cache directory")
+	}
+	installedFiles, err := getInstalledJNLPFiles()
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		entry, err := readEntry(filepath.Join(workDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if entry.LastUsed.IsZero() {
+			entry.LastUsed = file.ModTime()
+		}
+		if entry.JNLPFile != "" {
+			if stat, err := os.Stat(entry.JNLPFile); err == nil {
+				for _, installedFile := range installedFiles {
+					if os.SameFile(stat, installedFile) {
+						entry.Installed = true
+					}
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// getInstalledJNLPFiles returns original JNLP files of installed apps which exist
+func getInstalledJNLPFiles() ([]os.FileInfo, error) {
+	apps, err := loadInstalledApps()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find installed apps")
+	}
+	var files []os.FileInfo
+	for _, app := range apps {
+		if jnlpFile := app.JNLPFile(); jnlpFile != "" {
+			if stat, err := os.Stat(jnlpFile); err == nil {
+				files = append(files, stat)
+			}
+		}
+	}
+	return files, nil
+}
+
+func readEntry(dir string) (*Entry, error) {
+	entry := &Entry{Dir: dir}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			entry.Size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read cache directory %s", dir)
+	}
+	jnlpFile := filepath.Join(dir, originalFileName)
+	if stat, err := os.Stat(jnlpFile); err == nil {
+		entry.JNLPFile = jnlpFile
+		entry.LastUsed = stat.ModTime()
+		if decoded, err := jnlp.DecodeFile(jnlpFile); err == nil {
+			entry.Title = decoded.Title()
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, sourceURLFileName)); err == nil {
+		entry.SourceURL = strings.TrimSpace(string(data))
+	}
+	return entry, nil
+}
+
+// TotalSize returns total size of entries in bytes
+func TotalSize(entries []*Entry) int64 {
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	return size
+}
+
+// Remove deletes the directory of entry
+func Remove(entry *Entry) error {
+	if err := os.RemoveAll(entry.Dir); err != nil {
+		return errors.Wrapf(err, "unable to remove cache directory %s", entry.Dir)
+	}
+	return nil
+}
+
+// Prune removes entries of workDir selected by options and returns them
+func Prune(workDir string, options *PruneOptions) ([]*Entry, error) {
+	entries, err := List(workDir)
+	if err != nil {
+		return nil, err
+	}
+	pruned := selectForPruning(entries, options, time.Now())
+	for _, entry := range pruned {
+		if err := Remove(entry); err != nil {
+			return nil, err
+		}
+	}
+	return pruned, nil
+}
+
+// Clear removes all entries of workDir and returns them. Installed apps are uninstalled by uninstall first,
+// so their shortcuts, file associations and Control Panel entries don't refer to removed files.
+func Clear(workDir string, uninstall func(entry *Entry) error) ([]*Entry, error) {
+	entries, err := List(workDir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []*Entry
+	for _, entry := range entries {
+		if entry.Installed {
+			if err := uninstall(entry); err != nil {
+				return removed, errors.Wrapf(err, "unable to uninstall %s", entry.Title)
+			}
+		}
+		if err := Remove(entry); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// selectForPruning returns entries matching options, entries must be sorted by List
+func selectForPruning(entries []*Entry, options *PruneOptions, now time.Time) []*Entry {
+	selected := make(map[*Entry]bool)
+	if options.Orphaned {
+		for _, entry := range entries {
+			if !entry.Installed {
+				selected[entry] = true
+			}
+		}
+	}
+	if options.OlderThan > 0 {
+		for _, entry := range entries {
+			if !entry.Installed && now.Sub(entry.LastUsed) > options.OlderThan {
+				selected[entry] = true
+			}
+		}
+	}
+	if options.MaxSize > 0 {
+		var size int64
+		for _, entry := range entries {
+			if selected[entry] {
+				continue
+			}
+			if !entry.Installed && size+entry.Size > options.MaxSize {
+				selected[entry] = true
+				continue
+			}
+			size += entry.Size
+		}
+	}
+	var pruned []*Entry
+	for _, entry := range entries {
+		if selected[entry] {
+			pruned = append(pruned, entry)
+		}
+	}
+	return pruned
+}
+
+// ParseSize parses size like 500MB, 2G or 1024
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30}, {"G", 1 << 30},
+		{"MB", 1 << 20}, {"M", 1 << 20},
+		{"KB", 1 << 10}, {"K", 1 << 10},
+		{"B", 1},
+	}
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, errors.Errorf("invalid size %q", s)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// FormatSize formats size in bytes like 1.5 MB
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return strconv.FormatFloat(float64(size)/(1<<30), 'f', 1, 64) + " GB"
+	case size >= 1<<20:
+		return strconv.FormatFloat(float64(size)/(1<<20), 'f', 1, 64) + " MB"
+	case size >= 1<<10:
+		return strconv.FormatFloat(float64(size)/(1<<10), 'f', 1, 64) + " KB"
+	}
+	return strconv.FormatInt(size, 10) + " B"
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rocketsoftware/open-web-launch/utils"
+)
+
+func Test_selectForPruning(t *testing.T) {
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	// sorted by time of last use as returned by List
+	entries := []*Entry{
+		{Dir: "new", Title: "App", SourceURL: "https://host/app.jnlp", Size: 300, LastUsed: now.Add(-day), Installed: true},
+		{Dir: "local", Title: "Local App", Size: 200, LastUsed: now.Add(-2 * day)},
+		{Dir: "old", Title: "App", SourceURL: "https://host/app.jnlp", Size: 100, LastUsed: now.Add(-10 * day)},
+		{Dir: "broken", Size: 10, LastUsed: now.Add(-20 * day)},
+		{Dir: "installed", Title: "Installed App", Size: 50, LastUsed: now.Add(-30 * day), Installed: true},
+		{Dir: "unused", Title: "Unused App", Size: 400, LastUsed: now.Add(-60 * day)},
+	}
+	tests := []struct {
+		name    string
+		options *PruneOptions
+		want    []string
+	}{
+		{"nothing", &PruneOptions{}, nil},
+		{"orphaned", &PruneOptions{Orphaned: true}, []string{"local", "old", "broken", "unused"}},
+		{"older than", &PruneOptions{OlderThan: 15 * day}, []string{"broken", "unused"}},
+		{"max size", &PruneOptions{MaxSize: 550}, []string{"old", "unused"}},
+		{"older than and max size", &PruneOptions{OlderThan: 15 * day, MaxSize: 900}, []string{"broken", "unused"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range selectForPruning(entries, tt.options, now) {
+				got = append(got, entry.Dir)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectForPruning() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Clear(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+	jnlpData := []byte(`<jnlp><information><title>App</title></information></jnlp>`)
+	for _, dir := range []string{"installed", "orphaned"} {
+		if err := os.MkdirAll(filepath.Join(workDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(workDir, dir, originalFileName), jnlpData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	installedFile := filepath.Join(workDir, "installed", originalFileName)
+	defer func(load func() ([]*utils.AppInfo, error)) { loadInstalledApps = load }(loadInstalledApps)
+	loadInstalledApps = func() ([]*utils.AppInfo, error) {
+		return []*utils.AppInfo{{Title: "App", UninstallCommand: []string{"openweblaunch", "-uninstall", "-gui", installedFile}}}, nil
+	}
+	pruned, err := Prune(workDir, &PruneOptions{Orphaned: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || filepath.Base(pruned[0].Dir) != "orphaned" {
+		t.Errorf("Prune() removed %v, want orphaned directory", pruned)
+	}
+	var uninstalled []string
+	removed, err := Clear(workDir, func(entry *Entry) error {
+		uninstalled = append(uninstalled, entry.JNLPFile)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || !reflect.DeepEqual(uninstalled, []string{installedFile}) {
+		t.Errorf("Clear() removed %v and uninstalled %v, want installed app uninstalled", removed, uninstalled)
+	}
+	if _, err := os.Stat(filepath.Dir(installedFile)); !os.IsNotExist(err) {
+		t.Errorf("Clear() didn't remove directory of installed app")
+	}
+}
+
+func Test_ParseSize(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"500MB", 500 << 20, false},
+		{"1.5g", 3 << 29, false},
+		{"10 KB", 10 << 10, false},
+		{"MB", 0, true},
+		{"-1", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseSize(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package compat provides Java compatibility profiles.
+// A profile adds module system options like --add-opens and --add-exports
+// to legacy Java Web Start applications running on Java 9 and later
+// so they can be fixed without editing vendor JNLP files.
+package compat
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	launcher_utils "github.com/rocketsoftware/open-web-launch/launcher/utils"
+	"github.com/rocketsoftware/open-web-launch/verifier"
+)
+
+// Profiles is a content of a compatibility profiles file
+type Profiles struct {
+	Profiles []*Profile `json:"profiles"`
+}
+
+// Profile describes JVM options applied to matching applications.
+// A profile without URL, Title and Signer is global and applies to every application.
+type Profile struct {
+	Name           string   `json:"name"`                     // Name of the profile, used for logging
+	URL            string   `json:"url,omitempty"`            // Pattern for JNLP URL, '*' matches any sequence of characters
+	Title          string   `json:"title,omitempty"`          // Pattern for application title
+	Signer         string   `json:"signer,omitempty"`         // SHA-256 fingerprint of the certificate used for signing JARs
+	MinJavaVersion int      `json:"minJavaVersion,omitempty"` // Minimum Java feature version the profile applies to, e.g. 9
+	MaxJavaVersion int      `json:"maxJavaVersion,omitempty"` // Maximum Java feature version the profile applies to, 0 means no limit
+	AddOpens       []string `json:"addOpens,omitempty"`       // Values for --add-opens, e.g. java.desktop/javax.swing=ALL-UNNAMED
+	AddExports     []string `json:"addExports,omitempty"`     // Values for --add-exports, e.g. java.desktop/sun.awt=ALL-UNNAMED
+	IllegalAccess  string   `json:"illegalAccess,omitempty"`  // Value for --illegal-access: permit, warn, debug or deny
+	JVMArgs        []string `json:"jvmArgs,omitempty"`        // Any other JVM options
+}
+
+// App identifies an application for profile matching
+type App struct {
+	URL    string // URL of JNLP file
+	Title  string // Application title
+	Signer string // SHA-256 fingerprint of signer certificate, may be empty
+}
+
+const (
+	firstModularJavaVersion      = 9
+	lastIllegalAccessJavaVersion = 16
+)
+
+// Load reads compatibility profiles from filename.
+// Missing file is not an error, no profiles are returned in that case.
+func Load(filename string) ([]*Profile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read compatibility profiles file %s", filename)
+	}
+	var profiles Profiles
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse compatibility profiles file %s", filename)
+	}
+	return profiles.Profiles, nil
+}
+
+// Matches returns true if profile should be applied to app running with Java javaVersion
+func (profile *Profile) Matches(app *App, javaVersion int) bool {
+	if profile.MinJavaVersion != 0 && javaVersion < profile.MinJavaVersion {
+		return false
+	}
+	if profile.MaxJavaVersion != 0 && javaVersion > profile.MaxJavaVersion {
+		return false
+	}
+	if profile.URL != "" && !launcher_utils.MatchPattern(profile.URL, app.URL) {
+		return false
+	}
+	if profile.Title != "" && !launcher_utils.MatchPattern(profile.Title, app.Title) {
+		return false
+	}
+	if profile.Signer != "" {
+		if app.Signer == "" || verifier.NormalizeFingerprint(profile.Signer) != verifier.NormalizeFingerprint(app.Signer) {
+			return false
+		}
+	}
+	return true
+}
+
+// Args returns JVM options of the profile which are valid for Java javaVersion.
+// Module system options are skipped for Java 8 and earlier because such JVMs refuse to start with them.
+func (profile *Profile) Args(javaVersion int) []string {
+	var args []string
+	if javaVersion >= firstModularJavaVersion {
+		for _, value := range profile.AddOpens {
+			args = append(args, "--add-opens="+value)
+		}
+		for _, value := range profile.AddExports {
+			args = append(args, "--add-exports="+value)
+		}
+		if profile.IllegalAccess != "" && javaVersion <= lastIllegalAccessJavaVersion {
+			args = append(args, "--illegal-access="+strings.ToLower(profile.IllegalAccess))
+		}
+	}
+	args = append(args, profile.JVMArgs...)
+	return args
+}
+
+// Args returns JVM options of all profiles matching app and names of the matched profiles
+func Args(profiles []*Profile, app *App, javaVersion int) (args []string, matched []string) {
+	for _, profile := range profiles {
+		if !profile.Matches(app, javaVersion) {
+			continue
+		}
+		args = append(args, profile.Args(javaVersion)...)
+		matched = append(matched, profile.Name)
+	}
+	return args, matched
+}
+
+// NeedsSigner returns true if any of profiles is matched by signer certificate
+func NeedsSigner(profiles []*Profile) bool {
+	for _, profile := range profiles {
+		if profile.Signer != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package compat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Args(t *testing.T) {
+	profiles := []*Profile{
+		{
+			Name:           "global",
+			MinJavaVersion: 9,
+			AddOpens:       []string{"java.base/java.lang=ALL-UNNAMED"},
+			IllegalAccess:  "Permit",
+		},
+		{
+			Name:       "intranet",
+			URL:        "https://intranet/*",
+			AddExports: []string{"java.desktop/sun.awt=ALL-UNNAMED"},
+		},
+		{
+			Name:    "signer",
+			Signer:  "ab:cd:ef",
+			JVMArgs: []string{"-Xmx1g"},
+		},
+	}
+	type args struct {
+		app         *App
+		javaVersion int
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantArgs    []string
+		wantMatched []string
+	}{
+		{"java 8", args{&App{URL: "https://intranet/app.jnlp"}, 8}, nil, []string{"intranet"}},
+		{"java 11", args{&App{URL: "https://internet/app.jnlp"}, 11}, []string{"--add-opens=java.base/java.lang=ALL-UNNAMED", "--illegal-access=permit"}, []string{"global"}},
+		{"java 17", args{&App{URL: "https://INTRANET/app.jnlp"}, 17}, []string{"--add-opens=java.base/java.lang=ALL-UNNAMED", "--add-exports=java.desktop/sun.awt=ALL-UNNAMED"}, []string{"global", "intranet"}},
+		{"signer", args{&App{URL: "file:///app.jnlp", Signer: "ABCDEF"}, 8}, []string{"-Xmx1g"}, []string{"signer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotArgs, gotMatched := Args(profiles, tt.args.app, tt.args.javaVersion)
+			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("Args() args = %v, want %v", gotArgs, tt.wantArgs)
+			}
+			if !reflect.DeepEqual(gotMatched, tt.wantMatched) {
+				t.Errorf("Args() matched = %v, want %v", gotMatched, tt.wantMatched)
+			}
+		})
+	}
+}
@@ -5,21 +5,26 @@ import (
 	"encoding/xml"
 	"io/ioutil"
 	"net/url"
+	"path"
+	"sort"
+	"strings"
 
 	launcher_utils "github.com/rocketsoftware/open-web-launch/launcher/utils"
+	"github.com/rocketsoftware/open-web-launch/settings"
 	"github.com/rocketsoftware/open-web-launch/utils/log"
 )
 
 // JNLP is a main xml element for a jnlp file
 type JNLP struct {
-	CodeBase          string       `xml:"codebase,attr"`
-	Spec              string       `xml:"spec,attr,omitempty"`
-	Href              string       `xml:"href,attr,omitempty"`
-	Version           string       `xml:"version,attr,omitempty"`
-	Information       *Information `xml:"information"`
-	Resources         []*Resources `xml:"resources"`
-	AppDescription    *AppDesc     `xml:"application-desc"`
-	AppletDescription *AppletDesc  `xml:"applet-desc"`
+	CodeBase            string         `xml:"codebase,attr"`
+	Spec                string         `xml:"spec,attr,omitempty"`
+	Href                string         `xml:"href,attr,omitempty"`
+	Version             string         `xml:"version,attr,omitempty"`
+	InformationElements []*Information `xml:"information"`
+	Information         *Information   `xml:"-"` // Information elements relevant for current platform and locale merged together
+	Resources           []*Resources   `xml:"resources"`
+	AppDescription      *AppDesc       `xml:"application-desc"`
+	AppletDescription   *AppletDesc    `xml:"applet-desc"`
 }
 
 // Resources that are needed for an application
@@ -87,6 +92,7 @@ type Information struct {
 	Desktop        *xml.Name       `xml:"desktop"`                   // Can be used to indicate the RIA's preference for putting a shortcut on the user's desktop
 	Menu           *Menu           `xml:"menu,omitempty"`            // Can be used to indicate the RIA's preference for putting a menu item in the user's start menus
 	OfflineAllowed *OfflineAllowed `xml:"offline-allowed,omitempty"` // Indicates that this application can operate when the client system is disconnected from the network.
+	Associations   []*Association  `xml:"association,omitempty"`     // Documents the RIA wants to be registered as the handler of
 	Version        string          `xml:"version,omitempty"`         // Application version
 }
 
@@ -105,9 +111,33 @@ type Description struct {
 type Icon struct {
 	Href       string `xml:"href,attr"`                 // A URL pointing to the icon file. Can be in one of the following formats: gif, jpg, png, ico.
 	Kind       string `xml:"kind,attr,omitempty"`       // Indicates the suggested use of the icon, can be: default, selected, disabled, rollover, splash, or shortcut.
+	Width      int    `xml:"width,attr,omitempty"`      // Can be used to indicate the resolution of the image
+	Height     int    `xml:"height,attr,omitempty"`     // Can be used to indicate the resolution of the image
+	Depth      int    `xml:"depth,attr,omitempty"`      // Describes the color depth of the image
+	Size       int64  `xml:"size,attr,omitempty"`       // Indicates the size of the icon file in bytes
 	Downloaded bool   `xml:"downloaded,attr,omitempty"` // For internal usage: indicates that the icon successfully downloaded
 }
 
+// Association can be used to hint that the RIA wishes to be registered with the operating system
+// as the primary handler of certain extensions and a certain mime-type
+type Association struct {
+	MimeType    string `xml:"mime-type,attr"`        // The mime-type of documents handled by the RIA
+	Extensions  string `xml:"extensions,attr"`       // A space separated list of file extensions handled by the RIA
+	Description string `xml:"description,omitempty"` // A description of the file type
+	Icon        *Icon  `xml:"icon,omitempty"`        // An icon to be used for files of the type
+}
+
+// ExtensionList returns extensions of the association without leading dots
+func (association *Association) ExtensionList() []string {
+	var extensions []string
+	for _, extension := range strings.FieldsFunc(association.Extensions, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if extension = strings.TrimPrefix(extension, "."); extension != "" {
+			extensions = append(extensions, extension)
+		}
+	}
+	return extensions
+}
+
 // Shortcut can be used to indicate the RIA's preference for putting a shortcut on the user's desktop
 type Shortcut struct {
 	Online  bool      `xml:"online,attr,omitempty"` // Can be used to describe the RIA's preference for creating a shortcut to run online or offline.
@@ -152,40 +182,137 @@ func Decode(data []byte) (*JNLP, error) {
 	if err := xml.Unmarshal(data, &jnlp); err != nil {
 		return nil, err
 	}
+	jnlp.Information = jnlp.mergeRelevantInformation(settings.Locale())
 	return &jnlp, nil
 }
 
 // DecodeFile decodes JNLP file
 func DecodeFile(filename string) (*JNLP, error) {
-	var jnlp JNLP
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	if err := xml.Unmarshal(data, &jnlp); err != nil {
-		return nil, err
-	}
-	return &jnlp, nil
+	return Decode(data)
 }
 
-// RelevantForCurrentPlatform returns true if resources are actual for current platform
+// RelevantForCurrentPlatform returns true if resources are actual for current platform and user's locale
 func (resources *Resources) RelevantForCurrentPlatform() bool {
-	return launcher_utils.AreResourcesRelevantForCurrentPlatform(resources.OS, resources.Arch)
+	return launcher_utils.AreResourcesRelevantForCurrentPlatform(resources.OS, resources.Arch) &&
+		launcher_utils.IsLocaleRelevant(resources.Locale, settings.Locale())
 }
 
 func (jnlp *JNLP) findRelevantResources() []*Resources {
 	var relevantResources []*Resources
 	for _, resources := range jnlp.Resources {
 		if resources.RelevantForCurrentPlatform() {
-			log.Printf("resources for os='%s' arch='%s' are relevant on current platform", resources.OS, resources.Arch)
+			log.Printf("resources for os='%s' arch='%s' locale='%s' are relevant on current platform", resources.OS, resources.Arch, resources.Locale)
 			relevantResources = append(relevantResources, resources)
 		} else {
-			log.Printf("resources for os='%s' arch='%s' are not relevant on current platform", resources.OS, resources.Arch)
+			log.Printf("resources for os='%s' arch='%s' locale='%s' are not relevant on current platform", resources.OS, resources.Arch, resources.Locale)
 		}
 	}
 	return relevantResources
 }
 
+// relevantFor returns true if information element should be considered on current platform for userLocale
+func (info *Information) relevantFor(userLocale string) bool {
+	if !launcher_utils.AreResourcesRelevantForCurrentPlatform(info.OS, info.Arch) {
+		return false
+	}
+	if !launcher_utils.IsLocaleRelevant(info.Locale, userLocale) {
+		return false
+	}
+	if info.Platform != "" {
+		requiredVersion, err := settings.ParseJavaVersion(info.Platform)
+		if err != nil {
+			log.Printf("warning: unable to parse platform='%s' of information element: %v", info.Platform, err)
+			return true
+		}
+		if _, err := settings.GetJavaVersion(); err != nil {
+			return true
+		}
+		return settings.CurrentJavaVersionMatches(requiredVersion)
+	}
+	return true
+}
+
+// specificity returns how specific information element is for userLocale,
+// more specific elements take precedence while merging
+func (info *Information) specificity(userLocale string) int {
+	specificity := 0
+	for _, attr := range []string{info.OS, info.Arch, info.Platform} {
+		if attr != "" {
+			specificity++
+		}
+	}
+	// a matching locale is more important than os, arch and platform
+	return specificity + 4*launcher_utils.LocaleMatch(info.Locale, userLocale)
+}
+
+// mergeRelevantInformation merges information elements relevant for current platform and userLocale.
+// Generic elements are used as defaults which are overridden by elements with os, arch, platform or locale attributes,
+// an element for an exactly matching locale has the highest precedence.
+func (jnlp *JNLP) mergeRelevantInformation(userLocale string) *Information {
+	var relevantInformation []*Information
+	for _, info := range jnlp.InformationElements {
+		if info.relevantFor(userLocale) {
+			log.Printf("information for os='%s' arch='%s' platform='%s' locale='%s' is relevant", info.OS, info.Arch, info.Platform, info.Locale)
+			relevantInformation = append(relevantInformation, info)
+		}
+	}
+	sort.SliceStable(relevantInformation, func(i, j int) bool {
+		return relevantInformation[i].specificity(userLocale) < relevantInformation[j].specificity(userLocale)
+	})
+	merged := &Information{}
+	for _, info := range relevantInformation {
+		merged.merge(info)
+	}
+	return merged
+}
+
+// merge overrides values of info with non-empty values of other
+func (info *Information) merge(other *Information) {
+	if other.Title != "" {
+		info.Title = other.Title
+	}
+	if other.Vendor != "" {
+		info.Vendor = other.Vendor
+	}
+	if other.Version != "" {
+		info.Version = other.Version
+	}
+	if other.Homepage != nil {
+		info.Homepage = other.Homepage
+	}
+	for _, description := range other.Descriptions {
+		replaced := false
+		for i, existingDescription := range info.Descriptions {
+			if existingDescription.Kind == description.Kind {
+				info.Descriptions[i] = description
+				replaced = true
+			}
+		}
+		if !replaced {
+			info.Descriptions = append(info.Descriptions, description)
+		}
+	}
+	// icons of a more specific element are preferred
+	info.Icons = append(append([]*Icon{}, other.Icons...), info.Icons...)
+	if other.Shortcut != nil {
+		info.Shortcut = other.Shortcut
+	}
+	if other.Desktop != nil {
+		info.Desktop = other.Desktop
+	}
+	if other.Menu != nil {
+		info.Menu = other.Menu
+	}
+	if other.OfflineAllowed != nil {
+		info.OfflineAllowed = other.OfflineAllowed
+	}
+	info.Associations = append(info.Associations, other.Associations...)
+}
+
 func (jnlp *JNLP) getJars() ([]string, error) {
 	var urls []string
 	codebaseURL, err := launcher_utils.ParseCodebaseURL(jnlp.CodeBase)
@@ -227,7 +354,7 @@ func (jnlp *JNLP) getNativeLibs() ([]string, error) {
 }
 
 // Title returns title of JNLP application
-// or empty string if Information tag not found
+// or empty string if no relevant Information tag found
 func (jnlp *JNLP) Title() string {
 	if jnlp.Information != nil {
 		return jnlp.Information.Title
@@ -244,3 +371,77 @@ func (resources *Resources) getJ2SE() *J2SE {
 	}
 	return nil
 }
+
+// ExpandVariables replaces $$codebase, $$context, $$site, $$name and $$hostname placeholders
+// in codebase, hrefs, properties and arguments using source - URL the JNLP file was downloaded from.
+// For source http://host:8080/app/sub/launch.jnlp the placeholders are expanded to
+// http://host:8080/app/sub/, http://host:8080/app, http://host:8080, launch.jnlp and host respectively.
+func (jnlp *JNLP) ExpandVariables(source *url.URL) {
+	if source == nil {
+		return
+	}
+	replacer := newVariableReplacer(source)
+	expand := func(s *string) {
+		if strings.Contains(*s, "$$") {
+			*s = replacer.Replace(*s)
+		}
+	}
+	expand(&jnlp.CodeBase)
+	expand(&jnlp.Href)
+	for _, resources := range jnlp.Resources {
+		for _, jar := range resources.JARs {
+			expand(&jar.Href)
+		}
+		for _, nativeLib := range resources.NativeLibs {
+			expand(&nativeLib.Href)
+		}
+		for _, extension := range resources.Extensions {
+			expand(&extension.Href)
+		}
+		for i := range resources.Properties {
+			expand(&resources.Properties[i].Value)
+		}
+		if j2se := resources.getJ2SE(); j2se != nil {
+			expand(&j2se.JavaVMArgs)
+		}
+	}
+	if info := jnlp.Information; info != nil {
+		if info.Homepage != nil {
+			expand(&info.Homepage.Href)
+		}
+		for _, icon := range info.Icons {
+			expand(&icon.Href)
+		}
+	}
+	if appDesc := jnlp.AppDescription; appDesc != nil {
+		for i := range appDesc.Arguments {
+			expand(&appDesc.Arguments[i])
+		}
+	}
+	if appletDesc := jnlp.AppletDescription; appletDesc != nil {
+		expand(&appletDesc.DocumentBase)
+		for i := range appletDesc.Params {
+			expand(&appletDesc.Params[i].Value)
+		}
+	}
+}
+
+func newVariableReplacer(source *url.URL) *strings.Replacer {
+	site := source.Scheme + "://" + source.Host
+	dir, name := path.Split(source.Path)
+	if source.Scheme == "file" {
+		site = source.Scheme + "://"
+	}
+	codebase := site + dir
+	context := site
+	if segments := strings.SplitN(strings.TrimPrefix(dir, "/"), "/", 2); len(segments) == 2 && segments[0] != "" {
+		context = site + "/" + segments[0]
+	}
+	return strings.NewReplacer(
+		"$$codebase", codebase,
+		"$$context", context,
+		"$$site", site,
+		"$$name", name,
+		"$$hostname", source.Hostname(),
+	)
+}
@@ -0,0 +1,201 @@
+package jnlp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ExpandVariables(t *testing.T) {
+	data := []byte(`<jnlp codebase="$$codebase" href="$$name">
+		<resources>
+			<jar href="$$context/lib/app.jar"/>
+			<property name="server" value="$$hostname"/>
+		</resources>
+		<application-desc main-class="Main">
+			<argument>$$site/api</argument>
+		</application-desc>
+	</jnlp>`)
+	jnlpFile, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, _ := url.Parse("https://apps.example.com:8443/webapp/start/launch.jnlp")
+	jnlpFile.ExpandVariables(source)
+	got := []string{
+		jnlpFile.CodeBase,
+		jnlpFile.Href,
+		jnlpFile.Resources[0].JARs[0].Href,
+		jnlpFile.Resources[0].Properties[0].Value,
+		jnlpFile.AppDescription.Arguments[0],
+	}
+	want := []string{
+		"https://apps.example.com:8443/webapp/start/",
+		"launch.jnlp",
+		"https://apps.example.com:8443/webapp/lib/app.jar",
+		"apps.example.com",
+		"https://apps.example.com:8443/api",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandVariables() = %v, want %v", got, want)
+	}
+}
+
+func Test_mergeRelevantInformation(t *testing.T) {
+	data := []byte(`<jnlp codebase="https://host/app/">
+		<information>
+			<title>App</title>
+			<vendor>Vendor</vendor>
+			<description>Application</description>
+			<icon href="default.png"/>
+		</information>
+		<information locale="de">
+			<title>Anwendung</title>
+			<description>Deutsche Anwendung</description>
+		</information>
+		<information locale="de_AT">
+			<title>Anwendung AT</title>
+			<icon href="at.png"/>
+		</information>
+		<information locale="fr">
+			<title>Application</title>
+		</information>
+		<information os="NoSuchOS">
+			<vendor>Other Vendor</vendor>
+		</information>
+	</jnlp>`)
+	jnlpFile, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		userLocale      string
+		wantTitle       string
+		wantVendor      string
+		wantDescription string
+		wantFirstIcon   string
+	}{
+		{"en_US", "App", "Vendor", "Application", "default.png"},
+		{"de_DE", "Anwendung", "Vendor", "Deutsche Anwendung", "default.png"},
+		{"de_AT", "Anwendung AT", "Vendor", "Deutsche Anwendung", "at.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userLocale, func(t *testing.T) {
+			info := jnlpFile.mergeRelevantInformation(tt.userLocale)
+			got := []string{info.Title, info.Vendor, info.Descriptions[0].Text, info.Icons[0].Href}
+			want := []string{tt.wantTitle, tt.wantVendor, tt.wantDescription, tt.wantFirstIcon}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("mergeRelevantInformation() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_Association_ExtensionList(t *testing.T) {
+	tests := []struct {
+		extensions string
+		want       []string
+	}{
+		{"txt", []string{"txt"}},
+		{"doc docx", []string{"doc", "docx"}},
+		{".csv, .tsv", []string{"csv", "tsv"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.extensions, func(t *testing.T) {
+			association := &Association{Extensions: tt.extensions}
+			if got := association.ExtensionList(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtensionList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_javaProxyArgs(t *testing.T) {
+	proxy := &url.URL{Scheme: "http", Host: "proxy:3128"}
+	tests := []struct {
+		name       string
+		httpProxy  *url.URL
+		httpsProxy *url.URL
+		noProxy    []string
+		want       []string
+	}{
+		{"direct", nil, nil, []string{"example.com"}, nil},
+		{"same proxy", proxy, proxy, nil, []string{
+			"-Dhttp.proxyHost=proxy", "-Dhttp.proxyPort=3128", "-Dhttps.proxyHost=proxy", "-Dhttps.proxyPort=3128",
+		}},
+		{"default port", &url.URL{Scheme: "http", Host: "proxy"}, nil, nil, []string{"-Dhttp.proxyHost=proxy", "-Dhttp.proxyPort=80"}},
+		{"socks", &url.URL{Scheme: "socks5", Host: "socks"}, &url.URL{Scheme: "socks5", Host: "socks"}, nil, []string{
+			"-DsocksProxyHost=socks", "-DsocksProxyPort=1080",
+		}},
+		{"bypass", proxy, nil, []string{"example.com", ".corp.com", "*.lab.com", "10.0.0.0/8", "10.1.1.1", "build:8080"}, []string{
+			"-Dhttp.proxyHost=proxy", "-Dhttp.proxyPort=3128",
+			"-Dhttp.nonProxyHosts=localhost|127.*|[::1]|example.com|*.example.com|*.corp.com|*.lab.com|10.1.1.1|build|*.build",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := javaProxyArgs(tt.httpProxy, tt.httpsProxy, tt.noProxy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("javaProxyArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_redactJavaArgs(t *testing.T) {
+	args := []string{"-Djavax.net.ssl.keyStore=client.p12", "-Djavax.net.ssl.keyStorePassword=secret", "-Dapp.password=secret", "-password", "Main"}
+	want := []string{"-Djavax.net.ssl.keyStore=client.p12", "-Djavax.net.ssl.keyStorePassword=********", "-Dapp.password=********", "-password", "Main"}
+	if got := redactJavaArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactJavaArgs() = %v, want %v", got, want)
+	}
+}
+
+func Test_readCachedJNLPFile(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "jnlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+	rawurl := "https://example.com/app.jnlp"
+	now := time.Now()
+	apps := []struct {
+		dir       string
+		sourceURL string
+		lastUsed  time.Time
+	}{
+		{"old", rawurl, now.Add(-2 * time.Hour)},
+		{"recent", rawurl, now.Add(-time.Hour)},
+		{"other", "https://example.com/other.jnlp", now},
+	}
+	for _, app := range apps {
+		dir := filepath.Join(workDir, app.dir)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "source.url"), []byte(app.sourceURL), 0644); err != nil {
+			t.Fatal(err)
+		}
+		originalFile := filepath.Join(dir, "original.jnlp")
+		if err := ioutil.WriteFile(originalFile, []byte(app.dir), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(originalFile, app.lastUsed, app.lastUsed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	launcher := &Launcher{WorkDir: workDir}
+	data, err := launcher.readCachedJNLPFile(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "recent" {
+		t.Errorf("readCachedJNLPFile() = %q, want %q", data, "recent")
+	}
+	if _, err := launcher.readCachedJNLPFile("https://example.com/missing.jnlp"); err == nil {
+		t.Error("readCachedJNLPFile() error = nil, want error")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/url"
 	"os"
 	"os/exec"
@@ -11,8 +12,12 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rocketsoftware/open-web-launch/launcher"
+	"github.com/rocketsoftware/open-web-launch/launcher/bundle"
+	"github.com/rocketsoftware/open-web-launch/launcher/compat"
+	"github.com/rocketsoftware/open-web-launch/launcher/policy"
 	launcher_utils "github.com/rocketsoftware/open-web-launch/launcher/utils"
 
 	"github.com/pkg/errors"
@@ -20,11 +25,12 @@ import (
 	"github.com/rocketsoftware/open-web-launch/settings"
 	"github.com/rocketsoftware/open-web-launch/utils"
 	"github.com/rocketsoftware/open-web-launch/utils/download"
+	"github.com/rocketsoftware/open-web-launch/utils/icon"
 	"github.com/rocketsoftware/open-web-launch/utils/log"
 	"github.com/rocketsoftware/open-web-launch/verifier"
 )
 
-var errCancelled = errors.New("cancelled by user")
+var errCancelled = launcher.WithCode(errors.New("cancelled by user"), launcher.ErrorCancelled)
 
 // Launcher is a JNLP Launcher
 type Launcher struct {
@@ -32,6 +38,7 @@ type Launcher struct {
 	WindowTitle       string // Title of GUI Window
 	jnlp              *JNLP
 	jnlpOld           *JNLP
+	jnlpOldFile       string // Original JNLP file of jnlpOld, it identifies the app installed before the update
 	filedata          []byte
 	resourceDir       string
 	relevantResources []*Resources
@@ -41,6 +48,19 @@ type Launcher struct {
 	options           *launcher.Options
 	cert              []byte
 	logFile           string
+	sourceURL         *url.URL // URL the JNLP file was downloaded from, file:// URL for local files
+	shortcutIcon      *string  // Shortcut icon converted by getShortcutIcon
+	offline           bool     // Resources are not downloaded, cached ones are used
+	policy            *policy.Policy
+	policyRule        *policy.Rule // Rule of the policy applied to the application
+	noVerification    bool         // JARs are not verified, it is decided by decideVerification before downloading
+	java              string       // Java executable selected by the policy rule, settings.Java() is used if it is empty
+	javaVersion       *settings.JavaVersion
+	tempDir           string // Directory of temporary argument files, removed after Java starts
+	progressMutex     sync.Mutex
+	phase             string // Phase of the run reported to the observer
+	progressSteps     int
+	progressMax       int
 }
 
 // New creates a new JNLP Launcher
@@ -76,7 +96,26 @@ func (launcher *Launcher) RunByFilename(filename string) error {
 	return launcher.runByFilenameOrURL(filename, false)
 }
 
+// reset clears state of the previous run, so one launcher can run several applications in a session with the browser
+func (launcher *Launcher) reset() {
+	*launcher = Launcher{
+		WorkDir:     launcher.WorkDir,
+		WindowTitle: launcher.WindowTitle,
+		options:     launcher.options,
+		logFile:     launcher.logFile,
+	}
+}
+
 func (launcher *Launcher) runByFilenameOrURL(filenameOrURL string, isURL bool) error {
+	launcher.reset()
+	if launcher.options != nil && launcher.options.Silent {
+		launcher.gui = nil
+		if err := launcher.proccessFilenameOrURL(filenameOrURL, isURL); err != nil {
+			launcher.notifyFailed(err)
+			return err
+		}
+		return launcher.waitIfNeeded()
+	}
 	launcher.gui = gui.New()
 	launcher.gui.SetLogFile(launcher.logFile)
 	var wg sync.WaitGroup
@@ -86,6 +125,7 @@ func (launcher *Launcher) runByFilenameOrURL(filenameOrURL string, isURL bool) e
 		launcher.gui.WaitForWindow()
 		if err := launcher.proccessFilenameOrURL(filenameOrURL, isURL); err != nil {
 			log.Println(err)
+			launcher.notifyFailed(err)
 			launcher.gui.SendErrorMessage(err)
 		} else {
 			launcher.gui.Terminate()
@@ -95,33 +135,124 @@ func (launcher *Launcher) runByFilenameOrURL(filenameOrURL string, isURL bool) e
 		return err
 	}
 	wg.Wait()
-	return nil
+	return launcher.waitIfNeeded()
+}
+
+// notifyFailed reports the error of the run to its observer
+func (launcher *Launcher) notifyFailed(err error) {
+	if observer := launcher.observer(); observer != nil {
+		observer.Failed(err)
+	}
+}
+
+// askCredentials asks the user for credentials required by a server
+func (launcher *Launcher) askCredentials(host, realm string, failed, insecure bool) (*download.Credentials, bool) {
+	text := fmt.Sprintf("%s requires a user name and password.", host)
+	if realm != "" {
+		text = fmt.Sprintf("%s requires a user name and password for %q.", host, realm)
+	}
+	if failed {
+		text = "The user name or password is incorrect. " + text
+	}
+	if insecure {
+		text += " The connection isn't secure, they will be sent without encryption."
+	}
+	username, password, ok := launcher.gui.AskCredentials(text)
+	launcher.notifyPrompt(text, ok)
+	if !ok {
+		return nil, false
+	}
+	return &download.Credentials{Username: username, Password: password}, true
+}
+
+// waitIfNeeded waits until the started application exits if it is requested by options
+func (launcher *Launcher) waitIfNeeded() error {
+	if launcher.options == nil || !launcher.options.Wait || launcher.cmd == nil || launcher.cmd.Process == nil {
+		return nil
+	}
+	log.Printf("waiting for the application to exit")
+	return launcher.cmd.Wait()
 }
 
 func (launcher *Launcher) proccessFilenameOrURL(filenameOrURL string, isURL bool) (err error) {
 	var filedata []byte
 	log.Printf("Processing %s\n", filenameOrURL)
 	if err = launcher.CheckPlatform(); err != nil {
+		err = withCode(err, errorJava)
 		return
 	}
+	launcher.offline = launcher.options != nil && launcher.options.Offline
+	download.SetOffline(launcher.offline)
+	launcher.observeDownloads()
+	launcher.setPhase(phaseResolve)
+	if launcher.gui != nil {
+		download.SetCredentialsPrompt(launcher.askCredentials)
+	} else {
+		download.SetCredentialsPrompt(nil)
+	}
 	if isURL {
-		url := launcher.normalizeURL(filenameOrURL)
-		filedata, err = download.ToMemory(url)
+		normalizedURL := launcher.normalizeURL(filenameOrURL)
+		if launcher.sourceURL, err = url.Parse(normalizedURL); err != nil {
+			return
+		}
+		if launcher.offline {
+			filedata, err = launcher.readCachedJNLPFile(normalizedURL)
+		} else if filedata, err = download.ToMemory(normalizedURL); err != nil && download.IsNetworkError(err) {
+			if cached, cacheErr := launcher.readCachedJNLPFile(normalizedURL); cacheErr == nil {
+				log.Printf("switching to offline mode because %v", err)
+				launcher.offline = true
+				filedata, err = cached, nil
+			}
+		}
 	} else {
 		filedata, err = ioutil.ReadFile(filenameOrURL)
 	}
 	if err != nil {
+		err = withCode(err, errorDownload)
 		return
 	}
-	if filedata, err = launcher.checkForUpdate(filedata); err != nil {
+	if !isURL {
+		launcher.sourceURL = launcher.findSourceURL(filenameOrURL, filedata)
+	}
+	if launcher.offline {
+		log.Printf("offline mode, jnlp file is not checked for update")
+	} else if filedata, err = launcher.checkForUpdate(filedata); err != nil {
+		err = withCode(err, errorDownload)
 		return
 	}
+	download.SetOffline(launcher.offline)
 	if err = launcher.run(filedata); err != nil {
 		return
 	}
 	return
 }
 
+// readCachedJNLPFile returns the most recently used cached copy of JNLP file downloaded from rawurl.
+// The original JNLP file is saved on every launch, so its modification time is the time of last use.
+func (launcher *Launcher) readCachedJNLPFile(rawurl string) ([]byte, error) {
+	sourceURLFiles, _ := filepath.Glob(filepath.Join(launcher.WorkDir, "*", "source.url"))
+	var latest string
+	var latestTime time.Time
+	for _, sourceURLFile := range sourceURLFiles {
+		data, err := ioutil.ReadFile(sourceURLFile)
+		if err != nil || strings.TrimSpace(string(data)) != rawurl {
+			continue
+		}
+		originalFile := filepath.Join(filepath.Dir(sourceURLFile), "original.jnlp")
+		stat, err := os.Stat(originalFile)
+		if err != nil {
+			continue
+		}
+		if latest == "" || stat.ModTime().After(latestTime) {
+			latest, latestTime = originalFile, stat.ModTime()
+		}
+	}
+	if latest == "" {
+		return nil, errors.Errorf("%s is not cached and can't be downloaded in offline mode", rawurl)
+	}
+	return ioutil.ReadFile(latest)
+}
+
 // Terminate forces GUI to close
 func (launcher *Launcher) Terminate() {
 	if launcher.gui != nil {
@@ -200,6 +331,25 @@ func (launcher *Launcher) getCodebaseURL() (*url.URL, error) {
 	return codebaseURL, nil
 }
 
+// resolveCodebase makes codebase of jnlpFile absolute.
+// Codebase passed using -codebase option takes precedence, an empty or relative codebase
+// is resolved against URL the JNLP file was downloaded from.
+func (launcher *Launcher) resolveCodebase(jnlpFile *JNLP) error {
+	if launcher.options != nil && launcher.options.Codebase != "" {
+		log.Printf("codebase %q is overridden with %q", jnlpFile.CodeBase, launcher.options.Codebase)
+		jnlpFile.CodeBase = launcher.options.Codebase
+	}
+	codebaseURL, err := launcher_utils.ResolveCodebaseURL(jnlpFile.CodeBase, launcher.sourceURL)
+	if err != nil {
+		return err
+	}
+	if codebaseURL.String() != jnlpFile.CodeBase {
+		log.Printf("codebase %q is resolved to %s", jnlpFile.CodeBase, codebaseURL)
+	}
+	jnlpFile.CodeBase = codebaseURL.String()
+	return nil
+}
+
 func (launcher *Launcher) getProperties() []Property {
 	var properties []Property
 	relevantResources := launcher.getRelevantResources()
@@ -242,7 +392,17 @@ func (launcher *Launcher) command() (*exec.Cmd, error) {
 		return nil, err
 	}
 	extensionJars := launcher.getExtensionJars()
-	javaArgs := launcher.getJVMArgs()
+	// proxies and the key store go first, so they can be overridden by the application and settings
+	javaArgs := launcher.getProxyArgs()
+	javaArgs = append(javaArgs, launcher.getKeyStoreArgs()...)
+	javaArgs = append(javaArgs, launcher.getJVMArgs()...)
+	javaArgs = append(javaArgs, launcher.getCompatibilityArgs()...)
+	if launcher.policyRule != nil {
+		javaArgs = append(javaArgs, launcher.policyRule.JVMArgs...)
+	}
+	if launcher.options != nil {
+		javaArgs = append(javaArgs, launcher.options.JVMArgs...)
+	}
 	var args []string
 	nativelibs, err := launcher.getNativeLibs()
 	if err != nil {
@@ -261,6 +421,7 @@ func (launcher *Launcher) command() (*exec.Cmd, error) {
 	for _, jar := range extensionJars {
 		args = append(args, filepath.Join(launcher.resourceDir, path.Base(jar)))
 	}
+	classPathIndex := len(javaArgs)
 	javaArgs = append(javaArgs, "-cp", strings.Join(args, ClassPathSeparator))
 	properties := launcher.getProperties()
 	for _, property := range properties {
@@ -269,28 +430,411 @@ func (launcher *Launcher) command() (*exec.Cmd, error) {
 	if len(nativeLibPaths) > 0 {
 		javaArgs = append(javaArgs, fmt.Sprintf("-Djava.library.path=%s", strings.Join(nativeLibPaths, ClassPathSeparator)))
 	}
-	if splash := launcher.getSplashScreen(); splash != "" {
+	if splash := launcher.getSplashScreen(); splash != "" && (launcher.options == nil || !launcher.options.NoSplash) {
 		javaArgs = append(javaArgs, fmt.Sprintf("-splash:%s", splash))
 	}
 	if jnlp.AppDescription != nil {
 		javaArgs = append(javaArgs, jnlp.AppDescription.MainClass)
-		for _, appArg := range jnlp.AppDescription.Arguments {
-			javaArgs = append(javaArgs, appArg)
-		}
+		javaArgs = append(javaArgs, launcher.getApplicationArgs()...)
 	} else if jnlp.AppletDescription != nil {
 		return nil, errors.New("found <applet-desc> tag but applets are not supported")
 	} else {
 		return nil, errors.New("<application-desc> tag wasn't found in JNLP file")
 	}
-	log.Printf("java arguments %s\n", strings.Join(javaArgs, " "))
-	cmd := exec.Command(settings.Java(), javaArgs...)
+	if getCommandLineLength(launcher.javaExecutable(), javaArgs) > maxCommandLineLength {
+		classPathArgs, err := launcher.getLongClassPathArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		var shortenedArgs []string
+		shortenedArgs = append(shortenedArgs, javaArgs[:classPathIndex]...)
+		shortenedArgs = append(shortenedArgs, classPathArgs...)
+		shortenedArgs = append(shortenedArgs, javaArgs[classPathIndex+2:]...)
+		javaArgs = shortenedArgs
+	}
+	log.Printf("java arguments %s\n", strings.Join(redactJavaArgs(javaArgs), " "))
+	cmd := exec.Command(launcher.javaExecutable(), javaArgs...)
 	if launcher.options != nil && launcher.options.IsRunningFromBrowser {
 		utils.BreakAwayFromParent(cmd)
 	}
 	return cmd, nil
 }
 
+// getApplicationArgs returns arguments of the main class,
+// they are replaced with -open or -print and a document if the app is started for a file association
+func (launcher *Launcher) getApplicationArgs() []string {
+	if launcher.options != nil && launcher.options.Open != "" {
+		return []string{"-open", launcher.options.Open}
+	}
+	if launcher.options != nil && launcher.options.Print != "" {
+		return []string{"-print", launcher.options.Print}
+	}
+	return launcher.jnlp.AppDescription.Arguments
+}
+
+// getLongClassPathArgs returns arguments which pass classPath to java without exceeding command line limits:
+// @argfile for Java 9 and later, a pathing JAR with Class-Path manifest attribute for earlier versions
+func (launcher *Launcher) getLongClassPathArgs(classPath []string) ([]string, error) {
+	javaVersion, err := launcher.getJavaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if javaVersion.FeatureVersion() >= 9 {
+		argFile := filepath.Join(launcher.resourceDir, "classpath.args")
+		log.Printf("command line is too long, classpath is passed using argument file %s", argFile)
+		if err := launcher_utils.WriteArgFile(argFile, []string{"-cp", strings.Join(classPath, ClassPathSeparator)}, 0644); err != nil {
+			return nil, err
+		}
+		return []string{"@" + argFile}, nil
+	}
+	pathingJAR := filepath.Join(launcher.resourceDir, "classpath.jar")
+	log.Printf("command line is too long, classpath is passed using pathing JAR %s", pathingJAR)
+	var relativeClassPath []string
+	for _, entry := range classPath {
+		relativeEntry, err := filepath.Rel(launcher.resourceDir, entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to add %s into pathing JAR", entry)
+		}
+		relativeClassPath = append(relativeClassPath, relativeEntry)
+	}
+	if err := launcher_utils.WritePathingJAR(pathingJAR, relativeClassPath); err != nil {
+		return nil, err
+	}
+	return []string{"-cp", pathingJAR}, nil
+}
+
+func getCommandLineLength(executable string, args []string) int {
+	length := len(executable)
+	for _, arg := range args {
+		length += len(arg) + 3 // separator and quotes
+	}
+	return length
+}
+
+func (launcher *Launcher) getCompatibilityArgs() []string {
+	filename := settings.CompatibilityProfilesFile()
+	profiles, err := compat.Load(filename)
+	if err != nil {
+		log.Printf("warning: compatibility profiles will not be applied: %v", err)
+		return nil
+	}
+	if len(profiles) == 0 {
+		return nil
+	}
+	javaVersion, err := launcher.getJavaVersion()
+	if err != nil {
+		log.Printf("warning: compatibility profiles will not be applied: %v", err)
+		return nil
+	}
+	app := &compat.App{
+		URL:   launcher.getJNLPURL(),
+		Title: launcher.jnlp.Title(),
+	}
+	if compat.NeedsSigner(profiles) {
+		app.Signer = launcher.getSignerFingerprint()
+	}
+	args, matched := compat.Args(profiles, app, javaVersion.FeatureVersion())
+	log.Printf("compatibility profiles %v from %s applied for Java %d", matched, filename, javaVersion.FeatureVersion())
+	return args
+}
+
+// getProxyArgs returns system properties passing proxies used for downloads to the application
+func (launcher *Launcher) getProxyArgs() []string {
+	target := launcher.getJNLPURL()
+	if codebaseURL, err := launcher.getCodebaseURL(); err == nil {
+		target = codebaseURL.String()
+	}
+	httpProxy, httpsProxy, noProxy := download.Proxies(target)
+	args := javaProxyArgs(httpProxy, httpsProxy, noProxy)
+	if len(args) > 0 {
+		log.Printf("proxy arguments for Java are %v", args)
+	}
+	return args
+}
+
+// getKeyStoreArgs returns system properties passing the client certificate for the codebase to the application
+func (launcher *Launcher) getKeyStoreArgs() []string {
+	target := launcher.getJNLPURL()
+	if codebaseURL, err := launcher.getCodebaseURL(); err == nil {
+		target = codebaseURL.String()
+	}
+	certificate := download.JavaKeyStore(target)
+	if certificate == nil {
+		return nil
+	}
+	args := []string{
+		"-Djavax.net.ssl.keyStore=" + certificate.PKCS12,
+		"-Djavax.net.ssl.keyStoreType=PKCS12",
+	}
+	if certificate.Password == "" {
+		log.Printf("client certificate %s is passed to Java as its key store", certificate.PKCS12)
+		return args
+	}
+	// the password is passed in an argument file readable only by the user, so it isn't visible in the process list
+	javaVersion, err := launcher.getJavaVersion()
+	if err != nil || javaVersion.FeatureVersion() < 9 {
+		log.Printf("warning: client certificate %s is not passed to Java, argument files for its password require Java 9 or later, use a key store without a password for earlier versions", certificate.PKCS12)
+		return nil
+	}
+	tempDir, err := launcher.getTempDir()
+	if err != nil {
+		log.Printf("warning: client certificate %s is not passed to Java: %v", certificate.PKCS12, err)
+		return nil
+	}
+	argFile := filepath.Join(tempDir, "keystore.args")
+	args = append(args, "-Djavax.net.ssl.keyStorePassword="+certificate.Password)
+	if err := launcher_utils.WriteArgFile(argFile, args, 0600); err != nil {
+		log.Printf("warning: client certificate %s is not passed to Java: %v", certificate.PKCS12, err)
+		return nil
+	}
+	log.Printf("client certificate %s is passed to Java as its key store using argument file %s", certificate.PKCS12, argFile)
+	return []string{"@" + argFile}
+}
+
+// getTempDir returns a directory accessible only by the user for temporary files of the command, it is created once
+func (launcher *Launcher) getTempDir() (string, error) {
+	if launcher.tempDir == "" {
+		dir, err := ioutil.TempDir("", "openweblaunch")
+		if err != nil {
+			return "", errors.Wrap(err, "unable to create temporary directory")
+		}
+		launcher.tempDir = dir
+	}
+	return launcher.tempDir, nil
+}
+
+// removeTempDir removes temporary files of the command. If Java was started, they are removed after argFileReadDelay
+// because Java reads argument files when it starts and doesn't report when it has done it.
+func (launcher *Launcher) removeTempDir(started bool) {
+	if launcher.tempDir == "" {
+		return
+	}
+	if started {
+		time.Sleep(argFileReadDelay)
+	}
+	if err := os.RemoveAll(launcher.tempDir); err != nil {
+		log.Printf("warning: unable to remove temporary directory %s: %v", launcher.tempDir, err)
+	}
+	launcher.tempDir = ""
+}
+
+// argFileReadDelay is the time Java gets to read temporary argument files after it is started
+var argFileReadDelay = 2 * time.Second
+
+// redactJavaArgs returns args with values of password properties replaced, so they can be logged
+func redactJavaArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if name := strings.SplitN(arg, "=", 2)[0]; strings.HasPrefix(name, "-D") && strings.HasSuffix(strings.ToLower(name), "password") && name != arg {
+			arg = name + "=********"
+		}
+		redacted[i] = arg
+	}
+	return redacted
+}
+
+// javaProxyArgs converts proxies to Java networking properties, SOCKS proxies are passed as socksProxyHost.
+// Networks like 10.0.0.0/8 can't be expressed in http.nonProxyHosts and are skipped.
+func javaProxyArgs(httpProxy, httpsProxy *url.URL, noProxy []string) []string {
+	var args []string
+	socks := false
+	for _, proxy := range []struct {
+		property string // Prefix of host and port properties
+		url      *url.URL
+	}{{"http.proxy", httpProxy}, {"https.proxy", httpsProxy}} {
+		if proxy.url == nil {
+			continue
+		}
+		property := proxy.property
+		if proxy.url.Scheme == "socks5" {
+			if socks {
+				continue
+			}
+			property, socks = "socksProxy", true
+		}
+		port := proxy.url.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxy.url.Scheme]
+		}
+		args = append(args, fmt.Sprintf("-D%sHost=%s", property, proxy.url.Hostname()))
+		args = append(args, fmt.Sprintf("-D%sPort=%s", property, port))
+	}
+	if len(args) == 0 || len(noProxy) == 0 {
+		return args
+	}
+	// replacing the default of Java, so it is kept
+	hosts := []string{"localhost", "127.*", "[::1]"}
+	for _, entry := range noProxy {
+		if host, _, err := net.SplitHostPort(entry); err == nil {
+			entry = host
+		}
+		switch {
+		case entry == "*":
+			hosts = append(hosts, "*")
+		case strings.Contains(entry, "/"):
+			log.Printf("warning: network %s can't be bypassed by Java proxy settings", entry)
+		case net.ParseIP(entry) != nil:
+			hosts = append(hosts, entry)
+		case strings.HasPrefix(entry, "*."):
+			hosts = append(hosts, entry)
+		case strings.HasPrefix(entry, "."):
+			hosts = append(hosts, "*"+entry)
+		default:
+			hosts = append(hosts, entry, "*."+entry)
+		}
+	}
+	return append(args, "-Dhttp.nonProxyHosts="+strings.Join(hosts, "|"))
+}
+
+// getJNLPURL returns URL of JNLP file using codebase and href attributes,
+// falls back to URL or filename the file was opened from
+func (launcher *Launcher) getJNLPURL() string {
+	if launcher.jnlp.Href != "" {
+		codebaseURL, err := launcher.getCodebaseURL()
+		if err == nil {
+			if hrefURL, err := url.Parse(launcher.jnlp.Href); err == nil {
+				return codebaseURL.ResolveReference(hrefURL).String()
+			}
+		}
+	}
+	if launcher.sourceURL != nil {
+		return launcher.sourceURL.String()
+	}
+	return ""
+}
+
+// getSourceURL returns URL the JNLP file was downloaded from, file:// URL for local files.
+// Unlike getJNLPURL it doesn't depend on attributes of the file, so it is used for security decisions.
+func (launcher *Launcher) getSourceURL() string {
+	if launcher.sourceURL == nil {
+		return ""
+	}
+	return launcher.sourceURL.String()
+}
+
+// getJARURLs returns URLs of JARs, native libraries and extensions of the application
+func (launcher *Launcher) getJARURLs() ([]string, error) {
+	jars, err := launcher.getJars()
+	if err != nil {
+		return nil, err
+	}
+	nativeLibs, err := launcher.getNativeLibs()
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := launcher.getExtensions()
+	if err != nil {
+		return nil, err
+	}
+	urls := append(jars, nativeLibs...)
+	for _, extension := range extensions {
+		urls = append(urls, extension.URL)
+	}
+	return urls, nil
+}
+
+// getMainJARFile returns path of the downloaded JAR with main="true" attribute or the first JAR,
+// empty string if the application has no JARs
+func (launcher *Launcher) getMainJARFile() string {
+	jars, err := launcher.getJars()
+	if err != nil || len(jars) == 0 {
+		return ""
+	}
+	mainJar := jars[0]
+	for _, resources := range launcher.getRelevantResources() {
+		for _, jar := range resources.JARs {
+			if jar.Main {
+				mainJar = jar.Href
+			}
+		}
+	}
+	return filepath.Join(launcher.resourceDir, path.Base(mainJar))
+}
+
+// getSignerFingerprint returns SHA-256 fingerprint of the certificate used for signing all JARs of the application.
+// It is empty if JARs are not verified, because a signature block can be copied into an unsigned JAR,
+// and if a JAR is not signed or JARs are signed with different certificates.
+func (launcher *Launcher) getSignerFingerprint() string {
+	if launcher.isVerificationDisabled() {
+		return ""
+	}
+	jars, err := launcher.getJars()
+	if err != nil {
+		return ""
+	}
+	nativeLibJars, err := launcher.getNativeLibs()
+	if err != nil {
+		return ""
+	}
+	signer := ""
+	for i, jar := range append(jars, nativeLibJars...) {
+		filename := filepath.Join(launcher.resourceDir, path.Base(jar))
+		fingerprint, err := verifier.GetJARSignerFingerprint(filename)
+		if err != nil {
+			log.Printf("unable to get signer of %s: %v", filepath.Base(filename), err)
+			return ""
+		}
+		if i > 0 && fingerprint != signer {
+			log.Printf("JARs of %s are signed with different certificates", launcher.jnlp.Title())
+			return ""
+		}
+		signer = fingerprint
+	}
+	return signer
+}
+
+// javaExecutable returns Java executable which runs the application
+func (launcher *Launcher) javaExecutable() string {
+	if launcher.java != "" {
+		return launcher.java
+	}
+	return settings.Java()
+}
+
+// getJavaVersion returns version of Java which runs the application
+func (launcher *Launcher) getJavaVersion() (*settings.JavaVersion, error) {
+	if launcher.javaVersion != nil {
+		return launcher.javaVersion, nil
+	}
+	return settings.GetJavaVersion()
+}
+
+// isVerificationDisabled reports whether JARs of the run are not verified
+func (launcher *Launcher) isVerificationDisabled() bool {
+	return launcher.noVerification
+}
+
+// decideVerification decides whether JARs are verified. They are not verified if verification is disabled
+// by settings, or the application comes from an exception site imported from Java deployment settings
+// and the user agrees to run it without verification.
+func (launcher *Launcher) decideVerification() {
+	launcher.noVerification = settings.IsVerificationDisabled()
+	jnlpURL := launcher.getSourceURL()
+	if launcher.noVerification || !settings.IsExceptionSite(jnlpURL) {
+		return
+	}
+	jarURLs, err := launcher.getJARURLs()
+	if err != nil {
+		return
+	}
+	for _, jarURL := range jarURLs {
+		if !settings.IsExceptionSite(jarURL) {
+			log.Printf("JARs of exception site %s are verified because %s is not an exception site", jnlpURL, jarURL)
+			return
+		}
+	}
+	question := fmt.Sprintf("%s is in the exception site list of Java. Do you want to run %s without verifying signatures of its JARs?", jnlpURL, launcher.jnlp.Title())
+	accepted := launcher.gui.Confirm(question)
+	launcher.notifyPrompt(question, accepted)
+	if accepted {
+		log.Printf("JARs of exception site %s are not verified", jnlpURL)
+	}
+	launcher.noVerification = accepted
+}
+
 func (launcher *Launcher) exec() error {
+	started := false
+	defer func() { launcher.removeTempDir(started) }()
 	cmd, err := launcher.command()
 	if err != nil {
 		return errors.Wrap(err, "unable to run java application")
@@ -299,54 +843,239 @@ func (launcher *Launcher) exec() error {
 	if launcher.gui.Closed() {
 		return errCancelled
 	}
-	return cmd.Start()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	started = true
+	return nil
 }
 
 func (launcher *Launcher) run(filedata []byte) error {
 	var jnlpFile *JNLP
 	var err error
 	if jnlpFile, err = Decode(filedata); err != nil {
-		return errors.Wrap(err, "parsing JNLP")
+		return withCode(errors.Wrap(err, "parsing JNLP"), errorInvalidJNLP)
+	}
+	jnlpFile.ExpandVariables(launcher.sourceURL)
+	if err := launcher.resolveCodebase(jnlpFile); err != nil {
+		return withCode(err, errorInvalidJNLP)
+	}
+	if launcher.offline && jnlpFile.Information.OfflineAllowed == nil {
+		return withCode(errors.New("the application can't run offline because its JNLP file doesn't contain <offline-allowed> element"), errorOffline)
+	}
+	isExport := launcher.options != nil && launcher.options.Export != ""
+	if isExport && jnlpFile.Information.OfflineAllowed == nil {
+		return errors.New("the application can't be exported because its JNLP file doesn't contain <offline-allowed> element")
 	}
 	launcher.jnlp = jnlpFile
 	launcher.filedata = filedata
 	launcher.resourceDir = launcher.generateResourcesDirName(filedata)
+	launcher.policy, launcher.policyRule = nil, nil
+	launcher.java, launcher.javaVersion = "", nil
 	launcher.gui.SetTitle(launcher.jnlp.Title())
+	if err := launcher.checkPolicy(false); err != nil {
+		return withCode(err, errorBlocked)
+	}
 	if err := launcher.saveOriginalFile(); err != nil {
-		return err
+		return withCode(err, errorInstall)
+	}
+	if err := launcher.saveSourceURL(); err != nil {
+		return withCode(err, errorInstall)
 	}
 	if err := launcher.estimateProgressMax(); err != nil {
-		return err
+		return withCode(err, errorInvalidJNLP)
 	}
-	if err := launcher.checkRequiredJavaVersion(); err != nil {
-		return err
+	// Java of a rule decided after downloading can differ from the default one, so its version is checked then
+	javaChecked := launcher.policy == nil || launcher.policyRule != nil
+	if javaChecked {
+		if err := launcher.checkRequiredJavaVersion(); err != nil {
+			return withCode(err, errorJava)
+		}
 	}
+	launcher.decideVerification()
+	launcher.setPhase(phaseDownload)
 	if err := launcher.downloadJARs(); err != nil {
-		return err
+		return withCode(err, errorDownload)
 	}
 	if err := launcher.extractNativeLibs(); err != nil {
-		return err
+		return withCode(err, errorInstall)
 	}
 	if err := launcher.downloadExtensions(); err != nil {
-		return err
+		return withCode(err, errorDownload)
 	}
 	if err := launcher.downloadIcons(); err != nil {
-		return err
+		return withCode(err, errorDownload)
+	}
+	if launcher.policyRule == nil {
+		if err := launcher.checkPolicy(true); err != nil {
+			return withCode(err, errorBlocked)
+		}
+	}
+	if !javaChecked {
+		if err := launcher.checkRequiredJavaVersion(); err != nil {
+			return withCode(err, errorJava)
+		}
 	}
+	if isExport {
+		return withCode(launcher.exportBundle(), errorInstall)
+	}
+	launcher.setPhase(phaseInstall)
 	launcher.removeOldShortcutsIfNeeded()
-	if err := launcher.createShortcuts(); err != nil {
-		return err
+	isImport := launcher.options != nil && launcher.options.Import
+	if !isImport || launcher.options.ImportShortcuts {
+		if err := launcher.createShortcuts(); err != nil {
+			return withCode(err, errorInstall)
+		}
+	}
+	if !isImport || launcher.options.ImportAssociations {
+		if err := launcher.registerAssociations(isImport); err != nil {
+			return withCode(err, errorInstall)
+		}
 	}
 	if settings.AddAppToControlPanel() {
 		if err := launcher.installApp(); err != nil {
-			return err
+			return withCode(err, errorInstall)
 		}
 	}
+	if isImport {
+		launcher.gui.SendTextMessage("Import complete")
+		return nil
+	}
 	if launcher.gui.Closed() {
 		return errCancelled
 	}
 	launcher.gui.SendTextMessage("Starting application...")
-	return launcher.exec()
+	launcher.setPhase(phaseStart)
+	return withCode(launcher.exec(), errorStart)
+}
+
+// checkPolicy evaluates the policy file configured by administrators and applies the matched rule.
+// Rules checking signer or checksum are decided after JARs are downloaded.
+func (launcher *Launcher) checkPolicy(downloaded bool) error {
+	filename := settings.PolicyFile()
+	if filename == "" {
+		return nil
+	}
+	if launcher.policy == nil {
+		var err error
+		if launcher.policy, err = policy.Load(filename); err != nil {
+			return errors.Wrap(err, "the application is blocked because policy can't be loaded")
+		}
+	}
+	jarURLs, err := launcher.getJARURLs()
+	if err != nil {
+		return errors.Wrap(err, "the application is blocked because URLs of its JARs can't be resolved")
+	}
+	app := &policy.App{
+		URL:     launcher.getSourceURL(),
+		JARURLs: jarURLs,
+		Title:   launcher.jnlp.Title(),
+	}
+	if downloaded {
+		app.Downloaded = true
+		app.Signer = launcher.getSignerFingerprint()
+		if mainJAR := launcher.getMainJARFile(); mainJAR != "" {
+			if app.Checksum, err = policy.FileChecksum(mainJAR); err != nil {
+				return errors.Wrap(err, "unable to get checksum of the main JAR")
+			}
+		}
+	}
+	rule, decided := launcher.policy.Evaluate(app)
+	if !decided {
+		log.Printf("policy rule for %s will be selected after JARs are downloaded", app.URL)
+		return nil
+	}
+	launcher.policyRule = rule
+	log.Printf("policy rule %q from %s matched %s, action is %s", rule.Name, filename, app.URL, rule.Action)
+	switch rule.Action {
+	case policy.Block:
+		return errors.New(rule.BlockedMessage())
+	case policy.Prompt:
+		question := fmt.Sprintf("Do you want to run %s from %s?", app.Title, app.URL)
+		accepted := launcher.gui.Confirm(question)
+		launcher.notifyPrompt(question, accepted)
+		if !accepted {
+			return errors.Errorf("the application is not allowed to run by policy rule %q", rule.Name)
+		}
+	}
+	if rule.JavaDir != "" {
+		java, err := settings.PolicyJava(rule.JavaDir)
+		if err != nil {
+			return errors.Wrapf(err, "unable to use Java of policy rule %q", rule.Name)
+		}
+		if launcher.javaVersion, err = settings.GetJavaVersionOf(java); err != nil {
+			return errors.Wrapf(err, "unable to use Java of policy rule %q", rule.Name)
+		}
+		launcher.java = java
+		log.Printf("java executable is %s selected by policy rule %q, version is %s", java, rule.Name, launcher.javaVersion.String)
+	}
+	return nil
+}
+
+// getBundleFiles returns names of files in the resource directory the application needs to run:
+// the original JNLP file, its source URL, JARs, nativelibs, extensions with their JARs and downloaded icons
+func (launcher *Launcher) getBundleFiles() ([]string, error) {
+	files := []string{filepath.Base(launcher.getOriginalFilePath())}
+	if _, err := os.Stat(launcher.getSourceURLFilePath()); err == nil {
+		files = append(files, filepath.Base(launcher.getSourceURLFilePath()))
+	}
+	jarURLs, err := launcher.getJARURLs()
+	if err != nil {
+		return nil, err
+	}
+	for _, jarURL := range jarURLs {
+		files = append(files, path.Base(jarURL))
+	}
+	extensions, err := launcher.getExtensions()
+	if err != nil {
+		return nil, err
+	}
+	for _, extension := range extensions {
+		extensionJNLP, err := decodeExtension(extension, filepath.Join(launcher.resourceDir, path.Base(extension.URL)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse jnlp file for extension %s", extension.Name)
+		}
+		jars, err := extensionJNLP.getJars()
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get JARs for extension %s", extension.Name)
+		}
+		for _, jar := range jars {
+			files = append(files, path.Base(jar))
+		}
+	}
+	for _, icon := range launcher.jnlp.Information.Icons {
+		if icon.Downloaded {
+			files = append(files, path.Base(icon.Href))
+		}
+	}
+	return files, nil
+}
+
+// exportBundle writes the resource directory of the resolved application into bundle file,
+// so it can be imported on machines without access to the JNLP server
+func (launcher *Launcher) exportBundle() error {
+	info := launcher.jnlp.Information
+	manifest := &bundle.Manifest{
+		Title:             info.Title,
+		Vendor:            info.Vendor,
+		Version:           info.Version,
+		SignerFingerprint: launcher.getSignerFingerprint(),
+		Created:           time.Now(),
+	}
+	if launcher.sourceURL != nil && launcher.sourceURL.Scheme != "file" {
+		manifest.SourceURL = launcher.sourceURL.String()
+	}
+	launcher.gui.SendTextMessage(fmt.Sprintf("Exporting %s", filepath.Base(launcher.options.Export)))
+	files, err := launcher.getBundleFiles()
+	if err != nil {
+		return errors.Wrap(err, "unable to export application")
+	}
+	if err := bundle.Create(launcher.options.Export, launcher.resourceDir, files, manifest); err != nil {
+		return errors.Wrap(err, "unable to export application")
+	}
+	log.Printf("exported %d files into %s", len(manifest.Files), launcher.options.Export)
+	launcher.gui.SendTextMessage("Export complete")
+	return nil
 }
 
 func (launcher *Launcher) downloadIcons() error {
@@ -378,7 +1107,7 @@ func (launcher *Launcher) downloadIcons() error {
 		icon.Downloaded = true
 		launcher.gui.SendTextMessage(fmt.Sprintf("Downloading %s finished", path.Base(icon.Href)))
 	}
-	launcher.gui.ProgressStep()
+	launcher.progressStep()
 	return nil
 }
 
@@ -427,32 +1156,32 @@ func (launcher *Launcher) downloadJARs() error {
 				errChan <- err
 				return
 			}
-			launcher.gui.ProgressStep()
+			launcher.progressStep()
 			launcher.gui.SendTextMessage(fmt.Sprintf("Downloading JAR %s finished\n", path.Base(url)))
 			if launcher.gui.Closed() {
 				return
 			}
-			if !settings.IsVerificationDisabled() {
+			if !launcher.isVerificationDisabled() {
 				launcher.gui.SendTextMessage(fmt.Sprintf("Checking JAR %s\n", path.Base(url)))
 				if err := verifier.VerifyWithJARSigner(filename, false); err != nil {
-					errChan <- errors.Wrapf(err, "JAR verification failed %s", filepath.Base(filename))
+					errChan <- withCode(errors.Wrapf(err, "JAR verification failed %s", filepath.Base(filename)), errorVerification)
 					return
 				}
 				launcher.gui.SendTextMessage(fmt.Sprintf("Checking JAR %s finished\n", path.Base(url)))
 			}
-			launcher.gui.ProgressStep()
+			launcher.progressStep()
 			if launcher.gui.Closed() {
 				return
 			}
-			if !settings.IsVerificationDisabled() && !settings.IsVerificationSameOriginDisabled() {
+			if !launcher.isVerificationDisabled() && !settings.IsVerificationSameOriginDisabled() {
 				cert, err := verifier.GetJARCertificate(filename)
 				if err != nil {
-					errChan <- errors.Wrapf(err, "JAR certificate error %s", filepath.Base(filename))
+					errChan <- withCode(errors.Wrapf(err, "JAR certificate error %s", filepath.Base(filename)), errorVerification)
 					return
 				}
 				certChan <- cert
 			}
-			launcher.gui.ProgressStep()
+			launcher.progressStep()
 		}(url)
 	}
 	wg.Wait()
@@ -465,11 +1194,11 @@ func (launcher *Launcher) downloadJARs() error {
 	if err, ok := <-errChan; ok {
 		return err
 	}
-	if !settings.IsVerificationDisabled() && !settings.IsVerificationSameOriginDisabled() {
+	if !launcher.isVerificationDisabled() && !settings.IsVerificationSameOriginDisabled() {
 		firstCert := <-certChan
 		for cert := range certChan {
 			if bytes.Equal(firstCert, cert) {
-				return errors.New("all JARs have to be signed with the same certificate")
+				return withCode(errors.New("all JARs have to be signed with the same certificate"), errorVerification)
 			}
 		}
 		launcher.cert = firstCert
@@ -480,6 +1209,21 @@ func (launcher *Launcher) downloadJARs() error {
 	return nil
 }
 
+// decodeExtension decodes the downloaded JNLP file of extension and resolves its codebase
+func decodeExtension(extension *Extension, filename string) (*JNLP, error) {
+	extensionJNLP, err := DecodeFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if extensionURL, err := url.Parse(extension.URL); err == nil {
+		extensionJNLP.ExpandVariables(extensionURL)
+		if codebaseURL, err := launcher_utils.ResolveCodebaseURL(extensionJNLP.CodeBase, extensionURL); err == nil {
+			extensionJNLP.CodeBase = codebaseURL.String()
+		}
+	}
+	return extensionJNLP, nil
+}
+
 func (launcher *Launcher) downloadExtensions() error {
 	launcher.gui.SendTextMessage("Downloading extensions...")
 	extensions, err := launcher.getExtensions()
@@ -513,7 +1257,7 @@ func (launcher *Launcher) downloadExtensions() error {
 			if launcher.gui.Closed() {
 				return
 			}
-			extensionJNLP, err := DecodeFile(filename)
+			extensionJNLP, err := decodeExtension(extension, filename)
 			if err != nil {
 				errChan <- errors.Wrapf(err, "unable to parse jnlp file for extension %s", extension.Name)
 				return
@@ -532,19 +1276,19 @@ func (launcher *Launcher) downloadExtensions() error {
 					return
 				}
 				launcher.gui.SendTextMessage(fmt.Sprintf("Downloading JAR %s finished\n", path.Base(jarURL)))
-				if !settings.IsVerificationDisabled() {
+				if !launcher.isVerificationDisabled() {
 					if err := verifier.VerifyWithJARSigner(filename, false); err != nil {
-						errChan <- errors.Wrapf(err, "JAR verification failed %s", filepath.Base(filename))
+						errChan <- withCode(errors.Wrapf(err, "JAR verification failed %s", filepath.Base(filename)), errorVerification)
 						return
 					}
 					if !settings.IsVerificationSameOriginDisabled() {
 						cert, err := verifier.GetJARCertificate(filename)
 						if err != nil {
-							errChan <- errors.Wrapf(err, "JAR certificate error %s", filepath.Base(filename))
+							errChan <- withCode(errors.Wrapf(err, "JAR certificate error %s", filepath.Base(filename)), errorVerification)
 							return
 						}
 						if bytes.Equal(launcher.cert, cert) {
-							errChan <- errors.New("all JARs have to be signed with the same certificate")
+							errChan <- withCode(errors.New("all JARs have to be signed with the same certificate"), errorVerification)
 							return
 						}
 					}
@@ -554,7 +1298,7 @@ func (launcher *Launcher) downloadExtensions() error {
 					return
 				}
 			}
-			launcher.gui.ProgressStep()
+			launcher.progressStep()
 			launcher.gui.SendTextMessage(fmt.Sprintf("Downloading extension %s finished\n", extension.Name))
 		}(extension)
 	}
@@ -609,26 +1353,26 @@ func (launcher *Launcher) estimateProgressMax() error {
 	}
 	extensionJars := launcher.getExtensionJars()
 	progressMax := 3*(len(jars)+len(nativeLibJars)) + len(extensionJars) + 1
-	launcher.gui.SetProgressMax(progressMax)
+	launcher.setProgressMax(progressMax)
 	return nil
 }
 
 func (launcher *Launcher) createShortcuts() error {
 	info := launcher.jnlp.Information
 	title := info.Title
-	iconSrc := launcher.findShortcutIcon()
+	iconSrc := launcher.getShortcutIcon()
 	description := launcher.getShortcutDescription()
 	arguments := launcher.getShortcutArguments()
 	if info.Desktop != nil || (info.Shortcut != nil && info.Shortcut.Desktop != nil) {
 		launcher.gui.SendTextMessage("Creating Desktop shortcut")
-		if err := utils.CreateDesktopShortcut(os.Args[0], title, description, iconSrc, arguments...); err != nil {
+		if err := utils.CreateDesktopShortcut(utils.Executable(), title, description, iconSrc, arguments...); err != nil {
 			return err
 		}
 	}
 	if info.Shortcut != nil && info.Shortcut.Menu != nil {
 		submenu := info.Shortcut.Menu.SubMenu
 		launcher.gui.SendTextMessage("Creating Start Menu shortcut")
-		if err := utils.CreateStartMenuShortcut(os.Args[0], submenu, title, description, iconSrc, arguments...); err != nil {
+		if err := utils.CreateStartMenuShortcut(utils.Executable(), submenu, title, description, iconSrc, arguments...); err != nil {
 			return err
 		}
 	}
@@ -641,6 +1385,12 @@ func (launcher *Launcher) removeOldShortcutsIfNeeded() {
 		return
 	}
 	launcher.removeShortcuts(jnlpOld)
+	launcher.unregisterAssociations(jnlpOld)
+	if settings.AddAppToControlPanel() {
+		if err := launcher.uninstallApp(jnlpOld, launcher.jnlpOldFile); err != nil {
+			log.Printf("warning: %v", err)
+		}
+	}
 }
 
 func (launcher *Launcher) removeShortcuts(jnlp *JNLP) {
@@ -655,29 +1405,180 @@ func (launcher *Launcher) removeShortcuts(jnlp *JNLP) {
 	}
 	if info.Shortcut != nil && info.Shortcut.Menu != nil {
 		submenu := info.Shortcut.Menu.SubMenu
-		log.Printf("removing old start menu folder: %s", submenu)
-		if err := utils.RemoveStartMenuFolder(submenu); err != nil {
-			log.Printf("warning: error while removing old start menu folder: %v", err)
+		log.Printf("removing old start menu shortcut: %s", title)
+		if err := utils.RemoveStartMenuShortcut(submenu, title); err != nil {
+			log.Printf("warning: error while removing old start menu shortcut: %v", err)
+		}
+		if submenu != "" {
+			log.Printf("removing old start menu folder: %s", submenu)
+			if err := utils.RemoveStartMenuFolder(submenu); err != nil {
+				log.Printf("warning: error while removing old start menu folder: %v", err)
+			}
+		}
+	}
+}
+
+// registerAssociations registers file associations after the user agrees, confirmed associations are
+// registered without asking, e.g. during import with -association
+func (launcher *Launcher) registerAssociations(confirmed bool) error {
+	info := launcher.jnlp.Information
+	associations := launcher.getFileAssociations(launcher.jnlp)
+	if len(associations) == 0 {
+		return nil
+	}
+	if !confirmed && !launcher.confirmAssociations(associations) {
+		log.Printf("file associations of %s are not registered", info.Title)
+		return nil
+	}
+	launcher.gui.SendTextMessage("Registering file associations")
+	arguments := launcher.getShortcutArguments()
+	jnlpFile := arguments[len(arguments)-1]
+	options := arguments[:len(arguments)-1]
+	openArguments := append(append(append([]string{}, options...), "-open", utils.DocumentArgument), jnlpFile)
+	printArguments := append(append(append([]string{}, options...), "-print", utils.DocumentArgument), jnlpFile)
+	return utils.RegisterFileAssociations(utils.Executable(), info.Title, associations, openArguments, printArguments)
+}
+
+// confirmAssociations asks the user whether the app may open documents of associations.
+// The answer is remembered for the JNLP file, so the user is asked again only if the file changes.
+func (launcher *Launcher) confirmAssociations(associations []*utils.FileAssociation) bool {
+	acceptedFile := launcher.getAssociationsAcceptedFilePath()
+	if _, err := os.Stat(acceptedFile); err == nil {
+		return true
+	}
+	var documents []string
+	for _, association := range associations {
+		if len(association.Extensions) == 0 {
+			documents = append(documents, association.MimeType)
+		}
+		for _, extension := range association.Extensions {
+			documents = append(documents, "."+extension)
+		}
+	}
+	question := fmt.Sprintf("Do you want %s to open %s files?", launcher.jnlp.Title(), strings.Join(documents, ", "))
+	accepted := launcher.gui.ConfirmAction(question, "Register")
+	launcher.notifyPrompt(question, accepted)
+	if accepted {
+		if err := ioutil.WriteFile(acceptedFile, nil, 0644); err != nil {
+			log.Printf("warning: unable to remember file associations are accepted: %v", err)
+		}
+	}
+	return accepted
+}
+
+func (launcher *Launcher) unregisterAssociations(jnlp *JNLP) {
+	// important: the method may run without GUI
+	info := jnlp.Information
+	if len(info.Associations) == 0 {
+		return
+	}
+	log.Printf("removing file associations: %s", info.Title)
+	if err := utils.UnregisterFileAssociations(info.Title, launcher.getFileAssociations(jnlp)); err != nil {
+		log.Printf("warning: error while removing file associations: %v", err)
+	}
+}
+
+func (launcher *Launcher) getFileAssociations(jnlp *JNLP) []*utils.FileAssociation {
+	var associations []*utils.FileAssociation
+	for _, association := range jnlp.Information.Associations {
+		fileAssociation := &utils.FileAssociation{
+			MimeType:    association.MimeType,
+			Extensions:  association.ExtensionList(),
+			Description: association.Description,
+		}
+		if err := fileAssociation.Validate(); err != nil {
+			log.Printf("warning: file association of %s is ignored: %v", jnlp.Title(), err)
+			continue
+		}
+		if jnlp == launcher.jnlp {
+			fileAssociation.Icon = launcher.getShortcutIcon()
 		}
+		associations = append(associations, fileAssociation)
 	}
+	return associations
 }
 
+// getShortcutIcon returns the best shortcut icon converted into a format required by the platform
+func (launcher *Launcher) getShortcutIcon() string {
+	if launcher.shortcutIcon != nil {
+		return *launcher.shortcutIcon
+	}
+	iconSrc := ""
+	if filename := launcher.findShortcutIcon(); filename != "" {
+		converted, err := utils.ConvertShortcutIcon(filename, launcher.jnlp.Information.Title, launcher.resourceDir)
+		if err != nil {
+			log.Printf("warning: unable to convert shortcut icon: %v\n", err)
+		} else {
+			iconSrc = converted
+		}
+	}
+	launcher.shortcutIcon = &iconSrc
+	return iconSrc
+}
+
+// findShortcutIcon returns the downloaded icon most suitable for shortcuts:
+// icons of "shortcut" kind are preferred, then icons of 256x256 or larger, then the largest ones
 func (launcher *Launcher) findShortcutIcon() string {
-	iconDir := launcher.resourceDir
-	for _, icon := range launcher.jnlp.Information.Icons {
-		if icon.Kind != "" && icon.Kind != "default" && icon.Kind != "shortcut" {
+	var best *Icon
+	bestSize, bestDepth := 0, 0
+	for _, jnlpIcon := range launcher.jnlp.Information.Icons {
+		if jnlpIcon.Kind != "" && jnlpIcon.Kind != "default" && jnlpIcon.Kind != "shortcut" {
 			continue
 		}
-		if !icon.Downloaded {
+		if !jnlpIcon.Downloaded {
 			continue
 		}
-		if path.Ext(icon.Href) != ".ico" {
+		size, depth := launcher.getIconSize(jnlpIcon)
+		if best != nil && !isBetterShortcutIcon(jnlpIcon, size, depth, best, bestSize, bestDepth) {
 			continue
 		}
-		return filepath.Join(iconDir, path.Base(icon.Href))
+		best, bestSize, bestDepth = jnlpIcon, size, depth
+	}
+	if best == nil {
+		return ""
+	}
+	return filepath.Join(launcher.resourceDir, path.Base(best.Href))
+}
 
+// getIconSize returns size and color depth of the icon,
+// the size is taken from the image itself if the JNLP file doesn't specify it
+func (launcher *Launcher) getIconSize(jnlpIcon *Icon) (int, int) {
+	size := jnlpIcon.Width
+	if jnlpIcon.Height < size {
+		size = jnlpIcon.Height
 	}
-	return ""
+	if size > 0 {
+		return size, jnlpIcon.Depth
+	}
+	img, err := icon.Decode(filepath.Join(launcher.resourceDir, path.Base(jnlpIcon.Href)))
+	if err != nil {
+		log.Printf("warning: %v\n", err)
+		return 0, jnlpIcon.Depth
+	}
+	bounds := img.Bounds()
+	size = bounds.Dx()
+	if bounds.Dy() < size {
+		size = bounds.Dy()
+	}
+	return size, jnlpIcon.Depth
+}
+
+func isBetterShortcutIcon(candidate *Icon, size, depth int, best *Icon, bestSize, bestDepth int) bool {
+	if (candidate.Kind == "shortcut") != (best.Kind == "shortcut") {
+		return candidate.Kind == "shortcut"
+	}
+	const preferredSize = 256
+	if (size >= preferredSize) != (bestSize >= preferredSize) {
+		return size >= preferredSize
+	}
+	if size != bestSize {
+		if size >= preferredSize {
+			// both are large enough, the smaller one needs less downscaling
+			return size < bestSize
+		}
+		return size > bestSize
+	}
+	return depth > bestDepth
 }
 
 func (launcher *Launcher) getShortcutDescription() string {
@@ -716,6 +1617,10 @@ func (launcher *Launcher) getShortcutArguments() []string {
 	if launcher.options != nil && launcher.options.DisableVerificationSameOrigin {
 		arguments = append(arguments, "-disableVerificationSameOrigin")
 	}
+	if launcher.options != nil && launcher.options.Codebase != "" {
+		arguments = append(arguments, "-codebase")
+		arguments = append(arguments, launcher.options.Codebase)
+	}
 	arguments = append(arguments, launcher.getOriginalFilePath())
 	return arguments
 }
@@ -737,10 +1642,14 @@ func (launcher *Launcher) checkForUpdate(filedata []byte) ([]byte, error) {
 	if jnlpFile, err = Decode(filedata); err != nil {
 		return nil, errors.Wrap(err, "parsing JNLP")
 	}
+	jnlpFile.ExpandVariables(launcher.sourceURL)
 	if jnlpFile.Href == "" {
 		log.Printf("warning: unable to check jnlp file for update because <jnlp> tag doesn't have 'href' attribute or the attribute is empty")
 		return filedata, nil
 	}
+	if err = launcher.resolveCodebase(jnlpFile); err != nil {
+		return nil, err
+	}
 	var codeBaseURL *url.URL
 	codeBaseURL, err = launcher_utils.ParseCodebaseURL(jnlpFile.CodeBase)
 	if err != nil {
@@ -756,6 +1665,10 @@ func (launcher *Launcher) checkForUpdate(filedata []byte) ([]byte, error) {
 	var newFileData []byte
 	if newFileData, err = download.ToMemory(jnlpURL.String()); err != nil {
 		log.Printf("warning: unable to check jnlp file for update because %v", err)
+		if download.IsNetworkError(err) {
+			log.Printf("switching to offline mode because %s is not reachable", jnlpURL)
+			launcher.offline = true
+		}
 		return filedata, nil
 	}
 	if bytes.Compare(filedata, newFileData) == 0 {
@@ -769,6 +1682,8 @@ func (launcher *Launcher) checkForUpdate(filedata []byte) ([]byte, error) {
 	}
 	log.Printf("jnlp file updated successfully")
 	launcher.jnlpOld = jnlpFile
+	launcher.jnlpOldFile = filepath.Join(launcher.generateResourcesDirName(filedata), "original.jnlp")
+	launcher.sourceURL = jnlpURL
 	return newFileData, nil
 }
 
@@ -780,20 +1695,60 @@ func (launcher *Launcher) getOriginalFilePath() string {
 	return filepath.Join(launcher.resourceDir, "original.jnlp")
 }
 
+func (launcher *Launcher) getSourceURLFilePath() string {
+	return filepath.Join(launcher.resourceDir, "source.url")
+}
+
+func (launcher *Launcher) getAssociationsAcceptedFilePath() string {
+	return filepath.Join(launcher.resourceDir, "associations.accepted")
+}
+
+// saveSourceURL saves URL the JNLP file was downloaded from next to the original file,
+// so variables like $$codebase can be expanded when the app is started using a shortcut
+func (launcher *Launcher) saveSourceURL() error {
+	if launcher.sourceURL == nil || launcher.sourceURL.Scheme == "file" {
+		return nil
+	}
+	if err := ioutil.WriteFile(launcher.getSourceURLFilePath(), []byte(launcher.sourceURL.String()), 0644); err != nil {
+		return errors.Wrap(err, "unable to save source URL of jnlp file")
+	}
+	return nil
+}
+
+// findSourceURL returns URL a local JNLP file was originally downloaded from
+// if the file is a cached copy, otherwise returns file:// URL of the file
+func (launcher *Launcher) findSourceURL(filename string, filedata []byte) *url.URL {
+	resourceDir := launcher.generateResourcesDirName(filedata)
+	if data, err := ioutil.ReadFile(filepath.Join(resourceDir, "source.url")); err == nil {
+		if sourceURL, err := url.Parse(strings.TrimSpace(string(data))); err == nil {
+			log.Printf("jnlp file was downloaded from %s", sourceURL)
+			return sourceURL
+		}
+	}
+	sourceURL, err := launcher_utils.FileURL(filename)
+	if err != nil {
+		log.Printf("warning: unable to get URL for file %s: %v", filename, err)
+		return nil
+	}
+	return sourceURL
+}
+
 func (launcher *Launcher) installApp() error {
 	info := launcher.jnlp.Information
-	uninstallString := os.Args[0] + " -uninstall -gui \"" + launcher.getOriginalFilePath() + "\""
+	uninstallCommand := []string{utils.Executable(), "-uninstall", "-gui", launcher.getOriginalFilePath()}
+	uninstallString := utils.QuoteString(utils.Executable()) + " -uninstall -gui \"" + launcher.getOriginalFilePath() + "\""
 	url := ""
 	if info.Homepage != nil {
 		url = info.Homepage.Href
 	}
 	app := &utils.AppInfo{
-		Title:           info.Title,
-		UninstallString: uninstallString,
-		Icon:            launcher.findShortcutIcon(),
-		Version:         info.Version,
-		URL:             url,
-		Publisher:       info.Vendor,
+		Title:            info.Title,
+		UninstallString:  uninstallString,
+		UninstallCommand: uninstallCommand,
+		Icon:             launcher.getShortcutIcon(),
+		Version:          info.Version,
+		URL:              url,
+		Publisher:        info.Vendor,
 	}
 	log.Printf("adding app into Control Panel")
 	if err := utils.InstallApp(app); err != nil {
@@ -802,10 +1757,11 @@ func (launcher *Launcher) installApp() error {
 	return nil
 }
 
-func (launcher *Launcher) uninstallApp(jnlp *JNLP) error {
+// uninstallApp removes the app with the original JNLP file originalFile from Control Panel
+func (launcher *Launcher) uninstallApp(jnlp *JNLP, originalFile string) error {
 	title := jnlp.Title()
 	if title != "" {
-		if err := utils.UninstallApp(title); err != nil {
+		if err := utils.UninstallApp(title, originalFile); err != nil {
 			return errors.Wrap(err, "unable to uninstall app from control panel")
 		}
 	}
@@ -833,8 +1789,11 @@ func (launcher *Launcher) checkRequiredJavaVersion() error {
 			if err != nil {
 				return errors.Wrapf(err, `unable to parse version="%s" in jnlp file`, j2se.Version)
 			}
-			if !settings.CurrentJavaVersionMatches(requiredVersion) {
-				currentVersion, _ := settings.GetJavaVersion()
+			currentVersion, err := launcher.getJavaVersion()
+			if err != nil {
+				return err
+			}
+			if !currentVersion.Matches(requiredVersion) {
 				err = errors.Errorf(`This JNLP file requires Java version "%s"`, j2se.Version)
 				return utils.AddExtraLine(err, `Open Web Launch is using Java version "`+currentVersion.String+`"`)
 			}
@@ -1,3 +1,6 @@
 package jnlp
 
 const ClassPathSeparator = ":"
+
+// maxCommandLineLength is a safe limit for java command line, macOS limits all arguments to 256 KiB
+const maxCommandLineLength = 100000
@@ -1,3 +1,6 @@
 package jnlp
 
 const ClassPathSeparator = ":"
+
+// maxCommandLineLength is a safe limit for java command line, Linux limits a single argument to 128 KiB
+const maxCommandLineLength = 100000
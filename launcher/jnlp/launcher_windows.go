@@ -1,3 +1,6 @@
 package jnlp
 
 const ClassPathSeparator = ";"
+
+// maxCommandLineLength is a safe limit for java command line, CreateProcess limits it to 32767 characters
+const maxCommandLineLength = 30000
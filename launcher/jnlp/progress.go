@@ -0,0 +1,105 @@
+package jnlp
+
+import (
+	"path"
+
+	"github.com/rocketsoftware/open-web-launch/launcher"
+	"github.com/rocketsoftware/open-web-launch/utils/download"
+)
+
+// Phases and error codes of runs, methods can't refer to them because their receiver hides the launcher package
+const (
+	phaseResolve  = launcher.PhaseResolve
+	phaseDownload = launcher.PhaseDownload
+	phaseInstall  = launcher.PhaseInstall
+	phaseStart    = launcher.PhaseStart
+
+	errorInvalidJNLP  = launcher.ErrorInvalidJNLP
+	errorDownload     = launcher.ErrorDownload
+	errorVerification = launcher.ErrorVerification
+	errorBlocked      = launcher.ErrorBlocked
+	errorJava         = launcher.ErrorJava
+	errorOffline      = launcher.ErrorOffline
+	errorInstall      = launcher.ErrorInstall
+	errorStart        = launcher.ErrorStart
+)
+
+var withCode = launcher.WithCode
+
+// observer returns the observer of the run or nil
+func (launcher *Launcher) observer() launcher.Observer {
+	if launcher.options == nil {
+		return nil
+	}
+	return launcher.options.Observer
+}
+
+// setPhase reports a new phase of the run
+func (launcher *Launcher) setPhase(phase string) {
+	launcher.progressMutex.Lock()
+	launcher.phase = phase
+	launcher.progressMutex.Unlock()
+	launcher.notifyProgress("", 0, 0)
+}
+
+// setProgressMax sets the number of progress steps of the run
+func (launcher *Launcher) setProgressMax(max int) {
+	launcher.progressMutex.Lock()
+	launcher.progressMax, launcher.progressSteps = max, 0
+	launcher.progressMutex.Unlock()
+	launcher.gui.SetProgressMax(max)
+}
+
+// progressStep advances progress of the run
+func (launcher *Launcher) progressStep() {
+	launcher.progressMutex.Lock()
+	launcher.progressSteps++
+	launcher.progressMutex.Unlock()
+	launcher.gui.ProgressStep()
+	launcher.notifyProgress("", 0, 0)
+}
+
+// downloadProgress reports bytes of a file being downloaded
+func (launcher *Launcher) downloadProgress(url string, bytes, total int64) {
+	launcher.notifyProgress(path.Base(url), bytes, total)
+}
+
+func (launcher *Launcher) notifyProgress(file string, bytes, total int64) {
+	observer := launcher.observer()
+	if observer == nil {
+		return
+	}
+	launcher.progressMutex.Lock()
+	progress := newProgress(launcher.phase, launcher.progressSteps, launcher.progressMax)
+	launcher.progressMutex.Unlock()
+	progress.File, progress.Bytes, progress.Total = file, bytes, total
+	observer.Progress(progress)
+}
+
+// newProgress returns progress of a run in phase after steps of max steps
+func newProgress(phase string, steps, max int) *launcher.Progress {
+	progress := &launcher.Progress{Phase: phase}
+	if max > 0 {
+		progress.Percent = steps * 100 / max
+	}
+	if phase == launcher.PhaseStart || progress.Percent > 100 {
+		progress.Percent = 100
+	}
+	return progress
+}
+
+// notifyPrompt reports the answer of the user to a security question
+func (launcher *Launcher) notifyPrompt(text string, accepted bool) {
+	if observer := launcher.observer(); observer != nil {
+		observer.Prompt(text, accepted)
+	}
+}
+
+// observeDownloads reports progress of downloads of the run to its observer
+func (launcher *Launcher) observeDownloads() {
+	if launcher.observer() != nil {
+		download.SetProgress(launcher.downloadProgress)
+	} else {
+		download.SetProgress(nil)
+	}
+}
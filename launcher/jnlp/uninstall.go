@@ -2,6 +2,7 @@ package jnlp
 
 import (
 	"io/ioutil"
+	"path/filepath"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -21,6 +22,7 @@ func (launcher *Launcher) UninstallByURL(url string, showGUI bool) error {
 
 func (launcher *Launcher) uninstallByFilenameOrURL(filenameOrURL string, showGUI bool, isURL bool) error {
 	log.Printf("uninstall using %s", filenameOrURL)
+	launcher.gui = nil
 	if showGUI {
 		launcher.gui = gui.New()
 		launcher.gui.SetLogFile(launcher.logFile)
@@ -68,10 +70,11 @@ func (launcher *Launcher) uninstallUsingFileData(filedata []byte) error {
 	launcher.gui.SetTitle(jnlpFile.Title())
 	launcher.gui.SetProgressMax(3)
 	launcher.removeShortcuts(jnlpFile)
+	launcher.unregisterAssociations(jnlpFile)
 	launcher.gui.ProgressStep()
 	launcher_utils.RemoveResourceDir(launcher.WorkDir, filedata)
 	launcher.gui.ProgressStep()
-	launcher.uninstallApp(jnlpFile)
+	launcher.uninstallApp(jnlpFile, filepath.Join(launcher.generateResourcesDirName(filedata), "original.jnlp"))
 	launcher.gui.ProgressStep()
 	launcher.gui.SendTextMessage("Uninstall complete")
 	return nil
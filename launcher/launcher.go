@@ -29,6 +29,19 @@ type Options struct {
 	ShowConsole                   bool
 	DisableVerification           bool
 	DisableVerificationSameOrigin bool
+	Codebase                      string   // Overrides codebase of JNLP file
+	Open                          string   // Document passed to the application with -open argument
+	Print                         string   // Document passed to the application with -print argument
+	Offline                       bool     // Don't check JNLP file for update
+	Wait                          bool     // Wait until the application exits
+	NoSplash                      bool     // Don't show splash screen of the application
+	JVMArgs                       []string // Additional arguments for JVM
+	Silent                        bool     // Don't show GUI
+	Import                        bool     // Install the application without running it
+	ImportShortcuts               bool     // Create shortcuts during import
+	ImportAssociations            bool     // Register file associations during import
+	Export                        string   // Bundle file the resolved application is written to instead of running it
+	Observer                      Observer // Receives progress, security questions and failures of the run
 }
 
 func RegisterProtocol(scheme string, launcher Launcher) {
@@ -0,0 +1,73 @@
+package launcher
+
+// Phases of a run reported to observers
+const (
+	PhaseResolve  = "resolve"  // Downloading and parsing the JNLP file
+	PhaseDownload = "download" // Downloading and verifying resources
+	PhaseInstall  = "install"  // Creating shortcuts and file associations
+	PhaseStart    = "start"    // Starting the application
+)
+
+// Error codes of failed runs
+const (
+	ErrorInvalidJNLP  = "invalid_jnlp"        // The JNLP file can't be parsed
+	ErrorDownload     = "download_failed"     // A file can't be downloaded
+	ErrorVerification = "verification_failed" // A JAR isn't signed properly
+	ErrorBlocked      = "blocked"             // The application is blocked by policy
+	ErrorCancelled    = "cancelled"           // The user cancelled the run
+	ErrorJava         = "java_unavailable"    // Java matching the application isn't available
+	ErrorOffline      = "offline_unavailable" // The application can't run offline
+	ErrorInstall      = "install_failed"      // Files or shortcuts can't be created
+	ErrorStart        = "start_failed"        // Java can't be started
+	ErrorUnknown      = "unknown"             // Any other failure
+)
+
+// Progress is progress of a run
+type Progress struct {
+	Phase   string
+	File    string // Name of the file being downloaded, empty between files
+	Bytes   int64  // Downloaded bytes of File
+	Total   int64  // Size of File, 0 if it is unknown
+	Percent int    // Progress of the whole run
+}
+
+// Observer is notified about a run, e.g. to show it in the browser page.
+// Progress is reported from several goroutines.
+type Observer interface {
+	Progress(progress *Progress)
+	Prompt(text string, accepted bool) // The user answered a security question
+	Failed(err error)                  // The run failed, also when the error is only shown in the window
+}
+
+// codedError is an error with a code for observers, errors.Cause returns the original error
+type codedError struct {
+	error
+	code string
+}
+
+func (err *codedError) Cause() error {
+	return err.error
+}
+
+// WithCode adds code to err unless it already has a code
+func WithCode(err error, code string) error {
+	if err == nil || ErrorCode(err) != "" {
+		return err
+	}
+	return &codedError{err, code}
+}
+
+// ErrorCode returns the first code found in the chain of causes of err or empty string
+func ErrorCode(err error) string {
+	for err != nil {
+		if coded, ok := err.(*codedError); ok {
+			return coded.code
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return ""
+		}
+		err = cause.Cause()
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package launcher
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func Test_ErrorCode(t *testing.T) {
+	base := errors.New("connection refused")
+	verification := WithCode(errors.New("JAR verification failed app.jar"), ErrorVerification)
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"without code", errors.Wrap(base, "downloading app.jar"), ""},
+		{"with code", WithCode(errors.Wrap(base, "downloading app.jar"), ErrorDownload), ErrorDownload},
+		{"wrapped code", errors.Wrap(WithCode(base, ErrorDownload), "running app"), ErrorDownload},
+		{"first code wins", WithCode(verification, ErrorDownload), ErrorVerification},
+		{"nil", WithCode(nil, ErrorDownload), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+	if errors.Cause(WithCode(base, ErrorDownload)) != base {
+		t.Error("errors.Cause() doesn't return the original error")
+	}
+}
@@ -0,0 +1,168 @@
+// Package policy provides enterprise rules deciding whether an application may run,
+// similar to Deployment Rule Sets of Java. Rules are evaluated in order and the first matching rule wins.
+// A rule can also select Java used for the application and add JVM options.
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	launcher_utils "github.com/rocketsoftware/open-web-launch/launcher/utils"
+	"github.com/rocketsoftware/open-web-launch/verifier"
+)
+
+// Action is a decision of a rule
+type Action string
+
+const (
+	Allow  Action = "allow"  // Run the application without asking the user
+	Block  Action = "block"  // Don't run the application
+	Prompt Action = "prompt" // Ask the user whether the application should run
+)
+
+// Policy is a content of a policy file
+type Policy struct {
+	Rules         []*Rule `json:"rules"`
+	DefaultAction Action  `json:"defaultAction,omitempty"` // Action for applications not matching any rule, allow by default
+}
+
+// Rule describes an action for matching applications.
+// Criteria which are not set match every application.
+type Rule struct {
+	Name     string   `json:"name"`               // Name of the rule, used for logging
+	URL      string   `json:"url,omitempty"`      // Pattern for URLs of JNLP file and JARs like "https://*.example.com/apps/*"
+	Title    string   `json:"title,omitempty"`    // Pattern for application title
+	Signer   string   `json:"signer,omitempty"`   // SHA-256 fingerprint of the certificate used for signing JARs
+	Checksum string   `json:"checksum,omitempty"` // SHA-256 checksum of the main JAR in hex
+	Action   Action   `json:"action"`
+	JavaDir  string   `json:"javaDir,omitempty"` // Java folder used for the application
+	JVMArgs  []string `json:"jvmArgs,omitempty"` // Additional JVM options
+	Message  string   `json:"message,omitempty"` // Message shown when the application is blocked
+}
+
+// App identifies an application for rule matching
+type App struct {
+	URL        string   // URL the JNLP file was downloaded from
+	JARURLs    []string // URLs of JARs, native libraries and extensions, they must match URL pattern of a rule too
+	Title      string   // Application title
+	Signer     string   // SHA-256 fingerprint of the certificate signing all JARs, empty if they are not verified or not downloaded yet
+	Checksum   string   // SHA-256 checksum of the main JAR, empty if it is not downloaded yet
+	Downloaded bool     // JARs are downloaded, so Signer and Checksum are known
+}
+
+// Load reads policy from filename
+func Load(filename string) (*Policy, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read policy file %s", filename)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse policy file %s", filename)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid policy file %s", filename)
+	}
+	return &policy, nil
+}
+
+func (policy *Policy) validate() error {
+	for i, rule := range policy.Rules {
+		if !rule.Action.isValid() {
+			return errors.Errorf("rule %d %q has invalid action %q", i+1, rule.Name, rule.Action)
+		}
+		if rule.URL != "" && !strings.Contains(rule.URL, "://") {
+			return errors.Errorf("rule %d %q has URL pattern %q without a scheme", i+1, rule.Name, rule.URL)
+		}
+	}
+	if policy.DefaultAction != "" && !policy.DefaultAction.isValid() {
+		return errors.Errorf("invalid default action %q", policy.DefaultAction)
+	}
+	return nil
+}
+
+func (action Action) isValid() bool {
+	return action == Allow || action == Block || action == Prompt
+}
+
+// Evaluate returns the first rule matching app or a default rule if none matches.
+// If JARs of app are not downloaded and the first rule matching URL and title checks signer or checksum,
+// the decision is postponed and decided is false.
+func (policy *Policy) Evaluate(app *App) (rule *Rule, decided bool) {
+	for _, rule := range policy.Rules {
+		if !rule.matchesDescriptor(app) {
+			continue
+		}
+		if rule.Signer != "" || rule.Checksum != "" {
+			if !app.Downloaded {
+				return nil, false
+			}
+			if !rule.matchesResources(app) {
+				continue
+			}
+		}
+		return rule, true
+	}
+	action := policy.DefaultAction
+	if action == "" {
+		action = Allow
+	}
+	return &Rule{Name: "default", Action: action}, true
+}
+
+func (rule *Rule) matchesDescriptor(app *App) bool {
+	if rule.URL != "" {
+		if !launcher_utils.MatchURLPattern(rule.URL, app.URL) {
+			return false
+		}
+		for _, jarURL := range app.JARURLs {
+			if !launcher_utils.MatchURLPattern(rule.URL, jarURL) {
+				return false
+			}
+		}
+	}
+	if rule.Title != "" && !launcher_utils.MatchPattern(rule.Title, app.Title) {
+		return false
+	}
+	return true
+}
+
+func (rule *Rule) matchesResources(app *App) bool {
+	if rule.Signer != "" {
+		if app.Signer == "" || verifier.NormalizeFingerprint(rule.Signer) != verifier.NormalizeFingerprint(app.Signer) {
+			return false
+		}
+	}
+	if rule.Checksum != "" && !strings.EqualFold(rule.Checksum, app.Checksum) {
+		return false
+	}
+	return true
+}
+
+// BlockedMessage returns the message shown when the application is blocked by rule
+func (rule *Rule) BlockedMessage() string {
+	if rule.Message != "" {
+		return rule.Message
+	}
+	return "The application is blocked by policy rule \"" + rule.Name + "\""
+}
+
+// FileChecksum returns SHA-256 checksum of filename in hex
+func FileChecksum(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"testing"
+)
+
+func Test_Evaluate(t *testing.T) {
+	policy := &Policy{
+		Rules: []*Rule{
+			{Name: "intranet signed", URL: "https://intranet/*", Signer: "ab:cd:ef", Action: Allow, JavaDir: "/opt/java8"},
+			{Name: "known jar", Checksum: "0A1B", Action: Allow},
+			{Name: "intranet", URL: "https://intranet/*", Action: Prompt},
+			{Name: "corp", URL: "https://*.corp.com/apps/*", Action: Allow},
+			{Name: "blocked title", Title: "Legacy *", Action: Block},
+		},
+		DefaultAction: Block,
+	}
+	tests := []struct {
+		name        string
+		app         *App
+		wantRule    string
+		wantDecided bool
+	}{
+		{"not downloaded", &App{URL: "https://intranet/app.jnlp"}, "", false},
+		{"signed", &App{URL: "https://INTRANET/app.jnlp", Signer: "ABCDEF", Downloaded: true}, "intranet signed", true},
+		{"unsigned", &App{URL: "https://intranet/app.jnlp", Downloaded: true}, "intranet", true},
+		{"JAR of another site", &App{URL: "https://intranet/app.jnlp", JARURLs: []string{"https://intranet/app.jar", "https://evil.com/lib.jar"}, Downloaded: true}, "default", true},
+		{"subdomain", &App{URL: "https://www.corp.com/apps/app.jnlp", Downloaded: true}, "corp", true},
+		{"path after host", &App{URL: "https://evil.com/.corp.com/apps/app.jnlp", Downloaded: true}, "default", true},
+		{"user info", &App{URL: "https://www.corp.com@evil.com/apps/app.jnlp", Downloaded: true}, "default", true},
+		{"query", &App{URL: "https://evil.com/?.corp.com/apps/app.jnlp", Downloaded: true}, "default", true},
+		{"host suffix", &App{URL: "https://www.corp.com.evil.com/apps/app.jnlp", Downloaded: true}, "default", true},
+		{"other port", &App{URL: "https://www.corp.com:8443/apps/app.jnlp", Downloaded: true}, "default", true},
+		{"default port", &App{URL: "https://www.corp.com:443/apps/app.jnlp", Downloaded: true}, "corp", true},
+		{"other scheme", &App{URL: "http://www.corp.com/apps/app.jnlp", Downloaded: true}, "default", true},
+		{"parent path", &App{URL: "https://www.corp.com/apps/../private/app.jnlp", Downloaded: true}, "default", true},
+		{"checksum", &App{URL: "https://internet/app.jnlp", Checksum: "0a1b", Downloaded: true}, "known jar", true},
+		{"title", &App{URL: "https://internet/app.jnlp", Title: "Legacy App", Downloaded: true}, "blocked title", true},
+		{"default", &App{URL: "https://internet/app.jnlp", Downloaded: true}, "default", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, decided := policy.Evaluate(tt.app)
+			if decided != tt.wantDecided {
+				t.Fatalf("Evaluate() decided = %v, want %v", decided, tt.wantDecided)
+			}
+			gotRule := ""
+			if rule != nil {
+				gotRule = rule.Name
+			}
+			if gotRule != tt.wantRule {
+				t.Errorf("Evaluate() rule = %q, want %q", gotRule, tt.wantRule)
+			}
+		})
+	}
+}
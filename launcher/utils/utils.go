@@ -5,8 +5,10 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -35,18 +37,47 @@ func RemoveResourceDir(workDir string, filedata []byte) error {
 	return nil
 }
 
-// ParseCodebaseURL parses codebase URL, adds trailing slash if needed
+// ParseCodebaseURL parses codebase URL, adds trailing slash to its path if needed
 func ParseCodebaseURL(codebase string) (*url.URL, error) {
-	if !strings.HasSuffix(codebase, "/") {
-		codebase += "/"
-	}
 	codebaseURL, err := url.Parse(codebase)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to parse codebase URL")
 	}
+	addTrailingSlash(codebaseURL)
 	return codebaseURL, nil
 }
 
+func addTrailingSlash(codebaseURL *url.URL) {
+	if strings.HasSuffix(codebaseURL.Path, "/") {
+		return
+	}
+	codebaseURL.Path += "/"
+	if codebaseURL.RawPath != "" {
+		codebaseURL.RawPath += "/"
+	}
+}
+
+// ResolveCodebaseURL returns absolute codebase URL.
+// An empty or relative codebase is resolved against jnlpURL - URL the JNLP file was downloaded from.
+func ResolveCodebaseURL(codebase string, jnlpURL *url.URL) (*url.URL, error) {
+	codebaseURL, err := url.Parse(codebase)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse codebase URL")
+	}
+	if codebaseURL.IsAbs() {
+		return ParseCodebaseURL(codebase)
+	}
+	if jnlpURL == nil {
+		return nil, errors.Errorf("codebase %q is not absolute and location of JNLP file is unknown", codebase)
+	}
+	if codebase == "" {
+		codebaseURL = &url.URL{Path: "./"}
+	}
+	resolved := jnlpURL.ResolveReference(codebaseURL)
+	addTrailingSlash(resolved)
+	return resolved, nil
+}
+
 // ArchSynonyms maps JWS architectures to Golang ones
 var ArchSynonyms = map[string]string{
 	"x86":    "386",
@@ -96,6 +127,35 @@ func AreResourcesRelevantForCurrentPlatform(os string, arch string) bool {
 	return false
 }
 
+// LocaleMatch returns how well locales - a space separated list of locales from JNLP file - match userLocale:
+// 0 if locales is empty, 1 if a language matches, 2 if both language and country match and -1 if nothing matches
+func LocaleMatch(locales string, userLocale string) int {
+	if locales == "" {
+		return 0
+	}
+	userLocale = strings.ToLower(strings.Replace(userLocale, "-", "_", -1))
+	userLanguage := strings.SplitN(userLocale, "_", 2)[0]
+	bestMatch := -1
+	for _, locale := range utils.SplitEscapedString(locales) {
+		locale = strings.ToLower(strings.Replace(locale, "-", "_", -1))
+		if locale == "" {
+			continue
+		}
+		if locale == userLocale {
+			return 2
+		}
+		if locale == userLanguage {
+			bestMatch = 1
+		}
+	}
+	return bestMatch
+}
+
+// IsLocaleRelevant returns true if resources or information for locales should be used for userLocale
+func IsLocaleRelevant(locales string, userLocale string) bool {
+	return LocaleMatch(locales, userLocale) >= 0
+}
+
 // Extract unpacks zip archive zipFilename into directory dir
 func Extract(zipFilename string, dir string) error {
 	absPath, err := filepath.Abs(dir)
@@ -140,4 +200,167 @@ func Extract(zipFilename string, dir string) error {
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// MatchPattern reports whether s matches pattern where '*' matches any sequence of characters
+// and the rest of the pattern is compared case-insensitively, e.g. "https://intranet/*"
+func MatchPattern(pattern string, s string) bool {
+	pattern = strings.ToLower(pattern)
+	s = strings.ToLower(s)
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(s, part)
+		if index == -1 {
+			return false
+		}
+		s = s[index+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
+
+// MatchURLPattern reports whether rawurl matches pattern like "https://*.example.com:8443/apps/*".
+// Scheme, host, port and path are matched separately: '*' in the host matches only characters of host names,
+// the port must be the port of the pattern or the default port of the scheme if the pattern has none,
+// the query of rawurl is ignored. Patterns without a scheme don't match any URL.
+func MatchURLPattern(pattern string, rawurl string) bool {
+	index := strings.Index(pattern, "://")
+	if index <= 0 {
+		return false
+	}
+	scheme, authority, patternPath := pattern[:index], pattern[index+3:], "/"
+	if index := strings.Index(authority, "/"); index != -1 {
+		authority, patternPath = authority[:index], authority[index:]
+	}
+	if strings.ContainsAny(scheme+authority, "@?#") || strings.ContainsAny(patternPath, "?#") {
+		return false
+	}
+	target, err := url.Parse(rawurl)
+	if err != nil || target.User != nil || target.Opaque != "" {
+		return false
+	}
+	if !MatchPattern(scheme, target.Scheme) {
+		return false
+	}
+	patternHost, patternPort := authority, ""
+	if index := strings.LastIndex(authority, ":"); index != -1 && !strings.HasSuffix(authority, "]") {
+		patternHost, patternPort = authority[:index], authority[index+1:]
+	}
+	if !MatchPattern(strings.Trim(patternHost, "[]"), target.Hostname()) {
+		return false
+	}
+	port := target.Port()
+	if port == "" {
+		port = defaultPorts[strings.ToLower(target.Scheme)]
+	}
+	if patternPort == "" {
+		patternPort = defaultPorts[strings.ToLower(target.Scheme)]
+	}
+	if patternPort != "*" && patternPort != port {
+		return false
+	}
+	targetPath := target.Path
+	if targetPath == "" {
+		targetPath = "/"
+	}
+	if cleaned := path.Clean(targetPath); cleaned != targetPath && cleaned+"/" != targetPath {
+		return false
+	}
+	return MatchPattern(patternPath, targetPath)
+}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443", "ftp": "21"}
+
+// WriteArgFile writes args into Java @argfile, supported by Java 9 and later.
+// Every argument is quoted so paths with spaces and backslashes are preserved.
+// An existing file is replaced, so the new file always has permissions perm.
+func WriteArgFile(filename string, args []string, perm os.FileMode) error {
+	var builder strings.Builder
+	for _, arg := range args {
+		builder.WriteString(QuoteArgFileArgument(arg))
+		builder.WriteString("\n")
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "unable to replace argument file %s", filename)
+	}
+	if err := ioutil.WriteFile(filename, []byte(builder.String()), perm); err != nil {
+		return errors.Wrapf(err, "unable to write argument file %s", filename)
+	}
+	return nil
+}
+
+// QuoteArgFileArgument quotes arg according to Java @argfile syntax
+func QuoteArgFileArgument(arg string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(arg) + `"`
+}
+
+const manifestMaxLineLength = 72
+
+// WritePathingJAR creates a JAR which contains only a manifest with Class-Path attribute
+// referring to classPath entries. Entries are paths relative to the directory of the JAR.
+// It allows running applications with very long classpaths on Java 8 which doesn't support @argfiles.
+func WritePathingJAR(filename string, classPath []string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to create pathing JAR %s", filename)
+		}
+	}()
+	var entries []string
+	for _, entry := range classPath {
+		entryURL := &url.URL{Path: filepath.ToSlash(entry)}
+		entries = append(entries, entryURL.EscapedPath())
+	}
+	manifest := "Manifest-Version: 1.0\r\n"
+	manifest += wrapManifestLine("Class-Path: " + strings.Join(entries, " "))
+	manifest += "Created-By: Open Web Launch\r\n\r\n"
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	writer, err := archive.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write([]byte(manifest)); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// wrapManifestLine splits line into lines no longer than 72 bytes, continuation lines start with a space
+func wrapManifestLine(line string) string {
+	var builder strings.Builder
+	maxLength := manifestMaxLineLength
+	for len(line) > maxLength {
+		builder.WriteString(line[:maxLength])
+		builder.WriteString("\r\n ")
+		line = line[maxLength:]
+		maxLength = manifestMaxLineLength - 1
+	}
+	builder.WriteString(line)
+	builder.WriteString("\r\n")
+	return builder.String()
+}
+
+// FileURL converts filename to an absolute file:// URL
+func FileURL(filename string) (*url.URL, error) {
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	slashPath := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(slashPath, "/") {
+		slashPath = "/" + slashPath
+	}
+	return &url.URL{Scheme: "file", Path: slashPath}, nil
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_MatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"https://intranet/*", "https://intranet/app/app.jnlp", true},
+		{"https://intranet/*", "https://internet/app.jnlp", false},
+		{"*.example.com/*.jnlp", "https://apps.EXAMPLE.com/a/b.jnlp", true},
+		{"*.example.com/*.jnlp", "https://apps.example.com/a/b.jar", false},
+		{"My App", "my app", true},
+		{"*", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.s, func(t *testing.T) {
+			if got := MatchPattern(tt.pattern, tt.s); got != tt.want {
+				t.Errorf("MatchPattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ResolveCodebaseURL(t *testing.T) {
+	jnlpURL, _ := url.Parse("https://host/apps/app/launch.jnlp")
+	tests := []struct {
+		codebase string
+		jnlpURL  *url.URL
+		want     string
+		wantErr  bool
+	}{
+		{"https://mirror/app", jnlpURL, "https://mirror/app/", false},
+		{"https://mirror/app?v=1", jnlpURL, "https://mirror/app/?v=1", false},
+		{"", jnlpURL, "https://host/apps/app/", false},
+		{"lib", jnlpURL, "https://host/apps/app/lib/", false},
+		{"../shared/", jnlpURL, "https://host/apps/shared/", false},
+		{"//cdn.example.com/app/", jnlpURL, "https://cdn.example.com/app/", false},
+		{"lib%20dir/", jnlpURL, "https://host/apps/app/lib%20dir/", false},
+		{"../x/?v=1", jnlpURL, "https://host/apps/x/?v=1", false},
+		{"../x?v=1", jnlpURL, "https://host/apps/x/?v=1", false},
+		{"lib", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.codebase, func(t *testing.T) {
+			got, err := ResolveCodebaseURL(tt.codebase, tt.jnlpURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveCodebaseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ResolveCodebaseURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_LocaleMatch(t *testing.T) {
+	tests := []struct {
+		locales    string
+		userLocale string
+		want       int
+	}{
+		{"", "en_US", 0},
+		{"en", "en_US", 1},
+		{"de en_US", "en_US", 2},
+		{"en-us", "en_US", 2},
+		{"de_DE", "de_AT", -1},
+		{"fr", "", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locales+" "+tt.userLocale, func(t *testing.T) {
+			if got := LocaleMatch(tt.locales, tt.userLocale); got != tt.want {
+				t.Errorf("LocaleMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_QuoteArgFileArgument(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"a.jar", `"a.jar"`},
+		{`C:\Program Files\a.jar`, `"C:\\Program Files\\a.jar"`},
+		{`say "hi"`, `"say \"hi\""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			if got := QuoteArgFileArgument(tt.arg); got != tt.want {
+				t.Errorf("QuoteArgFileArgument() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_WritePathingJAR(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pathing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var classPath []string
+	for i := 0; i < 20; i++ {
+		classPath = append(classPath, "library with spaces "+strings.Repeat("x", i)+".jar")
+	}
+	filename := filepath.Join(dir, "classpath.jar")
+	if err := WritePathingJAR(filename, classPath); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	if len(archive.File) != 1 || archive.File[0].Name != "META-INF/MANIFEST.MF" {
+		t.Fatalf("unexpected pathing JAR content %v", archive.File)
+	}
+	reader, err := archive.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\r\n")
+	for _, line := range lines {
+		if len(line) > 72 {
+			t.Errorf("manifest line is longer than 72 bytes: %q", line)
+		}
+	}
+	unwrapped := strings.Replace(string(data), "\r\n ", "", -1)
+	if !strings.Contains(unwrapped, "Class-Path: library%20with%20spaces%20.jar library%20with%20spaces%20x.jar ") {
+		t.Errorf("unexpected Class-Path in manifest %q", unwrapped)
+	}
+}
@@ -4,15 +4,46 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/pkg/errors"
 	"github.com/rocketsoftware/open-web-launch/utils/log"
 )
 
+// Maximum sizes of native messages, browsers disconnect hosts sending larger messages
+const (
+	MaxIncomingMessageSize = 64 * 1024 * 1024 // Messages from Chrome are limited to 64 MiB, Firefox allows more
+	MaxOutgoingMessageSize = 1024 * 1024      // Messages to the browser are limited to 1 MB
+)
+
+// MalformedMessageError is returned for a message which can't be decoded,
+// following messages can still be received because its length was valid
+type MalformedMessageError struct {
+	Err error
+}
+
+func (err *MalformedMessageError) Error() string {
+	return fmt.Sprintf("malformed message: %v", err.Err)
+}
+
+// Message is a message from the extension, messages without Type use the original protocol
 type Message struct {
-	URL    string `json:"jnlp,omitempty"`
-	Status string `json:"status,omitempty"`
+	Type    string            `json:"type,omitempty"`
+	ID      json.RawMessage   `json:"id,omitempty"`      // Request ID, string or number copied to events of the request
+	Version int               `json:"version,omitempty"` // Protocol version of the extension in hello messages
+	URL     string            `json:"jnlp,omitempty"`
+	Status  string            `json:"status,omitempty"`
+	Cookies []*Cookie         `json:"cookies,omitempty"` // Browser cookies for the origin of the JNLP file
+	Headers map[string]string `json:"headers,omitempty"` // Browser request headers for the origin of the JNLP file
+}
+
+// Cookie is a browser cookie sent by the extension
+type Cookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Path   string `json:"path,omitempty"`
+	Secure bool   `json:"secure,omitempty"`
 }
 
 // GetMessage gets a message from a connected browser extention
@@ -26,15 +57,26 @@ func GetMessage(reader io.Reader) (message *Message, err error) {
 	if err = binary.Read(reader, binary.LittleEndian, &dataLen); err != nil {
 		return
 	}
+	if dataLen < 0 || dataLen > MaxIncomingMessageSize {
+		err = errors.Errorf("invalid message length %d", dataLen)
+		return
+	}
 	data := make([]byte, dataLen)
-	if _, err = reader.Read(data); err != nil {
+	if _, err = io.ReadFull(reader, data); err != nil {
+		// stdin closed in the middle of a message isn't a clean exit
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
 		return
 	}
-	log.Printf("got message %v\n", string(data))
 	var msg Message
 	if err = json.Unmarshal(data, &msg); err != nil {
+		err = &MalformedMessageError{err}
 		return
 	}
+	// cookies and headers are secrets of the browser session, they aren't logged
+	log.Printf("got message type=%q id=%s jnlp=%q status=%q with %d cookies and %d headers\n",
+		msg.Type, msg.ID, msg.URL, msg.Status, len(msg.Cookies), len(msg.Headers))
 	return &msg, nil
 }
 
@@ -45,7 +87,10 @@ func SendMessage(writer io.Writer, message string) (err error) {
 			err = errors.Wrapf(err, "error while sending message '%s'", message)
 		}
 	}()
-	// messageLen := int32(len(message))
+	if len(message) > MaxOutgoingMessageSize {
+		err = errors.Errorf("message length %d exceeds %d", len(message), MaxOutgoingMessageSize)
+		return
+	}
 	buffer := new(bytes.Buffer)
 	if err = binary.Write(buffer, binary.LittleEndian, int32(len(message))); err != nil {
 		return
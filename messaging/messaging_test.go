@@ -1,8 +1,14 @@
 package messaging
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/pkg/errors"
 )
 
 func TestStatusMessage(t *testing.T) {
@@ -28,3 +34,54 @@ func TestSendMessage(t *testing.T) {
 		panic(err)
 	}
 }
+
+func Test_GetMessage(t *testing.T) {
+	frame := func(length int32, data string) []byte {
+		var buffer bytes.Buffer
+		binary.Write(&buffer, binary.LittleEndian, length)
+		buffer.WriteString(data)
+		return buffer.Bytes()
+	}
+	tests := []struct {
+		name      string
+		input     []byte
+		wantURL   string
+		wantCause error
+		malformed bool
+	}{
+		{"valid", frame(18, `{"jnlp": "a.jnlp"}`), "a.jnlp", nil, false},
+		{"malformed", frame(7, `{"jnlp"`), "", nil, true},
+		{"closed stdin", nil, "", io.EOF, false},
+		{"partial length", []byte{1, 0}, "", io.ErrUnexpectedEOF, false},
+		{"partial message", frame(18, `{"jnlp"`), "", io.ErrUnexpectedEOF, false},
+		{"negative length", frame(-1, ""), "", nil, false},
+		{"too large", frame(MaxIncomingMessageSize+1, ""), "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := GetMessage(bytes.NewReader(tt.input))
+			if tt.wantURL != "" {
+				if err != nil || message.URL != tt.wantURL {
+					t.Fatalf("GetMessage() = %+v, %v, want %s", message, err, tt.wantURL)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("GetMessage() = %+v, want error", message)
+			}
+			if tt.wantCause != nil && errors.Cause(err) != tt.wantCause {
+				t.Errorf("GetMessage() error = %v, want %v", err, tt.wantCause)
+			}
+			if _, ok := errors.Cause(err).(*MalformedMessageError); ok != tt.malformed {
+				t.Errorf("GetMessage() error = %v, malformed %v", err, tt.malformed)
+			}
+		})
+	}
+}
+
+func Test_SendMessage_tooLarge(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := SendMessage(&buffer, strings.Repeat("x", MaxOutgoingMessageSize+1)); err == nil || buffer.Len() != 0 {
+		t.Errorf("SendMessage() error = %v, written %d bytes", err, buffer.Len())
+	}
+}
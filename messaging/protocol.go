@@ -0,0 +1,75 @@
+package messaging
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ProtocolVersion is the version of the typed protocol. Messages without type use the original protocol,
+// where the host only answers {"status": ...} at the end.
+const ProtocolVersion = 1
+
+// Types of messages from the extension and events from the host
+const (
+	TypeHello    = "hello"    // Handshake, both sides send their protocol version
+	TypeStatus   = "status"   // Request checking that the host is installed
+	TypeLaunch   = "launch"   // Request running the JNLP file
+	TypeProgress = "progress" // Event with progress of a launch
+	TypePrompt   = "prompt"   // Event with a security question answered by the user
+	TypeResult   = "result"   // Final event of a request
+)
+
+// Statuses of results
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Error codes of requests, failed launches have error codes of the launcher
+const (
+	ErrorInvalidRequest     = "invalid_request"
+	ErrorUnsupportedVersion = "unsupported_version"
+)
+
+// Event is a message from the host in the typed protocol
+type Event struct {
+	Type     string          `json:"type"`
+	ID       json.RawMessage `json:"id,omitempty"`       // ID of the request the event belongs to
+	Version  int             `json:"version,omitempty"`  // Protocol version in hello and status results
+	Status   string          `json:"status,omitempty"`   // Status of results, ok or error
+	Progress *Progress       `json:"progress,omitempty"` // Progress of progress events
+	Prompt   *Prompt         `json:"prompt,omitempty"`   // Question of prompt events
+	Error    *Error          `json:"error,omitempty"`    // Error of failed results
+}
+
+// Progress is progress of a launch
+type Progress struct {
+	Phase   string `json:"phase"`           // resolve, download, install or start
+	File    string `json:"file,omitempty"`  // File being downloaded
+	Bytes   int64  `json:"bytes,omitempty"` // Downloaded bytes of the file
+	Total   int64  `json:"total,omitempty"` // Size of the file if it is known
+	Percent int    `json:"percent"`         // Progress of the whole launch
+}
+
+// Prompt is a security question answered by the user in the launcher window
+type Prompt struct {
+	Text     string `json:"text"`
+	Accepted bool   `json:"accepted"`
+}
+
+// Error is an error of a request
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// SendEvent sends an event to a connected browser extension
+func SendEvent(writer io.Writer, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "error while encoding %s event", event.Type)
+	}
+	return SendMessage(writer, string(data))
+}
@@ -0,0 +1,161 @@
+package settings
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+)
+
+// lockedKey is the name of a system setting listing keys users can't override,
+// e.g. Locked=DisableVerification,JavaDir
+const lockedKey = "Locked"
+
+// lockedSuffix marks a single key locked in configuration files, e.g. DisableVerification.locked=true
+const lockedSuffix = ".locked"
+
+// configLayer is a source of settings like a configuration file, a plist or a registry key
+type configLayer struct {
+	name   string            // Shown as the source of settings, e.g. path of the file
+	values map[string]string // Values by lower case key
+	locked map[string]bool   // Lower case keys which can't be overridden by the user layer
+}
+
+func newConfigLayer(name string) *configLayer {
+	return &configLayer{name: name, values: make(map[string]string), locked: make(map[string]bool)}
+}
+
+func (layer *configLayer) set(key, value string) {
+	key = strings.ToLower(key)
+	if key == strings.ToLower(lockedKey) {
+		for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+			layer.locked[strings.ToLower(name)] = true
+		}
+		return
+	}
+	if strings.HasSuffix(key, lockedSuffix) {
+		if value == "" || parseBool(value) {
+			layer.locked[strings.TrimSuffix(key, lockedSuffix)] = true
+		}
+		return
+	}
+	layer.values[key] = value
+}
+
+func (layer *configLayer) get(key string) (string, bool) {
+	value, ok := layer.values[strings.ToLower(key)]
+	return value, ok
+}
+
+// configLayers are layers of one level ordered by precedence
+type configLayers []*configLayer
+
+// newConfigLayers returns layers skipping missing ones
+func newConfigLayers(layers ...*configLayer) configLayers {
+	var result configLayers
+	for _, layer := range layers {
+		if layer != nil {
+			result = append(result, layer)
+		}
+	}
+	return result
+}
+
+func (layers configLayers) get(key string) (value string, source string, ok bool) {
+	for _, layer := range layers {
+		if value, ok := layer.get(key); ok {
+			return value, layer.name, true
+		}
+	}
+	return "", "", false
+}
+
+// lockedBy returns name of the first layer locking key or empty string
+func (layers configLayers) lockedBy(key string) string {
+	for _, layer := range layers {
+		if layer.locked[strings.ToLower(key)] {
+			return layer.name
+		}
+	}
+	return ""
+}
+
+// systemConfig and userConfig are loaded by platform specific loadConfigLayers,
+// settings of Java deployment files are appended with lower precedence if they are imported
+var systemConfig, userConfig configLayers
+
+// lookupSetting returns value of key and name of the layer it comes from.
+// User settings override system settings unless the key is locked by system settings.
+func lookupSetting(key string) (value string, source string, ok bool) {
+	if !isSettingLocked(key) {
+		if value, source, ok := userConfig.get(key); ok {
+			return value, source, true
+		}
+	}
+	return systemConfig.get(key)
+}
+
+// lookupBoolSetting returns boolean value of key or defaultValue if the key is not set
+func lookupBoolSetting(key string, defaultValue bool) bool {
+	value, _, ok := lookupSetting(key)
+	if !ok {
+		return defaultValue
+	}
+	return parseBool(value)
+}
+
+// isSettingLocked reports whether key is locked by system settings, so it can't be changed by the user
+func isSettingLocked(key string) bool {
+	return systemConfig.lockedBy(key) != ""
+}
+
+// ConfigSources returns names of configuration files or registry keys settings are loaded from, system ones first
+func ConfigSources() []string {
+	var sources []string
+	for _, layer := range append(append(configLayers{}, systemConfig...), userConfig...) {
+		sources = append(sources, layer.name)
+	}
+	return sources
+}
+
+func parseBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// parseConfigFile parses lines like Key=value, lines starting with # or ; are comments
+func parseConfigFile(name string, reader io.Reader) (*configLayer, error) {
+	layer := newConfigLayer(name)
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		index := strings.Index(line, "=")
+		if index <= 0 {
+			log.Printf("warning: %s:%d: expected key=value", name, lineNumber)
+			continue
+		}
+		key := strings.TrimSpace(line[:index])
+		value := strings.TrimSpace(line[index+1:])
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		layer.set(key, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", name)
+	}
+	return layer, nil
+}
@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_lookupSetting(t *testing.T) {
+	system, err := parseConfigFile("system", strings.NewReader(`
+# system defaults
+JavaDir = /opt/java8
+DisableVerification=false
+DisableVerification.locked=true
+Locale="de_DE"
+Locked=AddToControlPanel
+AddToControlPanel=1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := parseConfigFile("user", strings.NewReader(`
+javadir=/home/user/java11
+DisableVerification=true
+AddToControlPanel=0
+invalid line
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(system, user configLayers) { systemConfig, userConfig = system, user }(systemConfig, userConfig)
+	systemConfig, userConfig = newConfigLayers(system), newConfigLayers(user)
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource string
+		wantOK     bool
+	}{
+		{"JavaDir", "/home/user/java11", "user", true},
+		{"DisableVerification", "false", "system", true},
+		{"AddToControlPanel", "1", "system", true},
+		{"Locale", "de_DE", "system", true},
+		{"Java", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			value, source, ok := lookupSetting(tt.key)
+			if value != tt.wantValue || source != tt.wantSource || ok != tt.wantOK {
+				t.Errorf("lookupSetting() = %q, %q, %v, want %q, %q, %v", value, source, ok, tt.wantValue, tt.wantSource, tt.wantOK)
+			}
+		})
+	}
+}
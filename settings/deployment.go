@@ -0,0 +1,330 @@
+package settings
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+)
+
+// importJavaDeploymentSetting enables import of deployment.config, deployment.properties and exception.sites of Java Web Start
+const importJavaDeploymentSetting = "ImportJavaDeploymentSettings"
+
+// exceptionSites are sites from exception.sites files, apps from these sites may run without JAR verification
+// if the user agrees
+var exceptionSites []string
+
+// importJavaDeploymentSettings appends layers converted from system and user deployment.properties
+// and loads exception site lists
+func importJavaDeploymentSettings() {
+	systemConfigFile, userDir := javaDeploymentPaths()
+	var systemProperties, userProperties map[string]string
+	var systemPropertiesFile string
+	if config, err := readProperties(systemConfigFile); err == nil {
+		log.Printf("importing Java deployment settings from %s", systemConfigFile)
+		if location := config["deployment.system.config"]; location != "" {
+			systemPropertiesFile = fileURLToPath(location)
+			if systemProperties, err = readProperties(systemPropertiesFile); err != nil {
+				log.Printf("warning: unable to read system deployment properties: %v", err)
+			}
+		}
+	}
+	userPropertiesFile := filepath.Join(userDir, "deployment.properties")
+	userProperties, err := readProperties(userPropertiesFile)
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		log.Printf("warning: unable to read user deployment properties: %v", err)
+	}
+	if systemProperties != nil {
+		systemConfig = append(systemConfig, convertDeploymentProperties(systemPropertiesFile, systemProperties))
+	}
+	if userProperties != nil {
+		userConfig = append(userConfig, convertDeploymentProperties(userPropertiesFile, userProperties))
+	}
+	exceptionSitesFiles := []string{filepath.Join(userDir, "security", "exception.sites")}
+	if location := userProperties["deployment.user.security.exception.sites"]; location != "" {
+		exceptionSitesFiles[0] = fileURLToPath(location)
+	}
+	if location := systemProperties["deployment.system.security.exception.sites"]; location != "" {
+		exceptionSitesFiles = append(exceptionSitesFiles, fileURLToPath(location))
+	}
+	for _, filename := range exceptionSitesFiles {
+		sites, err := readExceptionSites(filename)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("warning: unable to read exception sites: %v", err)
+			}
+			continue
+		}
+		log.Printf("imported %d exception sites from %s", len(sites), filename)
+		exceptionSites = append(exceptionSites, sites...)
+	}
+}
+
+// convertDeploymentProperties maps Java deployment properties onto settings of Open Web Launch,
+// keys locked with <property>.locked are locked
+func convertDeploymentProperties(name string, properties map[string]string) *configLayer {
+	layer := newConfigLayer(name)
+	set := func(key, value string, propertyNames ...string) {
+		if value == "" {
+			return
+		}
+		layer.set(key, value)
+		for _, propertyName := range propertyNames {
+			if _, ok := properties[propertyName+".locked"]; ok {
+				layer.locked[strings.ToLower(key)] = true
+			}
+		}
+	}
+	switch properties["deployment.proxy.type"] {
+	case "0":
+		set("ProxyMode", "none", "deployment.proxy.type")
+	case "1":
+		httpProxy := proxyAddress(properties["deployment.proxy.http.host"], properties["deployment.proxy.http.port"])
+		httpsProxy := proxyAddress(properties["deployment.proxy.https.host"], properties["deployment.proxy.https.port"])
+		if properties["deployment.proxy.same"] == "true" || httpsProxy == "" {
+			httpsProxy = httpProxy
+		}
+		set("ProxyMode", "manual", "deployment.proxy.type")
+		set("HttpProxy", httpProxy, "deployment.proxy.type", "deployment.proxy.http.host")
+		set("HttpsProxy", httpsProxy, "deployment.proxy.type", "deployment.proxy.https.host")
+		if bypass := properties["deployment.proxy.bypass.list"]; bypass != "" {
+			set("NoProxy", strings.Replace(bypass, ";", ",", -1), "deployment.proxy.bypass.list")
+		}
+	case "2":
+		set("ProxyMode", "pac", "deployment.proxy.type")
+		set("ProxyAutoConfig", properties["deployment.proxy.auto.config.url"], "deployment.proxy.type", "deployment.proxy.auto.config.url")
+	}
+	if cacheDir := properties["deployment.user.cachedir"]; cacheDir != "" {
+		// separate from files of Java Web Start which are not managed by Open Web Launch
+		set("CacheDir", filepath.Join(cacheDir, "openweblaunch"), "deployment.user.cachedir")
+	}
+	switch strings.ToUpper(properties["deployment.security.level"]) {
+	case "HIGH", "VERY_HIGH":
+		set("DisableVerification", "false", "deployment.security.level")
+		set("DisableVerificationSameOrigin", "false", "deployment.security.level")
+	}
+	if java := properties["deployment.javaws.jre.0.path"]; java != "" && properties["deployment.javaws.jre.0.enabled"] != "false" {
+		set("Java", java, "deployment.javaws.jre.0.path")
+	}
+	return layer
+}
+
+func proxyAddress(host, port string) string {
+	if host == "" {
+		return ""
+	}
+	if port == "" {
+		return host
+	}
+	return host + ":" + port
+}
+
+// fileURLToPath converts file URL like file:///C:/Windows/Sun/Java/Deployment/deployment.properties to path
+func fileURLToPath(location string) string {
+	parsedURL, err := url.Parse(location)
+	if err != nil || parsedURL.Scheme != "file" {
+		return location
+	}
+	path := parsedURL.Path
+	if parsedURL.Opaque != "" {
+		path = parsedURL.Opaque
+	}
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // /C:/dir
+	}
+	return filepath.FromSlash(path)
+}
+
+func readProperties(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	properties, err := parseProperties(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %s", filename)
+	}
+	return properties, nil
+}
+
+// parseProperties parses Java properties with comments, line continuations and escape sequences
+func parseProperties(reader io.Reader) (map[string]string, error) {
+	properties := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	var logicalLine string
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logicalLine == "" && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+		if trailingBackslashes(line)%2 == 1 {
+			logicalLine += line[:len(line)-1]
+			continue
+		}
+		logicalLine += line
+		key, value, err := splitProperty(logicalLine)
+		if err != nil {
+			return nil, err
+		}
+		properties[key] = value
+		logicalLine = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if logicalLine != "" {
+		key, value, err := splitProperty(logicalLine)
+		if err != nil {
+			return nil, err
+		}
+		properties[key] = value
+	}
+	return properties, nil
+}
+
+func trailingBackslashes(line string) int {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count
+}
+
+// splitProperty splits line at the first unescaped '=', ':' or whitespace and unescapes key and value
+func splitProperty(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) != -1 {
+			end = i
+			break
+		}
+	}
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	key, err := unescapeProperty(line[:end])
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescapeProperty(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			builder.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", errors.Errorf("invalid escape sequence in %q", s)
+			}
+			code, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", errors.Errorf("invalid escape sequence in %q", s)
+			}
+			builder.WriteRune(rune(code))
+			i += 4
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+	return builder.String(), nil
+}
+
+// readExceptionSites reads exception.sites file with a URL on every line
+func readExceptionSites(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var sites []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sites = append(sites, line)
+	}
+	return sites, scanner.Err()
+}
+
+// IsExceptionSite reports whether rawurl is covered by a site from imported exception.sites files.
+// Exception sites are ignored if DisableVerification is locked, e.g. by deployment.security.level.locked.
+func IsExceptionSite(rawurl string) bool {
+	if lockedBy := systemConfig.lockedBy("DisableVerification"); lockedBy != "" {
+		return false
+	}
+	for _, site := range exceptionSites {
+		if matchExceptionSite(site, rawurl) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchExceptionSite reports whether rawurl has the same scheme, host and port as site
+// and its path is site's path or below it
+func matchExceptionSite(site, rawurl string) bool {
+	siteURL, err := url.Parse(site)
+	if err != nil || siteURL.Host == "" {
+		return false
+	}
+	target, err := url.Parse(rawurl)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(siteURL.Scheme, target.Scheme) || !strings.EqualFold(siteURL.Hostname(), target.Hostname()) {
+		return false
+	}
+	if portOrDefault(siteURL) != portOrDefault(target) {
+		return false
+	}
+	sitePath := strings.TrimSuffix(siteURL.Path, "/")
+	return target.Path == sitePath || strings.HasPrefix(target.Path, sitePath+"/")
+}
+
+func portOrDefault(parsedURL *url.URL) string {
+	if port := parsedURL.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(parsedURL.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
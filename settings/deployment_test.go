@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseProperties(t *testing.T) {
+	input := `# comment
+! another comment
+deployment.proxy.type=1
+deployment.proxy.http.host = proxy.example.com
+deployment.proxy.bypass.list:localhost;*.example.com
+deployment.system.config=file\:///C\:/Windows/Sun/Java/Deployment/deployment.properties
+deployment.security.level.locked
+deployment.user.cachedir=/home/user/java\
+    /cache
+deployment.javaws.jre.0.platform 1.8
+title=\u00c4pfel
+`
+	want := map[string]string{
+		"deployment.proxy.type":            "1",
+		"deployment.proxy.http.host":       "proxy.example.com",
+		"deployment.proxy.bypass.list":     "localhost;*.example.com",
+		"deployment.system.config":         "file:///C:/Windows/Sun/Java/Deployment/deployment.properties",
+		"deployment.security.level.locked": "",
+		"deployment.user.cachedir":         "/home/user/java/cache",
+		"deployment.javaws.jre.0.platform": "1.8",
+		"title":                            "Äpfel",
+	}
+	got, err := parseProperties(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseProperties() = %v, want %v", got, want)
+	}
+}
+
+func Test_matchExceptionSite(t *testing.T) {
+	tests := []struct {
+		site   string
+		rawurl string
+		want   bool
+	}{
+		{"https://intranet.example.com", "https://intranet.example.com/apps/app.jnlp", true},
+		{"https://intranet.example.com/apps/", "https://INTRANET.example.com:443/apps/app.jnlp", true},
+		{"https://intranet.example.com/apps", "https://intranet.example.com/apps2/app.jnlp", false},
+		{"http://intranet.example.com", "https://intranet.example.com/app.jnlp", false},
+		{"https://intranet.example.com:8443", "https://intranet.example.com/app.jnlp", false},
+		{"https://intranet.example.com", "https://evil.com/intranet.example.com/app.jnlp", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.site+" "+tt.rawurl, func(t *testing.T) {
+			if got := matchExceptionSite(tt.site, tt.rawurl); got != tt.want {
+				t.Errorf("matchExceptionSite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_IsExceptionSite(t *testing.T) {
+	defer func(sites []string, system configLayers) { exceptionSites, systemConfig = sites, system }(exceptionSites, systemConfig)
+	exceptionSites = []string{"https://intranet.example.com"}
+	systemConfig = nil
+	if !IsExceptionSite("https://intranet.example.com/app.jnlp") {
+		t.Error("IsExceptionSite() = false, want true")
+	}
+	system := convertDeploymentProperties("deployment.properties", map[string]string{
+		"deployment.security.level":        "HIGH",
+		"deployment.security.level.locked": "",
+	})
+	systemConfig = newConfigLayers(system)
+	if IsExceptionSite("https://intranet.example.com/app.jnlp") {
+		t.Error("IsExceptionSite() with locked DisableVerification = true, want false")
+	}
+}
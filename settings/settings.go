@@ -25,6 +25,18 @@ var (
 	addAppToControlPanel            bool
 	currentJavaVersion              *JavaVersion
 	useHttpProxyEnvironmentVariable bool
+	configDir                       string
+	locale                          string
+	cacheDir                        string
+	httpProxy                       string
+	httpsProxy                      string
+	noProxy                         string
+	proxyMode                       string
+	proxyAutoConfig                 string
+	credentialsFile                 string
+	allowInsecureAuthentication     bool
+	clientCertificatesFile          string
+	policyFile                      string
 )
 
 func EnsureJavaExecutableAvailability() error {
@@ -92,7 +104,11 @@ func AddAppToControlPanel() bool {
 // GetJavaVersionString returns detailed Java version information, e.g.
 // java version "1.8.0_171" Java(TM) SE Runtime Environment (build 1.8.0_171-b11) Java HotSpot(TM) 64-Bit Server VM (build 25.171-b11, mixed mode)
 func GetJavaVersionString() (string, error) {
-	cmd := exec.Command(javaExecutable, "-version")
+	return getJavaVersionString(javaExecutable)
+}
+
+func getJavaVersionString(java string) (string, error) {
+	cmd := exec.Command(java, "-version")
 	utils.HideWindow(cmd)
 	outputBytes, err := cmd.CombinedOutput()
 	if err != nil {
@@ -110,17 +126,36 @@ type JavaVersion struct {
 	String      string
 }
 
+// FeatureVersion returns Java feature release number,
+// e.g. 8 for "1.8.0_171" and 11 for "11.0.2"
+func (version *JavaVersion) FeatureVersion() int {
+	if version.Major == 1 {
+		return version.Minor
+	}
+	return version.Major
+}
+
 // GetJavaVersion returns major and minor Java version
-func GetJavaVersion() (javaVersion *JavaVersion, err error) {
+func GetJavaVersion() (*JavaVersion, error) {
+	if currentJavaVersion != nil {
+		return currentJavaVersion, nil
+	}
+	javaVersion, err := GetJavaVersionOf(javaExecutable)
+	if err != nil {
+		return nil, err
+	}
+	currentJavaVersion = javaVersion
+	return javaVersion, nil
+}
+
+// GetJavaVersionOf returns major and minor version of Java executable java
+func GetJavaVersionOf(java string) (javaVersion *JavaVersion, err error) {
 	defer func() {
 		if err != nil {
 			err = errors.Wrap(err, "unable to detect Java version")
 		}
 	}()
-	if currentJavaVersion != nil {
-		return currentJavaVersion, nil
-	}
-	versionOutput, err := GetJavaVersionString()
+	versionOutput, err := getJavaVersionString(java)
 	if err != nil {
 		return
 	}
@@ -136,10 +171,6 @@ func GetJavaVersion() (javaVersion *JavaVersion, err error) {
 	}
 	version := versionOutput[firstQuoteIndex+1 : secondQuoteIndex+firstQuoteIndex+1]
 	javaVersion, err = ParseJavaVersion(version)
-	if err != nil {
-		return
-	}
-	currentJavaVersion = javaVersion
 	return
 }
 
@@ -171,17 +202,22 @@ func CurrentJavaVersionMatches(version *JavaVersion) bool {
 	if currentJavaVersion == nil {
 		return false
 	}
-	if currentJavaVersion.Major < version.Major {
+	return currentJavaVersion.Matches(version)
+}
+
+// Matches reports whether Java of this version satisfies required version
+func (current *JavaVersion) Matches(version *JavaVersion) bool {
+	if current.Major < version.Major {
 		return false
 	}
-	if currentJavaVersion.Major > version.Major && version.AllowHigher {
+	if current.Major > version.Major && version.AllowHigher {
 		return true
 	}
-	if currentJavaVersion.Major == version.Major {
-		if currentJavaVersion.Minor < version.Minor {
+	if current.Major == version.Major {
+		if current.Minor < version.Minor {
 			return false
 		}
-		if currentJavaVersion.Minor == version.Minor || (currentJavaVersion.Minor > version.Minor && version.AllowHigher) {
+		if current.Minor == version.Minor || (current.Minor > version.Minor && version.AllowHigher) {
 			return true
 		}
 	}
@@ -208,7 +244,41 @@ func getJavaExecutableUsingJavaHome(showConsole bool) (string, error) {
 // UseJavaDir forces to use Java installation from directory dir.
 // Returns absolute path to the specified directory.
 func UseJavaDir(dir string) (string, error) {
-	javaSource = `-javadir '` + dir + `' command line argument`
+	if lockedBy := systemConfig.lockedBy("JavaDir"); lockedBy != "" {
+		return "", errors.Errorf(`javadir can't be changed because JavaDir is locked in %s`, lockedBy)
+	}
+	return useJavaDir(dir, `-javadir '`+dir+`' command line argument`)
+}
+
+// PolicyJava returns Java executable of the installation in directory dir selected by a policy rule.
+// Unlike UseJavaDir it doesn't change settings, so the Java is only used for applications matching the rule,
+// and it is allowed when JavaDir is locked because policies are set by administrators.
+func PolicyJava(dir string) (string, error) {
+	absPath, err := checkJavaDir(dir)
+	if err != nil {
+		return "", err
+	}
+	java := getJavaExecutableUsingJavaDir(absPath)
+	if _, err := os.Stat(java); err != nil {
+		return "", errors.Errorf("Java executable %s is missing", java)
+	}
+	return java, nil
+}
+
+func useJavaDir(dir string, source string) (string, error) {
+	absPath, err := checkJavaDir(dir)
+	if err != nil {
+		return "", err
+	}
+	javaSource = source
+	javaExecutable = getJavaExecutableUsingJavaDir(absPath)
+	jarSignerExecutable = getJARSignerExecutableUsingJavaDir(absPath)
+	currentJavaVersion = nil
+	return absPath, nil
+}
+
+// checkJavaDir returns absolute path of directory dir if it exists
+func checkJavaDir(dir string) (string, error) {
 	absPath, err := filepath.Abs(dir)
 	if err != nil {
 		return "", errors.Wrapf(err, `invalid javadir '%s'`, dir)
@@ -217,11 +287,12 @@ func UseJavaDir(dir string) (string, error) {
 	if os.IsNotExist(err) {
 		return "", errors.Errorf(`javadir '%s' doesn't exist`, dir)
 	}
+	if err != nil {
+		return "", errors.Wrapf(err, `invalid javadir '%s'`, dir)
+	}
 	if !fileInfo.IsDir() {
 		return "", errors.Errorf(`javadir '%s' is not a directory`, dir)
 	}
-	javaExecutable = getJavaExecutableUsingJavaDir(absPath)
-	jarSignerExecutable = getJARSignerExecutableUsingJavaDir(absPath)
 	return absPath, nil
 }
 
@@ -233,10 +304,18 @@ func ShowConsole() {
 }
 
 func DisableVerification() {
+	if lockedBy := systemConfig.lockedBy("DisableVerification"); lockedBy != "" {
+		log.Printf("warning: verification can't be disabled because DisableVerification is locked in %s", lockedBy)
+		return
+	}
 	disableVerification = true
 }
 
 func DisableVerificationSameOrigin() {
+	if lockedBy := systemConfig.lockedBy("DisableVerificationSameOrigin"); lockedBy != "" {
+		log.Printf("warning: same origin verification can't be disabled because DisableVerificationSameOrigin is locked in %s", lockedBy)
+		return
+	}
 	disableVerificationSameOrigin = true
 }
 
@@ -244,11 +323,128 @@ func UseHttpProxyEnvironmentVariable() bool {
 	return useHttpProxyEnvironmentVariable
 }
 
+// CacheDir returns the directory for cached applications configured in settings or empty string
+func CacheDir() string {
+	return cacheDir
+}
+
+// Proxies returns HTTP and HTTPS proxies and hosts bypassing them configured in settings,
+// values are empty if they are not configured
+func Proxies() (string, string, string) {
+	return httpProxy, httpsProxy, noProxy
+}
+
+// ProxyMode returns how proxies are chosen: env, none, manual or pac.
+// If ProxyMode is not set, it is pac if ProxyAutoConfig is set, manual if HttpProxy or HttpsProxy is set,
+// none if UseHttpProxyEnvironmentVariable is false and env otherwise.
+func ProxyMode() string {
+	return proxyMode
+}
+
+// ProxyAutoConfig returns URL or path of the proxy auto-config file configured in settings or empty string
+func ProxyAutoConfig() string {
+	return proxyAutoConfig
+}
+
+func getProxyModeSetting() string {
+	if mode, _, ok := lookupSetting("ProxyMode"); ok && mode != "" {
+		return strings.ToLower(mode)
+	}
+	switch {
+	case proxyAutoConfig != "":
+		return "pac"
+	case httpProxy != "" || httpsProxy != "":
+		return "manual"
+	case !useHttpProxyEnvironmentVariable:
+		return "none"
+	}
+	return "env"
+}
+
+// CredentialsFile returns path of the file in netrc format with credentials for servers configured in settings or empty string
+func CredentialsFile() string {
+	return credentialsFile
+}
+
+// AllowInsecureAuthentication reports whether basic and bearer credentials are sent over HTTP without asking the user
+func AllowInsecureAuthentication() bool {
+	return allowInsecureAuthentication
+}
+
+// ClientCertificatesFile returns path of the JSON file with client certificates configured in settings or empty string
+func ClientCertificatesFile() string {
+	return clientCertificatesFile
+}
+
+// PolicyFile returns path of the policy file configured in system settings or empty string
+func PolicyFile() string {
+	return policyFile
+}
+
+// Locale returns user's locale in the form used by JNLP files, e.g. en_US
+func Locale() string {
+	return locale
+}
+
+// normalizeLocale converts locale like en_US.UTF-8, en-US or de_DE@euro to en_US or de_DE
+func normalizeLocale(value string) string {
+	if index := strings.IndexAny(value, ".@"); index != -1 {
+		value = value[:index]
+	}
+	value = strings.Replace(value, "-", "_", -1)
+	if value == "C" || value == "POSIX" {
+		return ""
+	}
+	return value
+}
+
+// getLocaleFromEnvironment returns locale from LC_ALL, LC_MESSAGES or LANG environment variables
+func getLocaleFromEnvironment() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := normalizeLocale(os.Getenv(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// SetConfigDir sets the directory where per-user configuration files are stored
+func SetConfigDir(dir string) {
+	configDir = dir
+}
+
+// ConfigDir returns the directory where per-user configuration files are stored
+func ConfigDir() string {
+	return configDir
+}
+
+// CompatibilityProfilesFile returns path to the file with Java compatibility profiles
+func CompatibilityProfilesFile() string {
+	return filepath.Join(configDir, "compatibility.json")
+}
+
 func init() {
+	system, user := loadConfigLayers()
+	systemConfig, userConfig = newConfigLayers(system), newConfigLayers(user)
+	if lookupBoolSetting(importJavaDeploymentSetting, false) {
+		importJavaDeploymentSettings()
+	}
 	javaExecutable = getJavaExecutable()
 	jarSignerExecutable = getJARSignerExecutable()
 	disableVerification = getDisableVerificationSetting()
 	disableVerificationSameOrigin = getDisableVerificationSameOriginSetting()
 	addAppToControlPanel = getAddAppToControlPanelSetting()
 	useHttpProxyEnvironmentVariable = getUseHttpProxyEnvironmentVariableSetting()
+	locale = normalizeLocale(getLocaleSetting())
+	cacheDir, _, _ = lookupSetting("CacheDir")
+	httpProxy, _, _ = lookupSetting("HttpProxy")
+	httpsProxy, _, _ = lookupSetting("HttpsProxy")
+	noProxy, _, _ = lookupSetting("NoProxy")
+	proxyAutoConfig, _, _ = lookupSetting("ProxyAutoConfig")
+	proxyMode = getProxyModeSetting()
+	credentialsFile, _, _ = lookupSetting("CredentialsFile")
+	allowInsecureAuthentication = lookupBoolSetting("AllowInsecureAuthentication", false)
+	clientCertificatesFile, _, _ = lookupSetting("ClientCertificatesFile")
+	// only administrators can configure policies
+	policyFile, _, _ = systemConfig.get("PolicyFile")
 }
@@ -2,8 +2,12 @@ package settings
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"howett.net/plist"
@@ -11,12 +15,48 @@ import (
 
 const (
 	systemSettingsFile = "/Library/Preferences/com.rs.openweblaunch.plist"
+	userSettingsFile   = "Library/Preferences/com.rs.openweblaunch.plist" // relative to home directory
 )
 
-type Settings struct {
-	DisableVerification           bool   `plist:"DisableVerification"`
-	DisableVerificationSameOrigin bool   `plist:"DisableVerificationSameOrigin"`
-	JavaDir                       string `plist:"JavaDir"`
+func loadConfigLayers() (system *configLayer, user *configLayer) {
+	system, _ = decodeSettings(systemSettingsFile)
+	if home, err := os.UserHomeDir(); err == nil {
+		user, _ = decodeSettings(filepath.Join(home, userSettingsFile))
+	}
+	return system, user
+}
+
+// decodeSettings reads a plist dictionary, Locked array lists keys users can't override
+func decodeSettings(filename string) (*configLayer, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := plist.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, errors.Wrapf(err, "unable to decode %s", filename)
+	}
+	layer := newConfigLayer(filename)
+	for key, value := range values {
+		switch value := value.(type) {
+		case []interface{}:
+			var items []string
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			layer.set(key, strings.Join(items, ","))
+		default:
+			layer.set(key, fmt.Sprint(value))
+		}
+	}
+	return layer, nil
+}
+
+// javaDeploymentPaths returns deployment.config of Java Web Start and the user's deployment directory
+func javaDeploymentPaths() (string, string) {
+	home, _ := os.UserHomeDir()
+	return "/Library/Application Support/Oracle/Java/Deployment/deployment.config",
+		filepath.Join(home, "Library", "Application Support", "Oracle", "Java", "Deployment")
 }
 
 func getJavaExecutable() string {
@@ -31,16 +71,12 @@ func getJavaExecutable() string {
 }
 
 func getJavaExecutableUsingSettings() (string, error) {
-	settings, err := decodeSettings()
-	if err != nil {
-		return "", err
-	}
-	javaDir := settings.JavaDir
-	if javaDir != "" {
-		javaSource = systemSettingsFile
+	javaDir, source, ok := lookupSetting("JavaDir")
+	if ok && javaDir != "" {
+		javaSource = source
 		return getJavaExecutableUsingJavaDir(javaDir), nil
 	}
-	return "", errors.Errorf("JavaDir not found in %s", systemSettingsFile)
+	return "", errors.New("JavaDir not found in settings")
 }
 
 func getJavaExecutableUsingJavaDir(dir string) string {
@@ -56,39 +92,31 @@ func getJARSignerExecutableUsingJavaDir(dir string) string {
 }
 
 func getDisableVerificationSetting() bool {
-	settings, err := decodeSettings()
-	if err != nil {
-		return false
-	}
-	return settings.DisableVerification
+	return lookupBoolSetting("DisableVerification", false)
 }
 
 func getDisableVerificationSameOriginSetting() bool {
-	settings, err := decodeSettings()
-	if err != nil {
-		return false
-	}
-	return settings.DisableVerificationSameOrigin
+	return lookupBoolSetting("DisableVerificationSameOrigin", false)
 }
 
 func getAddAppToControlPanelSetting() bool {
 	return false
 }
 
-func decodeSettings() (*Settings, error) {
-	var settings Settings
-	data, err := ioutil.ReadFile(systemSettingsFile)
-	if err != nil {
-		return nil, err
+func getUseHttpProxyEnvironmentVariableSetting() bool {
+	return lookupBoolSetting("UseHttpProxyEnvironmentVariable", true)
+}
+
+func getLocaleSetting() string {
+	if locale, _, ok := lookupSetting("Locale"); ok && locale != "" {
+		return locale
+	}
+	if locale := getLocaleFromEnvironment(); locale != "" {
+		return locale
 	}
-	decoder := plist.NewDecoder(bytes.NewReader(data))
-	err = decoder.Decode(&settings)
+	output, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output()
 	if err != nil {
-		return nil, err
+		return ""
 	}
-	return &settings, nil
-}
-
-func getUseHttpProxyEnvironmentVariableSetting() bool {
-	return true
+	return strings.TrimSpace(string(output))
 }
@@ -1,8 +1,63 @@
 package settings
 
-import "path/filepath"
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+)
+
+// systemConfigFile contains system defaults, keys locked there can't be overridden by users
+const systemConfigFile = "/etc/openweblaunch/settings.conf"
+
+// userConfigFile returns path of the configuration file in $XDG_CONFIG_HOME, ~/.config by default
+func userConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "openweblaunch", "settings.conf")
+}
+
+func loadConfigLayers() (system *configLayer, user *configLayer) {
+	return loadConfigFile(systemConfigFile), loadConfigFile(userConfigFile())
+}
+
+func loadConfigFile(filename string) *configLayer {
+	if filename == "" {
+		return nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("warning: unable to read settings: %v", err)
+		}
+		return nil
+	}
+	defer file.Close()
+	layer, err := parseConfigFile(filename, file)
+	if err != nil {
+		log.Printf("warning: %v", err)
+		return nil
+	}
+	return layer
+}
+
+// javaDeploymentPaths returns deployment.config of Java Web Start and the user's deployment directory
+func javaDeploymentPaths() (string, string) {
+	home, _ := os.UserHomeDir()
+	return "/etc/.java/deployment/deployment.config", filepath.Join(home, ".java", "deployment")
+}
 
 func getJavaExecutable() string {
+	if javaDir, source, ok := lookupSetting("JavaDir"); ok && javaDir != "" {
+		javaSource = source + " - JavaDir"
+		return getJavaExecutableUsingJavaDir(javaDir)
+	}
+	if java, source, ok := lookupSetting("Java"); ok && java != "" {
+		javaSource = source + " - Java"
+		return java
+	}
 	if java, err := getJavaExecutableUsingJavaHome(true); err == nil {
 		return java
 	}
@@ -15,6 +70,9 @@ func getJavaExecutableUsingJavaDir(dir string) string {
 }
 
 func getJARSignerExecutable() string {
+	if javaDir, _, ok := lookupSetting("JavaDir"); ok && javaDir != "" {
+		return getJARSignerExecutableUsingJavaDir(javaDir)
+	}
 	return "jarsigner"
 }
 
@@ -23,17 +81,24 @@ func getJARSignerExecutableUsingJavaDir(dir string) string {
 }
 
 func getDisableVerificationSetting() bool {
-	return false
+	return lookupBoolSetting("DisableVerification", false)
 }
 
 func getDisableVerificationSameOriginSetting() bool {
-	return false
+	return lookupBoolSetting("DisableVerificationSameOrigin", false)
 }
 
 func getAddAppToControlPanelSetting() bool {
-	return false
+	return lookupBoolSetting("AddToControlPanel", true)
 }
 
 func getUseHttpProxyEnvironmentVariableSetting() bool {
-	return true
+	return lookupBoolSetting("UseHttpProxyEnvironmentVariable", true)
+}
+
+func getLocaleSetting() string {
+	if locale, _, ok := lookupSetting("Locale"); ok && locale != "" {
+		return locale
+	}
+	return getLocaleFromEnvironment()
 }
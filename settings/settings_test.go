@@ -0,0 +1,38 @@
+package settings
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_PolicyJava(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Java executable depends on ShowConsole on Windows")
+	}
+	dir, err := ioutil.TempDir("", "java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := PolicyJava(dir); err == nil {
+		t.Error("PolicyJava() without Java executable succeeded")
+	}
+	java := filepath.Join(dir, "bin", "java")
+	if err := os.MkdirAll(filepath.Dir(java), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(java, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous := Java()
+	got, err := PolicyJava(dir)
+	if err != nil || got != java {
+		t.Errorf("PolicyJava() = %v, %v, want %v", got, err, java)
+	}
+	if Java() != previous {
+		t.Errorf("PolicyJava() changed Java() to %s", Java())
+	}
+}
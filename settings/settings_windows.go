@@ -1,7 +1,12 @@
 package settings
 
 import (
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sys/windows/registry"
@@ -9,126 +14,74 @@ import (
 
 var registryKey = `Software\Rocket Software\Open Web Launch`
 
-func getStringValueFromRootKey(rootKey registry.Key, key string) (string, error) {
-	registryKey, err := registry.OpenKey(rootKey, registryKey, registry.QUERY_VALUE)
-	defer registryKey.Close()
+// loadRegistryLayer reads values of the settings key under rootKey, Locked value can be
+// a multi-string or a comma separated list of values users can't override
+func loadRegistryLayer(rootKey registry.Key, rootName string) *configLayer {
+	key, err := registry.OpenKey(rootKey, registryKey, registry.QUERY_VALUE)
 	if err != nil {
-		return "", err
-	}
-	value, _, err := registryKey.GetStringValue(key)
-	if err != nil {
-		return "", err
+		return nil
 	}
-	return value, nil
-}
-
-func getUInt64ValueFromRootKey(rootKey registry.Key, key string) (uint64, error) {
-	registryKey, err := registry.OpenKey(rootKey, registryKey, registry.QUERY_VALUE)
-	defer registryKey.Close()
-	if err != nil {
-		return 0, err
-	}
-	value, _, err := registryKey.GetIntegerValue(key)
-	if err != nil {
-		return 0, err
+	defer key.Close()
+	names, err := key.ReadValueNames(0)
+	if err != nil {
+		return nil
+	}
+	layer := newConfigLayer(`Windows Registry - ` + rootName + `\` + registryKey)
+	for _, name := range names {
+		if value, _, err := key.GetStringValue(name); err == nil {
+			layer.set(name, value)
+		} else if value, _, err := key.GetIntegerValue(name); err == nil {
+			layer.set(name, strconv.FormatUint(value, 10))
+		} else if values, _, err := key.GetStringsValue(name); err == nil {
+			layer.set(name, strings.Join(values, ","))
+		}
 	}
-	return value, nil
-}
-
-func getJavaExecutableFromRootKey(rootKey registry.Key) (string, error) {
-	return getStringValueFromRootKey(rootKey, "Java")
+	return layer
 }
 
-func getJavaDirFromRootKey(rootKey registry.Key) (string, error) {
-	return getStringValueFromRootKey(rootKey, "JavaDir")
+func loadConfigLayers() (system *configLayer, user *configLayer) {
+	return loadRegistryLayer(registry.LOCAL_MACHINE, "LOCAL_MACHINE"), loadRegistryLayer(registry.CURRENT_USER, "CURRENT_USER")
 }
 
-func getJavaDetectionStrategyFromRootKey(rootKey registry.Key) (string, error) {
-	return getStringValueFromRootKey(rootKey, "JavaDetection")
-}
-
-func getShowConsoleSettingFromRootKey(rootKey registry.Key) (uint64, error) {
-	return getUInt64ValueFromRootKey(rootKey, "ShowConsole")
-}
-
-func getAddAppToControlPanelSettingFromRootKey(rootKey registry.Key) (uint64, error) {
-	return getUInt64ValueFromRootKey(rootKey, "AddToControlPanel")
-}
-
-func getUseHttpProxyEnvironmentVariableSettingFromRootKey(rootKey registry.Key) (uint64, error) {
-	return getUInt64ValueFromRootKey(rootKey, "UseHttpProxyEnvironmentVariable")
-}
-
-func getDisableVerificationSettingFromRootKey(rootKey registry.Key) (uint64, error) {
-	return getUInt64ValueFromRootKey(rootKey, "DisableVerification")
-}
-
-func getDisableVerificationSameOriginSettingFromRootKey(rootKey registry.Key) (uint64, error) {
-	return getUInt64ValueFromRootKey(rootKey, "DisableVerificationSameOrigin")
+// javaDeploymentPaths returns deployment.config of Java Web Start and the user's deployment directory
+func javaDeploymentPaths() (string, string) {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(os.Getenv("WINDIR"), "Sun", "Java", "Deployment", "deployment.config"),
+		filepath.Join(home, "AppData", "LocalLow", "Sun", "Java", "Deployment")
 }
 
 func getJavaDetectionStrategy() string {
-	strategy, err := getJavaDetectionStrategyFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		strategy, err = getJavaDetectionStrategyFromRootKey(registry.LOCAL_MACHINE)
-	}
-	if err != nil {
-		strategy = ""
-	}
+	strategy, _, _ := lookupSetting("JavaDetection")
 	return strategy
 }
 
 func getDisableVerificationSetting() bool {
-	disableVerification, err := getDisableVerificationSettingFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		disableVerification, err = getDisableVerificationSettingFromRootKey(registry.LOCAL_MACHINE)
-	}
-	if err != nil {
-		return false
-	}
-	return disableVerification == 1
+	return lookupBoolSetting("DisableVerification", false)
 }
 
 func getDisableVerificationSameOriginSetting() bool {
-	disableVerification, err := getDisableVerificationSameOriginSettingFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		disableVerification, err = getDisableVerificationSameOriginSettingFromRootKey(registry.LOCAL_MACHINE)
-	}
-	if err != nil {
-		return false
-	}
-	return disableVerification == 1
+	return lookupBoolSetting("DisableVerificationSameOrigin", false)
 }
 
 func getShowConsoleSetting() bool {
-	showConsole, err := getShowConsoleSettingFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		showConsole, err = getShowConsoleSettingFromRootKey(registry.LOCAL_MACHINE)
-	}
-	if err != nil {
-		return false
-	}
-	return showConsole == 1
+	return lookupBoolSetting("ShowConsole", false)
 }
 
 func getJavaDirFromRegistry() (string, error) {
-	javaSource = `Windows Registry - CURRENT_USER\` + registryKey + `\JavaDir`
-	javadir, err := getJavaDirFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		javaSource = `Windows Registry - LOCAL_MACHINE\` + registryKey + `\JavaDir`
-		return getJavaDirFromRootKey(registry.LOCAL_MACHINE)
-	}
-	return javadir, nil
+	return getJavaSettingFromRegistry("JavaDir")
 }
 
 func getJavaExecutableFromRegistry() (string, error) {
-	javaSource = `Windows Registry - CURRENT_USER\` + registryKey + `\Java`
-	java, err := getJavaExecutableFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		javaSource = `Windows Registry - LOCAL_MACHINE\` + registryKey + `\Java`
-		return getJavaExecutableFromRootKey(registry.LOCAL_MACHINE)
+	return getJavaSettingFromRegistry("Java")
+}
+
+func getJavaSettingFromRegistry(key string) (string, error) {
+	value, source, ok := lookupSetting(key)
+	if !ok {
+		return "", errors.Errorf("%s is not set in Windows Registry", key)
 	}
-	return java, nil
+	javaSource = source + `\` + key
+	return value, nil
 }
 
 func getDefaultJava(showConsole bool) string {
@@ -234,23 +187,28 @@ func getJARSignerExecutableUsingJavaDir(dir string) string {
 }
 
 func getAddAppToControlPanelSetting() bool {
-	addAppToControlPanel, err := getAddAppToControlPanelSettingFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		addAppToControlPanel, err = getAddAppToControlPanelSettingFromRootKey(registry.LOCAL_MACHINE)
-	}
-	if err != nil {
-		return false
-	}
-	return addAppToControlPanel == 1
+	return lookupBoolSetting("AddToControlPanel", false)
 }
 
 func getUseHttpProxyEnvironmentVariableSetting() bool {
-	useHttpProxyEnvVar, err := getUseHttpProxyEnvironmentVariableSettingFromRootKey(registry.CURRENT_USER)
-	if err != nil {
-		useHttpProxyEnvVar, err = getUseHttpProxyEnvironmentVariableSettingFromRootKey(registry.LOCAL_MACHINE)
+	return lookupBoolSetting("UseHttpProxyEnvironmentVariable", true)
+}
+
+var (
+	kernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	pGetUserDefaultLocaleName = kernel32.NewProc("GetUserDefaultLocaleName")
+)
+
+const cLOCALE_NAME_MAX_LENGTH = 85
+
+func getLocaleSetting() string {
+	if locale, _, ok := lookupSetting("Locale"); ok && locale != "" {
+		return locale
 	}
-	if err != nil {
-		return true
+	buffer := make([]uint16, cLOCALE_NAME_MAX_LENGTH)
+	ret, _, _ := pGetUserDefaultLocaleName.Call(uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)))
+	if ret == 0 {
+		return getLocaleFromEnvironment()
 	}
-	return useHttpProxyEnvVar == 1
+	return syscall.UTF16ToString(buffer)
 }
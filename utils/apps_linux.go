@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// appsManagerSubmenu is a submenu of the applications menu with entries for uninstalling apps,
+// it is the Linux counterpart of Add/Remove Programs in Windows Control Panel
+const appsManagerSubmenu = "Open Web Launch Applications"
+
+// InstallApp records app in the manifest of installed apps and adds its uninstall entry into appsManagerSubmenu.
+// Apps are identified by their original JNLP files, so different apps can have the same title.
+func InstallApp(app *AppInfo) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to register app %s", app.Title)
+		}
+	}()
+	apps, err := LoadInstalledApps()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i, installedApp := range apps {
+		if installedApp.JNLPFile() == app.JNLPFile() {
+			apps[i] = app
+			replaced = true
+		}
+	}
+	if !replaced {
+		apps = append(apps, app)
+	}
+	if err = saveInstalledApps(apps); err != nil {
+		return err
+	}
+	err = writeAppsManagerEntry(app)
+	return err
+}
+
+// UninstallApp removes app with the original JNLP file jnlpFile from the manifest of installed apps
+// and from appsManagerSubmenu, title is used in error messages only
+func UninstallApp(title, jnlpFile string) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to unregister app %s", title)
+		}
+	}()
+	apps, err := LoadInstalledApps()
+	if err != nil {
+		return err
+	}
+	var remainingApps []*AppInfo
+	for _, app := range apps {
+		if app.JNLPFile() != jnlpFile {
+			remainingApps = append(remainingApps, app)
+		}
+	}
+	if err = saveInstalledApps(remainingApps); err != nil {
+		return err
+	}
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return err
+	}
+	if err = removeIfExists(filepath.Join(applicationsDir, getAppsManagerEntryID(jnlpFile))); err != nil {
+		return err
+	}
+	if len(remainingApps) == 0 {
+		err = removeSubmenu(appsManagerSubmenu)
+	}
+	return err
+}
+
+// LoadInstalledApps returns apps recorded by InstallApp
+func LoadInstalledApps() ([]*AppInfo, error) {
+	manifest, err := getInstalledAppsManifest()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(manifest)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var apps []*AppInfo
+	if err := json.Unmarshal(data, &apps); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %s", manifest)
+	}
+	return apps, nil
+}
+
+func saveInstalledApps(apps []*AppInfo) error {
+	manifest, err := getInstalledAppsManifest()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(manifest), 0755); err != nil {
+		return err
+	}
+	if apps == nil {
+		apps = []*AppInfo{}
+	}
+	data, err := json.MarshalIndent(apps, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifest, data, 0644)
+}
+
+// getInstalledAppsManifest returns path of the manifest with installed apps
+func getInstalledAppsManifest() (string, error) {
+	dataHome, err := getDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, desktopEntryPrefix, "apps.json"), nil
+}
+
+// getAppsManagerEntryID returns ID of the uninstall entry of the app with the original JNLP file jnlpFile
+func getAppsManagerEntryID(jnlpFile string) string {
+	hash := sha256.Sum256([]byte(jnlpFile))
+	return desktopEntryID(appsManagerSubmenu, "uninstall", fmt.Sprintf("%x", hash[:8])) + ".desktop"
+}
+
+func writeAppsManagerEntry(app *AppInfo) error {
+	if len(app.UninstallCommand) == 0 {
+		return errors.New("uninstall command is not specified")
+	}
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return err
+	}
+	if err := writeSubmenu(appsManagerSubmenu); err != nil {
+		return err
+	}
+	comment := strings.TrimSpace(app.Publisher + " " + app.Version)
+	entry := &DesktopEntry{
+		Name:       "Uninstall " + app.Title,
+		Comment:    comment,
+		Exec:       app.UninstallCommand,
+		Icon:       app.Icon,
+		Categories: []string{submenuCategory(appsManagerSubmenu)},
+	}
+	return entry.Write(filepath.Join(applicationsDir, getAppsManagerEntryID(app.JNLPFile())))
+}
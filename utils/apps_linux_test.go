@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_InstallApp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	defer os.Unsetenv("XDG_DATA_HOME")
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	installed := []struct {
+		title    string
+		jnlpFile string
+	}{
+		{"First App", "/cache/first/original.jnlp"},
+		{"Second App", "/cache/second/original.jnlp"},
+		{"First App", "/cache/first/original.jnlp"},
+		{"First App", "/cache/other/original.jnlp"},
+	}
+	for _, tt := range installed {
+		app := &AppInfo{Title: tt.title, UninstallCommand: []string{"/opt/openweblaunch", "-uninstall", tt.jnlpFile}}
+		if err := InstallApp(app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apps, err := LoadInstalledApps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 3 {
+		t.Fatalf("LoadInstalledApps() returned %d apps, want 3", len(apps))
+	}
+	entry := filepath.Join(dir, "data", "applications", getAppsManagerEntryID("/cache/first/original.jnlp"))
+	if _, err := os.Stat(entry); err != nil {
+		t.Errorf("uninstall entry is not created: %v", err)
+	}
+
+	if err := UninstallApp("First App", "/cache/first/original.jnlp"); err != nil {
+		t.Fatal(err)
+	}
+	if apps, _ := LoadInstalledApps(); len(apps) != 2 || apps[1].JNLPFile() != "/cache/other/original.jnlp" {
+		t.Errorf("LoadInstalledApps() returned %d apps after uninstalling the first app, want the second app and the other app", len(apps))
+	}
+	if _, err := os.Stat(entry); !os.IsNotExist(err) {
+		t.Errorf("uninstall entry is not removed")
+	}
+	for _, tt := range installed[1:] {
+		if err := UninstallApp(tt.title, tt.jnlpFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if apps, _ := LoadInstalledApps(); len(apps) != 0 {
+		t.Errorf("LoadInstalledApps() returned %d apps after uninstall, want 0", len(apps))
+	}
+	menu := filepath.Join(dir, "config", "menus", "applications-merged", desktopEntryID(appsManagerSubmenu)+".menu")
+	if _, err := os.Stat(menu); !os.IsNotExist(err) {
+		t.Errorf("submenu is not removed")
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// mimeTypePattern is type/subtype with characters allowed by RFC 6838
+var mimeTypePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]{0,126}/[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]{0,126}$`)
+
+var extensionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_+-]{0,31}$`)
+
+// blockedExtensions are executables, scripts, shortcuts and web documents which apps may not handle
+var blockedExtensions = map[string]bool{
+	"ade": true, "adp": true, "app": true, "appimage": true, "application": true, "appref-ms": true,
+	"bash": true, "bat": true, "bin": true, "chm": true, "cmd": true, "com": true, "cpl": true,
+	"csh": true, "desktop": true, "dll": true, "exe": true, "gadget": true, "hta": true, "htm": true,
+	"html": true, "inf": true, "ins": true, "jar": true, "jnlp": true, "js": true, "jse": true,
+	"ksh": true, "library-ms": true, "lnk": true, "mht": true, "mhtml": true, "msc": true,
+	"msi": true, "msp": true, "mst": true, "pif": true, "pl": true, "ps1": true, "psm1": true,
+	"py": true, "rb": true, "reg": true, "run": true, "scf": true, "scr": true, "sct": true,
+	"settingcontent-ms": true, "sh": true, "shtml": true, "sys": true, "url": true, "vb": true,
+	"vbe": true, "vbs": true, "ws": true, "wsc": true, "wsf": true, "wsh": true, "xhtml": true,
+	"zsh": true,
+}
+
+// blockedMimeTypes are types of executables, scripts and web documents which apps may not handle
+var blockedMimeTypes = map[string]bool{
+	"application/java-archive":        true,
+	"application/javascript":          true,
+	"application/x-desktop":           true,
+	"application/x-executable":        true,
+	"application/x-java-jnlp-file":    true,
+	"application/x-ms-dos-executable": true,
+	"application/x-msdownload":        true,
+	"application/x-msi":               true,
+	"application/x-sharedlib":         true,
+	"application/x-shellscript":       true,
+	"application/xhtml+xml":           true,
+	"text/html":                       true,
+	"text/javascript":                 true,
+}
+
+// blockedMimeTypePrefixes are media types which are not documents, like URL schemes and directories
+var blockedMimeTypePrefixes = []string{"x-scheme-handler/", "inode/", "x-content/"}
+
+// Validate returns an error if the association has a malformed MIME type or extension,
+// or it would make an app the handler of executables, scripts, web pages or URL schemes
+func (association *FileAssociation) Validate() error {
+	mimeType := strings.ToLower(association.MimeType)
+	if !mimeTypePattern.MatchString(mimeType) {
+		return errors.Errorf("invalid MIME type %q", association.MimeType)
+	}
+	if blockedMimeTypes[mimeType] {
+		return errors.Errorf("MIME type %s can't be associated with applications", mimeType)
+	}
+	for _, prefix := range blockedMimeTypePrefixes {
+		if strings.HasPrefix(mimeType, prefix) {
+			return errors.Errorf("MIME type %s can't be associated with applications", mimeType)
+		}
+	}
+	for _, extension := range association.Extensions {
+		if !extensionPattern.MatchString(extension) {
+			return errors.Errorf("invalid extension %q", extension)
+		}
+		if blockedExtensions[strings.ToLower(extension)] {
+			return errors.Errorf("extension %s can't be associated with applications", extension)
+		}
+	}
+	return nil
+}
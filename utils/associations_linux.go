@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RegisterFileAssociations registers title as a handler of associations in the shared MIME-info database
+// and creates a hidden desktop entry which runs src with openArguments for a document.
+// Printing is not supported by desktop entries, so printArguments are ignored.
+func RegisterFileAssociations(src, title string, associations []*FileAssociation, openArguments, printArguments []string) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to register file associations for %s", title)
+		}
+	}()
+	if len(associations) == 0 {
+		return nil
+	}
+	mimeDir, applicationsDir, err := getAssociationDirs()
+	if err != nil {
+		return err
+	}
+	packagesDir := filepath.Join(mimeDir, "packages")
+	if err = os.MkdirAll(packagesDir, 0755); err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(filepath.Join(packagesDir, desktopEntryID(title)+".xml"), []byte(buildMimeInfo(associations)), 0644); err != nil {
+		return err
+	}
+	var execArgs []string
+	for _, arg := range append([]string{src}, openArguments...) {
+		if arg == DocumentArgument {
+			arg = "%f"
+		}
+		execArgs = append(execArgs, arg)
+	}
+	var mimeTypes []string
+	for _, association := range associations {
+		mimeTypes = append(mimeTypes, association.MimeType)
+	}
+	entry := &DesktopEntry{
+		Name:      title,
+		Exec:      execArgs,
+		Icon:      associations[0].Icon,
+		MimeTypes: mimeTypes,
+		NoDisplay: true,
+	}
+	entryID := getAssociationsDesktopEntryID(title)
+	if err = entry.Write(filepath.Join(applicationsDir, entryID)); err != nil {
+		return err
+	}
+	updateMimeDatabases(mimeDir, applicationsDir)
+	if xdgMime, lookErr := exec.LookPath("xdg-mime"); lookErr == nil {
+		exec.Command(xdgMime, append([]string{"default", entryID}, mimeTypes...)...).Run()
+	}
+	return nil
+}
+
+// UnregisterFileAssociations removes files created by RegisterFileAssociations
+func UnregisterFileAssociations(title string, associations []*FileAssociation) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to unregister file associations for %s", title)
+		}
+	}()
+	if len(associations) == 0 {
+		return nil
+	}
+	mimeDir, applicationsDir, err := getAssociationDirs()
+	if err != nil {
+		return err
+	}
+	if err = removeIfExists(filepath.Join(mimeDir, "packages", desktopEntryID(title)+".xml")); err != nil {
+		return err
+	}
+	if err = removeIfExists(filepath.Join(applicationsDir, getAssociationsDesktopEntryID(title))); err != nil {
+		return err
+	}
+	updateMimeDatabases(mimeDir, applicationsDir)
+	return nil
+}
+
+func getAssociationDirs() (mimeDir string, applicationsDir string, err error) {
+	dataHome, err := getDataHome()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dataHome, "mime"), filepath.Join(dataHome, "applications"), nil
+}
+
+func getAssociationsDesktopEntryID(title string) string {
+	return desktopEntryID(title) + "-documents.desktop"
+}
+
+// buildMimeInfo returns a shared MIME-info package describing associations
+func buildMimeInfo(associations []*FileAssociation) string {
+	var builder strings.Builder
+	builder.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	builder.WriteString(`<mime-info xmlns="http://www.freedesktop.org/standards/shared-mime-info">` + "\n")
+	for _, association := range associations {
+		builder.WriteString(fmt.Sprintf("  <mime-type type=\"%s\">\n", escapeXML(association.MimeType)))
+		if association.Description != "" {
+			builder.WriteString(fmt.Sprintf("    <comment>%s</comment>\n", escapeXML(association.Description)))
+		}
+		for _, extension := range association.Extensions {
+			builder.WriteString(fmt.Sprintf("    <glob pattern=\"*.%s\"/>\n", escapeXML(extension)))
+		}
+		builder.WriteString("  </mime-type>\n")
+	}
+	builder.WriteString("</mime-info>\n")
+	return builder.String()
+}
+
+// updateMimeDatabases rebuilds caches of MIME types and desktop entries, the tools may be missing on minimal systems.
+// The MIME database is not updated if mimeDir is empty.
+func updateMimeDatabases(mimeDir, applicationsDir string) {
+	if updateMimeDatabase, err := exec.LookPath("update-mime-database"); err == nil && mimeDir != "" {
+		exec.Command(updateMimeDatabase, mimeDir).Run()
+	}
+	if updateDesktopDatabase, err := exec.LookPath("update-desktop-database"); err == nil {
+		exec.Command(updateDesktopDatabase, applicationsDir).Run()
+	}
+}
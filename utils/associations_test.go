@@ -0,0 +1,31 @@
+package utils
+
+import "testing"
+
+func Test_FileAssociation_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		association *FileAssociation
+		wantErr     bool
+	}{
+		{"document", &FileAssociation{MimeType: "application/x-my-app", Extensions: []string{"myapp", "my-doc"}}, false},
+		{"vendor type", &FileAssociation{MimeType: "application/vnd.example+xml"}, false},
+		{"without MIME type", &FileAssociation{Extensions: []string{"myapp"}}, true},
+		{"without subtype", &FileAssociation{MimeType: "application"}, true},
+		{"new line", &FileAssociation{MimeType: "a/b\nExec=/bin/sh"}, true},
+		{"semicolon", &FileAssociation{MimeType: "a/b;c/d"}, true},
+		{"URL scheme", &FileAssociation{MimeType: "x-scheme-handler/https"}, true},
+		{"web page", &FileAssociation{MimeType: "Text/HTML"}, true},
+		{"executable", &FileAssociation{MimeType: "application/x-my-app", Extensions: []string{"EXE"}}, true},
+		{"shortcut", &FileAssociation{MimeType: "application/x-my-app", Extensions: []string{"lnk"}}, true},
+		{"JNLP", &FileAssociation{MimeType: "application/x-my-app", Extensions: []string{"jnlp"}}, true},
+		{"path", &FileAssociation{MimeType: "application/x-my-app", Extensions: []string{`doc\shell`}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.association.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows/registry"
+)
+
+const classesKeyPath = `Software\Classes\`
+
+var (
+	shell32         = syscall.NewLazyDLL("shell32.dll")
+	pSHChangeNotify = shell32.NewProc("SHChangeNotify")
+)
+
+const cSHCNE_ASSOCCHANGED = 0x08000000
+
+// RegisterFileAssociations registers a ProgID for each association in HKEY_CURRENT_USER\Software\Classes
+// with open and print verbs which run src with openArguments and printArguments
+func RegisterFileAssociations(src, title string, associations []*FileAssociation, openArguments, printArguments []string) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to register file associations for %s", title)
+		}
+	}()
+	for _, association := range associations {
+		progID := getProgID(title, association)
+		description := association.Description
+		if description == "" {
+			description = title
+		}
+		if err = setRegistryDefaultValue(classesKeyPath+progID, description); err != nil {
+			return err
+		}
+		if association.Icon != "" {
+			if err = setRegistryDefaultValue(classesKeyPath+progID+`\DefaultIcon`, association.Icon); err != nil {
+				return err
+			}
+		}
+		if err = setRegistryDefaultValue(classesKeyPath+progID+`\shell\open\command`, buildAssociationCommand(src, openArguments)); err != nil {
+			return err
+		}
+		if len(printArguments) > 0 {
+			if err = setRegistryDefaultValue(classesKeyPath+progID+`\shell\print\command`, buildAssociationCommand(src, printArguments)); err != nil {
+				return err
+			}
+		}
+		for _, extension := range association.Extensions {
+			extensionKeyPath := classesKeyPath + "." + extension
+			if err = setRegistryDefaultValue(extensionKeyPath, progID); err != nil {
+				return err
+			}
+			// the app stays in Open with list if another app takes the extension over
+			if err = setRegistryStringValue(extensionKeyPath+`\OpenWithProgids`, progID, ""); err != nil {
+				return err
+			}
+			if association.MimeType != "" {
+				if err = setRegistryStringValue(extensionKeyPath, "Content Type", association.MimeType); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	notifyAssociationsChanged()
+	return nil
+}
+
+// UnregisterFileAssociations removes ProgIDs created by RegisterFileAssociations and their OpenWithProgids entries,
+// the default value of an extension is cleared only if it still refers to the app.
+// Keys of extensions are deleted only if nothing else is left in them.
+func UnregisterFileAssociations(title string, associations []*FileAssociation) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to unregister file associations for %s", title)
+		}
+	}()
+	for _, association := range associations {
+		progID := getProgID(title, association)
+		for _, extension := range association.Extensions {
+			extensionKeyPath := classesKeyPath + "." + extension
+			if getRegistryDefaultValue(extensionKeyPath) == progID {
+				if err = deleteRegistryValue(extensionKeyPath, ""); err != nil {
+					return err
+				}
+				if association.MimeType != "" && getRegistryStringValue(extensionKeyPath, "Content Type") == association.MimeType {
+					if err = deleteRegistryValue(extensionKeyPath, "Content Type"); err != nil {
+						return err
+					}
+				}
+			}
+			if err = deleteRegistryValue(extensionKeyPath+`\OpenWithProgids`, progID); err != nil {
+				return err
+			}
+			if err = deleteRegistryKeyIfEmpty(extensionKeyPath + `\OpenWithProgids`); err != nil {
+				return err
+			}
+			if err = deleteRegistryKeyIfEmpty(extensionKeyPath); err != nil {
+				return err
+			}
+		}
+		if err = deleteRegistryKeyTree(classesKeyPath + progID); err != nil {
+			return err
+		}
+	}
+	notifyAssociationsChanged()
+	return nil
+}
+
+// getProgID returns a programmatic identifier like OpenWebLaunch.MyApp.txt
+func getProgID(title string, association *FileAssociation) string {
+	nonAlphanumeric := regexp.MustCompile("[^A-Za-z0-9]+")
+	progID := "OpenWebLaunch." + nonAlphanumeric.ReplaceAllString(title, "")
+	if len(association.Extensions) > 0 {
+		progID += "." + nonAlphanumeric.ReplaceAllString(association.Extensions[0], "")
+	}
+	return progID
+}
+
+func buildAssociationCommand(src string, arguments []string) string {
+	var args []string
+	for _, arg := range append([]string{src}, arguments...) {
+		if arg == DocumentArgument || strings.Contains(arg, " ") {
+			arg = QuoteString(arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+func setRegistryDefaultValue(path, value string) error {
+	return setRegistryStringValue(path, "", value)
+}
+
+func setRegistryStringValue(path, name, value string) error {
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	return key.SetStringValue(name, value)
+}
+
+func getRegistryDefaultValue(path string) string {
+	return getRegistryStringValue(path, "")
+}
+
+func getRegistryStringValue(path, name string) string {
+	key, err := registry.OpenKey(registry.CURRENT_USER, path, registry.QUERY_VALUE)
+	if err != nil {
+		return ""
+	}
+	defer key.Close()
+	value, _, err := key.GetStringValue(name)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// deleteRegistryValue deletes a value of a key of HKEY_CURRENT_USER, missing keys and values are ignored
+func deleteRegistryValue(path, name string) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, path, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	if err := key.DeleteValue(name); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+	return nil
+}
+
+// deleteRegistryKeyIfEmpty deletes a key of HKEY_CURRENT_USER if it has neither values nor subkeys
+func deleteRegistryKeyIfEmpty(path string) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, path, registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	info, err := key.Stat()
+	key.Close()
+	if err != nil {
+		return err
+	}
+	if info.SubKeyCount > 0 || info.ValueCount > 0 {
+		return nil
+	}
+	return registry.DeleteKey(registry.CURRENT_USER, path)
+}
+
+// deleteRegistryKeyTree deletes a key of HKEY_CURRENT_USER with all its subkeys
+func deleteRegistryKeyTree(path string) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, path, registry.ENUMERATE_SUB_KEYS)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	subkeys, err := key.ReadSubKeyNames(-1)
+	key.Close()
+	if err != nil {
+		return err
+	}
+	for _, subkey := range subkeys {
+		if err := deleteRegistryKeyTree(path + `\` + subkey); err != nil {
+			return err
+		}
+	}
+	return registry.DeleteKey(registry.CURRENT_USER, path)
+}
+
+// notifyAssociationsChanged makes Explorer reload file associations and icons
+func notifyAssociationsChanged() {
+	pSHChangeNotify.Call(uintptr(cSHCNE_ASSOCCHANGED), 0, 0, 0)
+}
@@ -0,0 +1,501 @@
+package download
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+)
+
+// maxAuthAttempts limits how many times credentials are asked for a request
+const maxAuthAttempts = 3
+
+// Credentials are a user name and a password for HTTP authentication,
+// the password is sent as a token if the server asks for bearer authentication
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialsPrompt asks the user for credentials for realm on host,
+// failed is true if the previous credentials were rejected. It returns false if the user cancels.
+// insecure is true if the credentials are sent without encryption, entering them confirms it.
+type CredentialsPrompt func(host, realm string, failed, insecure bool) (*Credentials, bool)
+
+// challenge is an authentication scheme with parameters from WWW-Authenticate header
+type challenge struct {
+	scheme string            // Lower case scheme like basic or digest
+	params map[string]string // Parameters by lower case name
+}
+
+// hostAuthorization is authorization which succeeded for an origin, it is sent with further requests to the origin
+type hostAuthorization struct {
+	challenge   *challenge
+	credentials *Credentials
+	nonceCount  int
+}
+
+// authenticator keeps credentials for the session, they are never saved
+type authenticator struct {
+	mutex            sync.Mutex // Also serializes prompts, so the user is asked once for parallel downloads
+	prompt           CredentialsPrompt
+	credentialsFiles []string
+	allowInsecure    bool                          // Basic and bearer credentials are sent over HTTP without asking the user
+	realms           map[string]*Credentials       // Credentials by origin and realm
+	insecureRealms   map[string]bool               // Origins and realms the user entered credentials for knowing they are sent without encryption
+	origins          map[string]*hostAuthorization // Last successful authorization by origin (scheme, host and port)
+}
+
+var auth = newAuthenticator()
+
+func newAuthenticator() *authenticator {
+	return &authenticator{
+		realms:         make(map[string]*Credentials),
+		insecureRealms: make(map[string]bool),
+		origins:        make(map[string]*hostAuthorization),
+	}
+}
+
+// SetCredentialsPrompt sets the function asking the user for credentials, nil disables prompts
+func SetCredentialsPrompt(prompt CredentialsPrompt) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	auth.prompt = prompt
+}
+
+// SetCredentialsFiles sets files in netrc format credentials are looked up in before asking the user
+func SetCredentialsFiles(filenames ...string) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	auth.credentialsFiles = nil
+	for _, filename := range filenames {
+		if filename != "" {
+			auth.credentialsFiles = append(auth.credentialsFiles, filename)
+		}
+	}
+}
+
+// SetInsecureAuthenticationAllowed allows to send basic and bearer credentials over HTTP without encryption.
+// Otherwise only credentials the user enters knowing they aren't encrypted are sent.
+func SetInsecureAuthenticationAllowed(allowed bool) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	auth.allowInsecure = allowed
+}
+
+// NetrcFile returns path of the user's netrc file from NETRC environment variable, ~/.netrc or ~/_netrc on Windows
+func NetrcFile() string {
+	if filename := os.Getenv("NETRC"); filename != "" {
+		return filename
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// doRequest sends a request answering authentication challenges of the server
+func doRequest(method, rawurl string) (*http.Response, error) {
+	request, err := http.NewRequest(method, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	auth.authorizeForOrigin(request)
+	response, err := client.Do(request)
+	var rejected *Credentials
+	for attempt := 0; err == nil && response.StatusCode == http.StatusUnauthorized && attempt < maxAuthAttempts; attempt++ {
+		challenge := selectChallenge(response.Header["Www-Authenticate"])
+		if challenge == nil {
+			log.Printf("no supported authentication schemes in %v for %s", response.Header["Www-Authenticate"], rawurl)
+			break
+		}
+		// the server can redirect to another host asking for credentials
+		target := response.Request.URL
+		credentials, ok := auth.credentials(target, challenge, rejected)
+		if !ok {
+			log.Printf("no credentials for %s realm %q", target.Host, challenge.params["realm"])
+			break
+		}
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+		if request, err = http.NewRequest(method, target.String(), nil); err != nil {
+			return nil, err
+		}
+		authorization := &hostAuthorization{challenge: challenge, credentials: credentials}
+		request.Header.Set("Authorization", authorization.header(request))
+		if target.Scheme != "https" && challenge.scheme != "digest" {
+			log.Printf("warning: credentials for %s are sent without encryption", target.Host)
+		}
+		if response, err = client.Do(request); err == nil && response.StatusCode != http.StatusUnauthorized {
+			auth.succeeded(target, authorization)
+		}
+		rejected = credentials
+	}
+	return response, err
+}
+
+// authorizeForOrigin adds authorization which succeeded for the origin of the request before.
+// Basic and bearer credentials are sent without a challenge only over HTTPS.
+func (auth *authenticator) authorizeForOrigin(request *http.Request) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	authorization, ok := auth.origins[originOf(request.URL)]
+	if !ok || (request.URL.Scheme != "https" && authorization.challenge.scheme != "digest") {
+		return
+	}
+	request.Header.Set("Authorization", authorization.header(request))
+}
+
+func (auth *authenticator) succeeded(target *url.URL, authorization *hostAuthorization) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	auth.origins[originOf(target)] = authorization
+}
+
+// credentials returns credentials for the realm of challenge from the session cache, credentials files or the user.
+// rejected are credentials the server refused for the previous attempt.
+// Basic and bearer credentials for HTTP are only taken from the user unless insecure authentication is allowed.
+func (auth *authenticator) credentials(target *url.URL, challenge *challenge, rejected *Credentials) (*Credentials, bool) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	realm := challenge.params["realm"]
+	origin := originOf(target)
+	key := origin + " " + realm
+	if rejected != nil {
+		delete(auth.origins, origin)
+	}
+	insecure := target.Scheme != "https" && challenge.scheme != "digest"
+	confirmed := !insecure || auth.allowInsecure || auth.insecureRealms[key]
+	if credentials, ok := auth.realms[key]; ok && confirmed && !sameCredentials(credentials, rejected) {
+		return credentials, true
+	}
+	delete(auth.realms, key)
+	delete(auth.insecureRealms, key)
+	hostname := target.Hostname()
+	for _, filename := range auth.credentialsFiles {
+		if !confirmed {
+			log.Printf("credentials files are not used for %s because credentials would be sent without encryption", hostname)
+			break
+		}
+		credentials, err := lookupNetrc(filename, hostname)
+		if err != nil {
+			if !os.IsNotExist(errors.Cause(err)) {
+				log.Printf("warning: %v", err)
+			}
+			continue
+		}
+		if credentials != nil && !sameCredentials(credentials, rejected) {
+			log.Printf("using credentials for %s from %s", hostname, filename)
+			auth.realms[key] = credentials
+			return credentials, true
+		}
+	}
+	if auth.prompt == nil {
+		return nil, false
+	}
+	credentials, ok := auth.prompt(target.Host, realm, rejected != nil, insecure)
+	if !ok {
+		return nil, false
+	}
+	auth.realms[key] = credentials
+	auth.insecureRealms[key] = insecure
+	return credentials, true
+}
+
+func sameCredentials(a, b *Credentials) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// header returns value of Authorization header for request
+func (authorization *hostAuthorization) header(request *http.Request) string {
+	credentials := authorization.credentials
+	switch authorization.challenge.scheme {
+	case "digest":
+		authorization.nonceCount++
+		return digestAuthorization(authorization.challenge, credentials, request.Method, request.URL.RequestURI(), authorization.nonceCount, randomHex(16))
+	case "bearer":
+		return "Bearer " + credentials.Password
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials.Username+":"+credentials.Password))
+}
+
+// selectChallenge returns the most secure supported challenge
+func selectChallenge(headers []string) *challenge {
+	var selected *challenge
+	rank := map[string]int{"bearer": 1, "basic": 2, "digest": 3}
+	for _, challenge := range parseChallenges(headers) {
+		if challenge.scheme == "digest" && !isSupportedDigest(challenge) {
+			continue
+		}
+		if rank[challenge.scheme] > 0 && (selected == nil || rank[challenge.scheme] > rank[selected.scheme]) {
+			selected = challenge
+		}
+	}
+	return selected
+}
+
+// parseChallenges parses WWW-Authenticate headers like `Digest realm="apps", qop="auth", nonce="abc", Basic realm="apps"`
+func parseChallenges(headers []string) []*challenge {
+	var challenges []*challenge
+	for _, header := range headers {
+		scanner := &headerScanner{s: header}
+		for {
+			scanner.skip(" \t,")
+			scheme := scanner.token()
+			if scheme == "" {
+				if scanner.done() {
+					break
+				}
+				scanner.position++ // unexpected character
+				continue
+			}
+			current := &challenge{scheme: strings.ToLower(scheme), params: make(map[string]string)}
+			challenges = append(challenges, current)
+			for {
+				scanner.skip(" \t,")
+				start := scanner.position
+				name := scanner.token()
+				scanner.skip(" \t")
+				if name == "" || !scanner.consume('=') {
+					// the next challenge
+					scanner.position = start
+					break
+				}
+				scanner.skip(" \t")
+				current.params[strings.ToLower(name)] = scanner.value()
+			}
+		}
+	}
+	return challenges
+}
+
+type headerScanner struct {
+	s        string
+	position int
+}
+
+func (scanner *headerScanner) done() bool {
+	return scanner.position >= len(scanner.s)
+}
+
+func (scanner *headerScanner) skip(characters string) {
+	for !scanner.done() && strings.IndexByte(characters, scanner.s[scanner.position]) != -1 {
+		scanner.position++
+	}
+}
+
+func (scanner *headerScanner) consume(c byte) bool {
+	if !scanner.done() && scanner.s[scanner.position] == c {
+		scanner.position++
+		return true
+	}
+	return false
+}
+
+func (scanner *headerScanner) token() string {
+	start := scanner.position
+	for !scanner.done() && strings.IndexByte(" \t,=\"", scanner.s[scanner.position]) == -1 {
+		scanner.position++
+	}
+	return scanner.s[start:scanner.position]
+}
+
+func (scanner *headerScanner) value() string {
+	if !scanner.consume('"') {
+		return scanner.token()
+	}
+	var builder strings.Builder
+	for !scanner.done() {
+		c := scanner.s[scanner.position]
+		scanner.position++
+		switch {
+		case c == '"':
+			return builder.String()
+		case c == '\\' && !scanner.done():
+			builder.WriteByte(scanner.s[scanner.position])
+			scanner.position++
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+func isSupportedDigest(challenge *challenge) bool {
+	if challenge.params["nonce"] == "" {
+		return false
+	}
+	switch strings.ToUpper(challenge.params["algorithm"]) {
+	case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+	default:
+		return false
+	}
+	qop := challenge.params["qop"]
+	return qop == "" || hasToken(qop, "auth")
+}
+
+func hasToken(list, token string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// digestAuthorization computes Authorization header for digest authentication as defined in RFC 7616
+func digestAuthorization(challenge *challenge, credentials *Credentials, method, uri string, nonceCount int, cnonce string) string {
+	params := challenge.params
+	algorithm := strings.ToUpper(params["algorithm"])
+	newHash := md5.New
+	if strings.HasPrefix(algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	digest := func(s string) string {
+		return hashString(newHash(), s)
+	}
+	ha1 := digest(credentials.Username + ":" + params["realm"] + ":" + credentials.Password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = digest(ha1 + ":" + params["nonce"] + ":" + cnonce)
+	}
+	ha2 := digest(method + ":" + uri)
+	nc := fmt.Sprintf("%08x", nonceCount)
+	qop := ""
+	if params["qop"] != "" {
+		qop = "auth"
+	}
+	var response string
+	if qop != "" {
+		response = digest(strings.Join([]string{ha1, params["nonce"], nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = digest(ha1 + ":" + params["nonce"] + ":" + ha2)
+	}
+	fields := []string{
+		fmt.Sprintf("username=%q", credentials.Username),
+		fmt.Sprintf("realm=%q", params["realm"]),
+		fmt.Sprintf("nonce=%q", params["nonce"]),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("response=%q", response),
+	}
+	if params["algorithm"] != "" {
+		fields = append(fields, "algorithm="+params["algorithm"])
+	}
+	if qop != "" {
+		fields = append(fields, "qop="+qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	if opaque, ok := params["opaque"]; ok {
+		fields = append(fields, fmt.Sprintf("opaque=%q", opaque))
+	}
+	return "Digest " + strings.Join(fields, ", ")
+}
+
+func hashString(h hash.Hash, s string) string {
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func randomHex(length int) string {
+	data := make([]byte, length/2)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+// lookupNetrc returns credentials for host from netrc file, the default entry is used if there is no entry for host.
+// It returns nil if there are no credentials for host.
+func lookupNetrc(filename, host string) (*Credentials, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	credentials, err := parseNetrc(file, host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", filename)
+	}
+	return credentials, nil
+}
+
+// parseNetrc parses netrc entries like "machine example.com login user password secret" and "default login user password secret"
+func parseNetrc(reader io.Reader, host string) (*Credentials, error) {
+	scanner := bufio.NewScanner(reader)
+	var tokens []string
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// a macro definition ends with an empty line
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if strings.HasPrefix(field, "#") {
+				break
+			}
+			if field == "macdef" {
+				inMacro = true
+				break
+			}
+			tokens = append(tokens, field)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var found, fallback *Credentials
+	var current *Credentials
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if i+1 == len(tokens) {
+				return nil, errors.New("missing machine name")
+			}
+			i++
+			current = &Credentials{}
+			if found == nil && strings.EqualFold(tokens[i], host) {
+				found = current
+			}
+		case "default":
+			current = &Credentials{}
+			if fallback == nil {
+				fallback = current
+			}
+		case "login", "password", "account":
+			if i+1 == len(tokens) {
+				return nil, errors.Errorf("missing value of %s", tokens[i])
+			}
+			if current != nil {
+				switch tokens[i] {
+				case "login":
+					current.Username = tokens[i+1]
+				case "password":
+					current.Password = tokens[i+1]
+				}
+			}
+			i++
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+	return fallback, nil
+}
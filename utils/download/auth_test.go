@@ -0,0 +1,188 @@
+package download
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseChallenges(t *testing.T) {
+	headers := []string{
+		`Negotiate, Digest realm="apps@example.com", qop="auth,auth-int", nonce="dcd98b", opaque="5ccc", Basic realm="apps"`,
+		`Bearer realm="api", error="invalid_token", error_description="The \"token\" expired"`,
+	}
+	var got []string
+	for _, challenge := range parseChallenges(headers) {
+		got = append(got, challenge.scheme+" "+challenge.params["realm"])
+	}
+	want := []string{"negotiate ", "digest apps@example.com", "basic apps", "bearer api"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChallenges() = %v, want %v", got, want)
+	}
+	if selected := selectChallenge(headers); selected.scheme != "digest" || selected.params["qop"] != "auth,auth-int" {
+		t.Errorf("selectChallenge() = %+v, want digest", selected)
+	}
+}
+
+func Test_digestAuthorization(t *testing.T) {
+	// example from RFC 2617
+	challenge := parseChallenges([]string{`Digest realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`})[0]
+	got := digestAuthorization(challenge, &Credentials{"Mufasa", "Circle Of Life"}, "GET", "/dir/index.html", 1, "0a4f113b")
+	if !strings.Contains(got, `response="6629fae49393a05397450978507c4ef1"`) || !strings.Contains(got, "nc=00000001") {
+		t.Errorf("digestAuthorization() = %s", got)
+	}
+}
+
+func Test_parseNetrc(t *testing.T) {
+	netrc := `# comment
+machine other.example.com login other password secret1
+macdef init
+cd /pub
+
+default login anonymous password guest
+machine apps.example.com
+	login user
+	password "secret2"`
+	tests := []struct {
+		host string
+		want *Credentials
+	}{
+		{"other.example.com", &Credentials{"other", "secret1"}},
+		{"APPS.example.com", &Credentials{"user", `"secret2"`}},
+		{"unknown.example.com", &Credentials{"anonymous", "guest"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := parseNetrc(strings.NewReader(netrc), tt.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNetrc() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_doRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="apps"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("jar"))
+	}))
+	defer server.Close()
+	var prompts []bool
+	answers := []*Credentials{{"user", "wrong"}, {"user", "secret"}}
+	SetCredentialsPrompt(func(host, realm string, failed, insecure bool) (*Credentials, bool) {
+		if realm != "apps" || !insecure || len(prompts) == len(answers) {
+			return nil, false
+		}
+		prompts = append(prompts, failed)
+		return answers[len(prompts)-1], true
+	})
+	defer SetCredentialsPrompt(nil)
+	for _, name := range []string{"/app.jar", "/lib.jar"} {
+		response, err := doRequest("GET", server.URL+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("doRequest(%s) status = %d, want 200", name, response.StatusCode)
+		}
+	}
+	if want := []bool{false, true}; !reflect.DeepEqual(prompts, want) {
+		t.Errorf("prompts = %v, want %v", prompts, want)
+	}
+}
+
+func Test_authenticator_credentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	netrc := filepath.Join(dir, "netrc")
+	if err := ioutil.WriteFile(netrc, []byte("machine example.com login user password secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	basic := &challenge{scheme: "basic", params: map[string]string{"realm": "apps"}}
+	digest := &challenge{scheme: "digest", params: map[string]string{"realm": "apps"}}
+	tests := []struct {
+		name          string
+		url           string
+		challenge     *challenge
+		allowInsecure bool
+		want          bool
+	}{
+		{"basic over https", "https://example.com/app.jar", basic, false, true},
+		{"digest over http", "http://example.com/app.jar", digest, false, true},
+		{"basic over http", "http://example.com/app.jar", basic, false, false},
+		{"basic over http allowed", "http://example.com/app.jar", basic, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newAuthenticator()
+			auth.credentialsFiles = []string{netrc}
+			auth.allowInsecure = tt.allowInsecure
+			target, _ := url.Parse(tt.url)
+			if _, got := auth.credentials(target, tt.challenge, nil); got != tt.want {
+				t.Errorf("credentials() found = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_authorizeForOrigin(t *testing.T) {
+	basic := &hostAuthorization{challenge: &challenge{scheme: "basic"}, credentials: &Credentials{"user", "secret"}}
+	auth := newAuthenticator()
+	auth.origins["https://example.com:443"] = basic
+	auth.origins["http://example.com:8080"] = basic
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/app.jar", true},
+		{"https://example.com:443/app.jar", true},
+		{"http://example.com/app.jar", false},
+		{"https://example.com:8443/app.jar", false},
+		{"http://example.com:8080/app.jar", false},
+	}
+	for _, tt := range tests {
+		request, err := http.NewRequest("GET", tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth.authorizeForOrigin(request)
+		if got := request.Header.Get("Authorization") != ""; got != tt.want {
+			t.Errorf("authorizeForOrigin(%s) sent authorization = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func Test_checkRedirect(t *testing.T) {
+	via, _ := http.NewRequest("GET", "https://example.com/app.jnlp", nil)
+	for target, want := range map[string]bool{
+		"https://example.com/app.jar": true,
+		"http://example.com/app.jar":  false,
+		"https://example.org/app.jar": false,
+	} {
+		request, _ := http.NewRequest("GET", target, nil)
+		request.Header.Set("Authorization", "Basic dXNlcjpzZWNyZXQ=")
+		if err := checkRedirect(request, []*http.Request{via}); err != nil {
+			t.Fatal(err)
+		}
+		if got := request.Header.Get("Authorization") != ""; got != want {
+			t.Errorf("checkRedirect(%s) kept authorization = %v, want %v", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package download
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ClientCertificate is a certificate presented to HTTPS servers requesting client authentication
+type ClientCertificate struct {
+	Hosts       []string `json:"hosts"`       // Host patterns like apps.example.com or *.example.com
+	Certificate string   `json:"certificate"` // PEM file with the certificate chain
+	Key         string   `json:"key"`         // PEM file with the private key, it may be in Certificate file
+	PKCS12      string   `json:"pkcs12"`      // PKCS#12 file with the certificate and the private key
+	Password    string   `json:"password"`    // Password of PKCS#12 file
+	Java        bool     `json:"java"`        // Pass PKCS#12 file to Java applications as their key store
+}
+
+// clientCertificatesConfig is the format of the file with client certificates
+type clientCertificatesConfig struct {
+	Certificates []*ClientCertificate `json:"certificates"`
+}
+
+// loadedCertificate is a client certificate with its private key
+type loadedCertificate struct {
+	config      *ClientCertificate
+	certificate tls.Certificate
+	transport   *http.Transport // Created on first use, so it has proxies configured by SetProxy
+}
+
+// certificateTransport sends requests to hosts matching client certificates through transports presenting them
+type certificateTransport struct {
+	mutex        sync.Mutex
+	certificates []*loadedCertificate
+}
+
+var clientCertificates = &certificateTransport{}
+
+// LoadClientCertificates loads client certificates configured in a JSON file like
+// {"certificates": [{"hosts": ["*.example.com"], "certificate": "client.pem", "key": "client.key"}]},
+// relative paths are relative to the directory of the file
+func LoadClientCertificates(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.Wrap(err, "unable to read client certificates")
+	}
+	var config clientCertificatesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return errors.Wrapf(err, "unable to parse client certificates %s", filename)
+	}
+	dir := filepath.Dir(filename)
+	for _, certificate := range config.Certificates {
+		for _, filename := range []*string{&certificate.Certificate, &certificate.Key, &certificate.PKCS12} {
+			if *filename != "" && !filepath.IsAbs(*filename) {
+				*filename = filepath.Join(dir, *filename)
+			}
+		}
+	}
+	return SetClientCertificates(config.Certificates)
+}
+
+// SetClientCertificates loads certificates and their private keys, they replace previously set certificates
+func SetClientCertificates(certificates []*ClientCertificate) error {
+	var loaded []*loadedCertificate
+	for _, certificate := range certificates {
+		if len(certificate.Hosts) == 0 {
+			return errors.New("client certificate without hosts")
+		}
+		for _, pattern := range certificate.Hosts {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Errorf("invalid host pattern %q of client certificate", pattern)
+			}
+		}
+		tlsCertificate, err := certificate.load()
+		if err != nil {
+			return err
+		}
+		if certificate.Java && certificate.PKCS12 == "" {
+			log.Printf("warning: client certificate %s can be passed to Java only as PKCS#12 file", certificate.Certificate)
+		}
+		loaded = append(loaded, &loadedCertificate{config: certificate, certificate: tlsCertificate})
+	}
+	clientCertificates.mutex.Lock()
+	defer clientCertificates.mutex.Unlock()
+	clientCertificates.certificates = loaded
+	return nil
+}
+
+// JavaKeyStore returns client certificate for the host of rawurl which is passed to Java applications or nil
+func JavaKeyStore(rawurl string) *ClientCertificate {
+	parsedURL, err := url.Parse(rawurl)
+	if err != nil {
+		return nil
+	}
+	loaded := clientCertificates.find(parsedURL.Hostname())
+	if loaded == nil || !loaded.config.Java || loaded.config.PKCS12 == "" {
+		return nil
+	}
+	return loaded.config
+}
+
+// load reads the certificate chain and the private key
+func (certificate *ClientCertificate) load() (tls.Certificate, error) {
+	if certificate.PKCS12 != "" {
+		data, err := ioutil.ReadFile(certificate.PKCS12)
+		if err != nil {
+			return tls.Certificate{}, errors.Wrap(err, "unable to read client certificate")
+		}
+		privateKey, leaf, caCerts, err := pkcs12.DecodeChain(data, certificate.Password)
+		if err != nil {
+			return tls.Certificate{}, errors.Wrapf(err, "unable to decode client certificate %s", certificate.PKCS12)
+		}
+		tlsCertificate := tls.Certificate{PrivateKey: privateKey, Leaf: leaf, Certificate: [][]byte{leaf.Raw}}
+		for _, caCert := range caCerts {
+			tlsCertificate.Certificate = append(tlsCertificate.Certificate, caCert.Raw)
+		}
+		return tlsCertificate, nil
+	}
+	if certificate.Certificate == "" {
+		return tls.Certificate{}, errors.New("client certificate without certificate or pkcs12 file")
+	}
+	keyFile := certificate.Key
+	if keyFile == "" {
+		keyFile = certificate.Certificate
+	}
+	tlsCertificate, err := tls.LoadX509KeyPair(certificate.Certificate, keyFile)
+	return tlsCertificate, errors.Wrapf(err, "unable to load client certificate %s", certificate.Certificate)
+}
+
+// matches reports whether the certificate is presented to host
+func (certificate *ClientCertificate) matches(host string) bool {
+	for _, pattern := range certificate.Hosts {
+		if matched, _ := path.Match(strings.ToLower(pattern), host); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the first certificate matching host or nil
+func (transport *certificateTransport) find(host string) *loadedCertificate {
+	host = strings.ToLower(host)
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	for _, loaded := range transport.certificates {
+		if loaded.config.matches(host) {
+			return loaded
+		}
+	}
+	return nil
+}
+
+// RoundTrip sends requests to hosts without client certificates through http.DefaultTransport
+func (transport *certificateTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Scheme != "https" {
+		return http.DefaultTransport.RoundTrip(request)
+	}
+	loaded := transport.find(request.URL.Hostname())
+	if loaded == nil {
+		return http.DefaultTransport.RoundTrip(request)
+	}
+	return transport.transportFor(loaded).RoundTrip(request)
+}
+
+// transportFor returns a copy of http.DefaultTransport presenting the certificate
+func (transport *certificateTransport) transportFor(loaded *loadedCertificate) *http.Transport {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	if loaded.transport == nil {
+		loaded.transport = http.DefaultTransport.(*http.Transport).Clone()
+		if loaded.transport.TLSClientConfig == nil {
+			loaded.transport.TLSClientConfig = &tls.Config{}
+		}
+		loaded.transport.TLSClientConfig.Certificates = []tls.Certificate{loaded.certificate}
+	}
+	return loaded.transport
+}
@@ -0,0 +1,130 @@
+package download
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_clientCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clientcert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemData := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...)
+	files := map[string]string{
+		"client.pem":   string(pemData),
+		"certs.json":   `{"certificates": [{"hosts": ["127.0.0.*"], "certificate": "client.pem", "java": true}]}`,
+		"invalid.json": `{"certificates": [{"hosts": ["[127"], "certificate": "client.pem"}]}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "client" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+	defer SetClientCertificates(nil)
+
+	tests := []struct {
+		name       string
+		config     string
+		wantErr    bool
+		wantStatus int
+	}{
+		{"without certificates", "", false, http.StatusForbidden},
+		{"with certificate", "certs.json", false, http.StatusOK},
+		{"invalid host pattern", "invalid.json", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetClientCertificates(nil)
+			if tt.config != "" {
+				if err := LoadClientCertificates(filepath.Join(dir, tt.config)); (err != nil) != tt.wantErr {
+					t.Fatalf("LoadClientCertificates() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+			response, err := doRequest("GET", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("doRequest() status = %d, want %d", response.StatusCode, tt.wantStatus)
+			}
+			// only PKCS#12 files are passed to Java
+			if certificate := JavaKeyStore(server.URL); certificate != nil {
+				t.Errorf("JavaKeyStore() = %+v, want nil", certificate)
+			}
+		})
+	}
+}
+
+func Test_ClientCertificate_load(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		// client.p12 is written by OpenSSL 3 with its defaults: PBES2, PBKDF2 and AES-256-CBC
+		{"OpenSSL 3", "secret", false},
+		{"wrong password", "wrong", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certificate := &ClientCertificate{PKCS12: filepath.Join("testdata", "client.p12"), Password: tt.password}
+			got, err := certificate.load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Leaf == nil || got.Leaf.Subject.CommonName != "client" || got.PrivateKey == nil || len(got.Certificate) != 1 {
+				t.Errorf("load() = %+v, want certificate of client with its private key", got)
+			}
+		})
+	}
+}
@@ -5,16 +5,44 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rocketsoftware/open-web-launch/utils/log"
 )
 
+// offline disables network access, only cached and local files are used
+var offline bool
+
+// SetOffline enables or disables offline mode
+func SetOffline(value bool) {
+	offline = value
+}
+
+// IsOffline reports whether offline mode is enabled
+func IsOffline() bool {
+	return offline
+}
+
+// IsNetworkError reports whether err is caused by a failed connection like an unknown host, a refused connection
+// or a timeout rather than by a response of the server
+func IsNetworkError(err error) bool {
+	err = errors.Cause(err)
+	if urlError, ok := err.(*url.Error); ok {
+		err = urlError.Err
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
 func ToMemory(url string) ([]byte, error) {
 	var buffer bytes.Buffer
 	if err := download(url, &buffer); err != nil {
@@ -35,6 +63,15 @@ func ToFile(url string, dir string, allowCached bool) (string, error) {
 	} else if err != nil {
 		return "", err
 	}
+	if offline {
+		if _, ok := localFilename(url); !ok {
+			if !isFileExist {
+				return "", errors.Errorf("%s is not cached and can't be downloaded in offline mode", url)
+			}
+			log.Printf("offline mode, cached version of %s will be used", url)
+			return filename, nil
+		}
+	}
 	if isFileExist {
 		lastModifiedTime, err := GetLastModifiedTime(url)
 		if err != nil {
@@ -69,7 +106,13 @@ func download(url string, writer io.Writer) (err error) {
 			err = errors.Wrapf(err, "downloading %s", url)
 		}
 	}()
-	response, err := http.Get(url)
+	if filename, ok := localFilename(url); ok {
+		return copyLocalFile(filename, writer)
+	}
+	if offline {
+		return errors.New("network access is disabled in offline mode")
+	}
+	response, err := doRequest("GET", url)
 	if err != nil {
 		return
 	}
@@ -78,15 +121,72 @@ func download(url string, writer io.Writer) (err error) {
 	if response.StatusCode != 200 {
 		return fmt.Errorf("HTTP %s", response.Status)
 	}
+	if fn := getProgress(); fn != nil {
+		total := response.ContentLength
+		if total < 0 {
+			total = 0
+		}
+		fn(url, 0, total)
+		writer = &progressWriter{writer: writer, url: url, total: total, fn: fn}
+	}
 	_, err = io.Copy(writer, body)
 	return
 }
 
+// ProgressFunc receives the number of downloaded bytes of url and its size, 0 if the size is unknown.
+// It is called from goroutines of parallel downloads.
+type ProgressFunc func(url string, bytes, total int64)
+
+var (
+	progressMutex sync.Mutex
+	progress      ProgressFunc
+)
+
+// SetProgress sets the function receiving progress of downloads, nil disables it
+func SetProgress(fn ProgressFunc) {
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+	progress = fn
+}
+
+func getProgress() ProgressFunc {
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+	return progress
+}
+
+// progressWriter reports bytes written to writer
+type progressWriter struct {
+	writer io.Writer
+	url    string
+	bytes  int64
+	total  int64
+	fn     ProgressFunc
+}
+
+func (writer *progressWriter) Write(data []byte) (int, error) {
+	n, err := writer.writer.Write(data)
+	writer.bytes += int64(n)
+	writer.fn(writer.url, writer.bytes, writer.total)
+	return n, err
+}
+
 func GetLastModifiedTime(url string) (time.Time, error) {
-	response, err := http.Head(url)
+	if filename, ok := localFilename(url); ok {
+		stat, err := os.Stat(filename)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return stat.ModTime(), nil
+	}
+	if offline {
+		return time.Time{}, errors.New("network access is disabled in offline mode")
+	}
+	response, err := doRequest("HEAD", url)
 	if err != nil {
 		return time.Time{}, err
 	}
+	defer response.Body.Close()
 	var lastModifiedTime time.Time
 	if response.StatusCode != 200 {
 		return time.Time{}, fmt.Errorf("HTTP %s", response.Status)
@@ -101,6 +201,30 @@ func GetLastModifiedTime(url string) (time.Time, error) {
 	return lastModifiedTime, nil
 }
 
+// localFilename returns a local path for file:// URL
+func localFilename(rawurl string) (string, bool) {
+	parsedURL, err := url.Parse(rawurl)
+	if err != nil || parsedURL.Scheme != "file" {
+		return "", false
+	}
+	filename := parsedURL.Path
+	// file:///C:/dir/file.jar
+	if len(filename) > 2 && filename[0] == '/' && filename[2] == ':' {
+		filename = strings.TrimPrefix(filename, "/")
+	}
+	return filepath.FromSlash(filename), true
+}
+
+func copyLocalFile(filename string, writer io.Writer) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(writer, file)
+	return err
+}
+
 func init() {
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 }
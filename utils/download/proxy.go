@@ -0,0 +1,321 @@
+package download
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+	"github.com/rocketsoftware/open-web-launch/utils/pac"
+)
+
+// ProxyMode defines how proxies for downloads are chosen
+type ProxyMode string
+
+const (
+	ProxyEnvironment ProxyMode = "env"    // HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	ProxyNone        ProxyMode = "none"   // Direct connections
+	ProxyManual      ProxyMode = "manual" // Proxies from settings
+	ProxyAutoConfig  ProxyMode = "pac"    // Proxy auto-config file
+)
+
+// ProxyConfig is a configuration of proxies used for downloads
+type ProxyConfig struct {
+	Mode       ProxyMode
+	HTTPProxy  string // Proxy for HTTP requests in manual mode, like proxy:8080 or http://proxy:8080
+	HTTPSProxy string // Proxy for HTTPS requests in manual mode, HTTPProxy is used if it is empty
+	NoProxy    string // Comma separated hosts, domains and networks connected directly in manual mode
+	AutoConfig string // URL or path of proxy auto-config file
+}
+
+// pacTimeout is a timeout of downloading proxy auto-config file
+const pacTimeout = 30 * time.Second
+
+// proxySelector chooses proxies for URLs
+type proxySelector struct {
+	mode       ProxyMode
+	httpProxy  *url.URL
+	httpsProxy *url.URL
+	noProxy    []string
+	autoConfig string
+
+	scriptOnce sync.Once
+	script     *pac.Script // Loaded on first use, nil if loading failed
+	mutex      sync.Mutex
+	cache      map[string]*url.URL // Proxies chosen by the script by scheme and host
+}
+
+var proxies *proxySelector
+
+// SetProxy configures proxies for all downloads
+func SetProxy(config *ProxyConfig) error {
+	selector := &proxySelector{mode: config.Mode, cache: make(map[string]*url.URL)}
+	var err error
+	switch config.Mode {
+	case ProxyNone:
+	case ProxyEnvironment:
+		selector.noProxy = splitNoProxy(getEnv("NO_PROXY", "no_proxy"))
+		if selector.httpProxy, err = parseProxyURL(getEnv("HTTP_PROXY", "http_proxy")); err != nil {
+			return err
+		}
+		if selector.httpsProxy, err = parseProxyURL(getEnv("HTTPS_PROXY", "https_proxy")); err != nil {
+			return err
+		}
+	case ProxyManual:
+		selector.noProxy = splitNoProxy(config.NoProxy)
+		if selector.httpProxy, err = parseProxyURL(config.HTTPProxy); err != nil {
+			return err
+		}
+		if selector.httpsProxy, err = parseProxyURL(config.HTTPSProxy); err != nil {
+			return err
+		}
+		if selector.httpsProxy == nil {
+			selector.httpsProxy = selector.httpProxy
+		}
+	case ProxyAutoConfig:
+		if config.AutoConfig == "" {
+			return errors.New("proxy auto-config location is not set")
+		}
+		selector.autoConfig = config.AutoConfig
+	default:
+		return errors.Errorf("unknown proxy mode %q", config.Mode)
+	}
+	proxies = selector
+	http.DefaultTransport.(*http.Transport).Proxy = func(request *http.Request) (*url.URL, error) {
+		return selector.proxyForURL(request.URL), nil
+	}
+	return nil
+}
+
+// Proxies returns proxies for HTTP and HTTPS connections to the host of rawurl and hosts connected directly.
+// Proxies chosen by proxy auto-config are returned for the host of rawurl and the list of hosts is empty.
+// Both proxies are nil if connections are direct or SetProxy was not called.
+func Proxies(rawurl string) (httpProxy *url.URL, httpsProxy *url.URL, noProxy []string) {
+	if proxies == nil {
+		return nil, nil, nil
+	}
+	if proxies.mode != ProxyAutoConfig {
+		return proxies.httpProxy, proxies.httpsProxy, proxies.noProxy
+	}
+	parsedURL, err := url.Parse(rawurl)
+	if err != nil || parsedURL.Host == "" {
+		return nil, nil, nil
+	}
+	return proxies.proxyForURL(&url.URL{Scheme: "http", Host: parsedURL.Hostname(), Path: "/"}),
+		proxies.proxyForURL(&url.URL{Scheme: "https", Host: parsedURL.Hostname(), Path: "/"}),
+		nil
+}
+
+func (selector *proxySelector) proxyForURL(target *url.URL) *url.URL {
+	switch selector.mode {
+	case ProxyNone:
+		return nil
+	case ProxyAutoConfig:
+		return selector.autoConfigProxy(target)
+	}
+	if bypassProxy(target, selector.noProxy) {
+		return nil
+	}
+	switch target.Scheme {
+	case "http":
+		return selector.httpProxy
+	case "https":
+		return selector.httpsProxy
+	}
+	return nil
+}
+
+// loadScript loads proxy auto-config, so commands which don't connect to the network don't download it
+func (selector *proxySelector) loadScript() {
+	source, err := loadAutoConfig(selector.autoConfig)
+	if err == nil {
+		selector.script, err = pac.Parse(string(source))
+	}
+	if err != nil {
+		log.Printf("warning: unable to load proxy auto-config %s, connecting directly: %v", selector.autoConfig, err)
+		return
+	}
+	log.Printf("loaded proxy auto-config %s", selector.autoConfig)
+}
+
+// autoConfigProxy returns the first proxy supported by Go from the result of proxy auto-config
+func (selector *proxySelector) autoConfigProxy(target *url.URL) *url.URL {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil
+	}
+	selector.scriptOnce.Do(selector.loadScript)
+	if selector.script == nil {
+		return nil
+	}
+	key := target.Scheme + "://" + target.Host
+	selector.mutex.Lock()
+	defer selector.mutex.Unlock()
+	if proxy, ok := selector.cache[key]; ok {
+		return proxy
+	}
+	// like browsers, path and query of HTTPS URLs are not passed to the script
+	scriptURL := target.String()
+	if target.Scheme == "https" {
+		scriptURL = key + "/"
+	}
+	var proxy *url.URL
+	result, err := selector.script.FindProxyForURL(scriptURL, target.Hostname())
+	if err == nil {
+		proxy, err = firstSupportedProxy(result)
+	}
+	if err != nil {
+		log.Printf("warning: proxy auto-config failed for %s, connecting directly: %v", key, err)
+	} else {
+		log.Printf("proxy auto-config returned %q for %s", result, key)
+	}
+	selector.cache[key] = proxy
+	return proxy
+}
+
+func firstSupportedProxy(result string) (*url.URL, error) {
+	entries, err := pac.ParseResult(result)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		switch entry.Type {
+		case "DIRECT":
+			return nil, nil
+		case "PROXY", "HTTP":
+			return &url.URL{Scheme: "http", Host: entry.Address}, nil
+		case "HTTPS":
+			return &url.URL{Scheme: "https", Host: entry.Address}, nil
+		case "SOCKS", "SOCKS5":
+			return &url.URL{Scheme: "socks5", Host: entry.Address}, nil
+		}
+	}
+	return nil, errors.Errorf("no supported proxies in %q", result)
+}
+
+func loadAutoConfig(location string) ([]byte, error) {
+	if filename, ok := localFilename(location); ok {
+		location = filename
+	}
+	parsedURL, err := url.Parse(location)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		data, err := ioutil.ReadFile(location)
+		return data, errors.Wrap(err, "unable to read proxy auto-config")
+	}
+	if offline {
+		return nil, errors.New("proxy auto-config can't be downloaded in offline mode")
+	}
+	// the script is downloaded directly, it decides which proxies are used
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Timeout:   pacTimeout,
+	}
+	response, err := client.Get(location)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to download proxy auto-config")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return nil, errors.Errorf("unable to download proxy auto-config %s: HTTP %s", location, response.Status)
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	return data, errors.Wrap(err, "unable to download proxy auto-config")
+}
+
+func getEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseProxyURL parses proxy like proxy:8080 or http://proxy:8080, empty proxy means a direct connection
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, errors.Errorf("invalid proxy address %q", proxy)
+	}
+	return proxyURL, nil
+}
+
+func splitNoProxy(noProxy string) []string {
+	var entries []string
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// bypassProxy reports whether target is connected directly. As with NO_PROXY in Go,
+// entries are hosts, IPs or networks like 10.0.0.0/8, optionally with a port, "*" matches all hosts,
+// a domain matches its subdomains and a domain with a leading "." or "*." matches only subdomains.
+// Localhost and loopback addresses are always connected directly.
+func bypassProxy(target *url.URL, noProxy []string) bool {
+	host := strings.ToLower(target.Hostname())
+	port := portOrDefault(target)
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+	for _, entry := range noProxy {
+		if entry == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && ip.Equal(entryIP) {
+				return true
+			}
+			continue
+		}
+		entryHost = strings.TrimPrefix(entryHost, "*")
+		if strings.HasPrefix(entryHost, ".") {
+			if strings.HasSuffix(host, entryHost) {
+				return true
+			}
+			continue
+		}
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
+
+func portOrDefault(target *url.URL) string {
+	if port := target.Port(); port != "" {
+		return port
+	}
+	if target.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,40 @@
+package download
+
+import (
+	"net/url"
+	"testing"
+)
+
+func Test_bypassProxy(t *testing.T) {
+	noProxy := splitNoProxy("intranet.example.com, .corp.example.com,*.lab.example.com, 10.0.0.0/8,192.168.1.1, build:8080")
+	tests := []struct {
+		rawurl string
+		want   bool
+	}{
+		{"http://localhost:8080/app.jnlp", true},
+		{"http://127.0.0.1/app.jnlp", true},
+		{"https://intranet.example.com/app.jnlp", true},
+		{"https://app.INTRANET.example.com/app.jnlp", true},
+		{"https://corp.example.com/app.jnlp", false},
+		{"https://app.corp.example.com/app.jnlp", true},
+		{"https://app.lab.example.com/app.jnlp", true},
+		{"https://myintranet.example.com/app.jnlp", false},
+		{"http://10.1.2.3/app.jnlp", true},
+		{"http://192.168.1.1/app.jnlp", true},
+		{"http://192.168.1.2/app.jnlp", false},
+		{"http://build:8080/app.jnlp", true},
+		{"http://build/app.jnlp", false},
+		{"https://www.example.com/app.jnlp", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rawurl, func(t *testing.T) {
+			target, err := url.Parse(tt.rawurl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bypassProxy(target, noProxy); got != tt.want {
+				t.Errorf("bypassProxy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
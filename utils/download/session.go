@@ -0,0 +1,118 @@
+package download
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BrowserSession is a session of the browser the JNLP file was opened from, like SSO cookies.
+// It is only sent to the origin of the JNLP file and is never saved.
+type BrowserSession struct {
+	URL     string         // URL of the JNLP file
+	Cookies []*http.Cookie // Cookies with Name, Value and optionally Path and Secure
+	Header  http.Header    // Headers like Authorization
+}
+
+// ignoredSessionHeaders are managed by the HTTP client or proxies and aren't taken from the browser
+var ignoredSessionHeaders = []string{
+	"Connection", "Content-Length", "Host", "Keep-Alive", "Proxy-Authorization", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// sessionTransport adds the browser session to requests to its origin. Unlike headers of requests,
+// they are not copied to redirects by http.Client, so they don't leak to other hosts.
+type sessionTransport struct {
+	mutex   sync.Mutex
+	origin  string
+	session *BrowserSession
+	next    http.RoundTripper
+}
+
+var browserSession = &sessionTransport{next: clientCertificates}
+
+// client sends all requests of downloads
+var client = &http.Client{Transport: browserSession, CheckRedirect: checkRedirect}
+
+// checkRedirect removes authorization from redirects to another origin, http.Client removes it only for other hosts,
+// so credentials could be sent without encryption after a redirect from HTTPS to HTTP
+func checkRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if originOf(request.URL) != originOf(via[0].URL) {
+		request.Header.Del("Authorization")
+	}
+	return nil
+}
+
+// SetBrowserSession sets the browser session used for the origin of its URL, nil removes it
+func SetBrowserSession(session *BrowserSession) {
+	origin := ""
+	if session != nil {
+		parsedURL, err := url.Parse(session.URL)
+		if err != nil || parsedURL.Host == "" {
+			session = nil
+		} else {
+			origin = originOf(parsedURL)
+			header := make(http.Header)
+			for name, values := range session.Header {
+				header[http.CanonicalHeaderKey(name)] = values
+			}
+			for _, name := range ignoredSessionHeaders {
+				header.Del(name)
+			}
+			session = &BrowserSession{URL: session.URL, Cookies: session.Cookies, Header: header}
+		}
+	}
+	browserSession.mutex.Lock()
+	defer browserSession.mutex.Unlock()
+	browserSession.origin, browserSession.session = origin, session
+}
+
+// RoundTrip adds cookies and headers of the browser session to requests to its origin
+func (transport *sessionTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.mutex.Lock()
+	session := transport.session
+	matches := session != nil && originOf(request.URL) == transport.origin
+	transport.mutex.Unlock()
+	if !matches {
+		return transport.next.RoundTrip(request)
+	}
+	request = request.Clone(request.Context())
+	for name, values := range session.Header {
+		// authorization answering a challenge of the server takes precedence
+		if _, ok := request.Header[name]; !ok {
+			request.Header[name] = values
+		}
+	}
+	for _, cookie := range session.Cookies {
+		if cookie.Secure && request.URL.Scheme != "https" {
+			continue
+		}
+		if !cookiePathMatches(cookie.Path, request.URL.EscapedPath()) {
+			continue
+		}
+		request.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return transport.next.RoundTrip(request)
+}
+
+// originOf returns scheme, host and port of target like https://apps.example.com:443
+func originOf(target *url.URL) string {
+	return strings.ToLower(target.Scheme) + "://" + strings.ToLower(target.Hostname()) + ":" + portOrDefault(target)
+}
+
+// cookiePathMatches reports whether a cookie with cookiePath is sent for requestPath as defined by RFC 6265
+func cookiePathMatches(cookiePath, requestPath string) bool {
+	if cookiePath == "" || cookiePath == "/" || cookiePath == requestPath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
@@ -0,0 +1,83 @@
+package download
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_SetBrowserSession(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "cookie=%s token=%s", r.Header.Get("Cookie"), r.Header.Get("X-Token"))
+	})
+	other := httptest.NewServer(echo)
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, other.URL+"/app.jar", http.StatusFound)
+			return
+		}
+		echo(w, r)
+	}))
+	defer server.Close()
+	SetBrowserSession(&BrowserSession{
+		URL: server.URL + "/apps/launch.jnlp",
+		Cookies: []*http.Cookie{
+			{Name: "SESSION", Value: "abc"},
+			{Name: "APPS", Value: "1", Path: "/apps"},
+			{Name: "SECURE", Value: "2", Secure: true},
+		},
+		Header: http.Header{"x-token": {"secret"}, "Host": {"evil.example.com"}},
+	})
+	defer SetBrowserSession(nil)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{server.URL + "/apps/launch.jnlp", "cookie=SESSION=abc; APPS=1 token=secret"},
+		{server.URL + "/lib/app.jar", "cookie=SESSION=abc token=secret"},
+		{server.URL + "/redirect", "cookie= token="},
+		{other.URL + "/app.jar", "cookie= token="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			response, err := doRequest("GET", tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			body, err := ioutil.ReadAll(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("got %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cookiePathMatches(t *testing.T) {
+	tests := []struct {
+		cookiePath  string
+		requestPath string
+		want        bool
+	}{
+		{"", "/app.jnlp", true},
+		{"/apps", "/apps", true},
+		{"/apps", "/apps/lib/app.jar", true},
+		{"/apps/", "/apps/app.jar", true},
+		{"/apps", "/appstore/app.jar", false},
+		{"/apps/lib", "/apps/app.jar", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cookiePath+" "+tt.requestPath, func(t *testing.T) {
+			if got := cookiePathMatches(tt.cookiePath, tt.requestPath); got != tt.want {
+				t.Errorf("cookiePathMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
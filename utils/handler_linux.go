@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// urlHandlerMimeTypes are handled by the desktop entry created by RegisterURLHandler
+var urlHandlerMimeTypes = []string{
+	"x-scheme-handler/jnlp",
+	"x-scheme-handler/jnlps",
+	"application/x-java-jnlp-file",
+}
+
+// RegisterURLHandler makes src the default handler of jnlp:// and jnlps:// URLs and JNLP files.
+// The desktop entry is rewritten only if it changed, e.g. when the executable was moved.
+func RegisterURLHandler(src string) error {
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return err
+	}
+	entry := &DesktopEntry{
+		Name:       "Open Web Launch",
+		Comment:    "Run Java Web Start applications",
+		Exec:       []string{src},
+		ExecSuffix: "%u",
+		Categories: []string{"Java"},
+		MimeTypes:  urlHandlerMimeTypes,
+		NoDisplay:  true,
+	}
+	filename := filepath.Join(applicationsDir, getURLHandlerEntryID())
+	if data, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(data, []byte(entry.String())) {
+		return nil
+	}
+	if err := entry.Write(filename); err != nil {
+		return errors.Wrap(err, "unable to register URL handler")
+	}
+	if xdgMime, err := exec.LookPath("xdg-mime"); err == nil {
+		args := append([]string{"default", getURLHandlerEntryID()}, urlHandlerMimeTypes...)
+		if output, err := exec.Command(xdgMime, args...).CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "unable to set default URL handler: %s", output)
+		}
+	}
+	updateMimeDatabases("", applicationsDir)
+	return nil
+}
+
+// UnregisterURLHandler removes the desktop entry created by RegisterURLHandler
+// and the defaults set for it by xdg-mime from the user's mimeapps.list
+func UnregisterURLHandler() error {
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return err
+	}
+	if err := removeIfExists(filepath.Join(applicationsDir, getURLHandlerEntryID())); err != nil {
+		return errors.Wrap(err, "unable to unregister URL handler")
+	}
+	if err := removeMimeApps(getURLHandlerEntryID(), urlHandlerMimeTypes); err != nil {
+		return errors.Wrap(err, "unable to unregister URL handler")
+	}
+	updateMimeDatabases("", applicationsDir)
+	return nil
+}
+
+// removeMimeApps removes desktop entry entryID from the lists of applications for mimeTypes
+// in $XDG_CONFIG_HOME/mimeapps.list, entries which become empty are removed
+func removeMimeApps(entryID string, mimeTypes []string) error {
+	configHome, err := getConfigHome()
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(configHome, "mimeapps.list")
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	isHandledMimeType := make(map[string]bool)
+	for _, mimeType := range mimeTypes {
+		isHandledMimeType[mimeType] = true
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	var result []string
+	for _, line := range lines {
+		i := strings.IndexByte(line, '=')
+		if i < 0 || !isHandledMimeType[strings.TrimSpace(line[:i])] {
+			result = append(result, line)
+			continue
+		}
+		var apps []string
+		removed := false
+		for _, app := range strings.Split(strings.TrimSpace(line[i+1:]), ";") {
+			if app == entryID {
+				removed = true
+			} else if app != "" {
+				apps = append(apps, app)
+			}
+		}
+		switch {
+		case !removed:
+			result = append(result, line)
+		case len(apps) > 0:
+			result = append(result, line[:i+1]+strings.Join(apps, ";")+";\n")
+		}
+	}
+	updated := strings.Join(result, "")
+	if updated == string(data) {
+		return nil
+	}
+	return ioutil.WriteFile(filename, []byte(updated), 0644)
+}
+
+// IsURLHandlerRegistered reports whether RegisterURLHandler has been called
+func IsURLHandlerRegistered() bool {
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(applicationsDir, getURLHandlerEntryID()))
+	return err == nil
+}
+
+func getURLHandlerEntryID() string {
+	return desktopEntryPrefix + ".desktop"
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_removeMimeApps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mimeapps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	entryID := getURLHandlerEntryID()
+	mimeApps := `[Default Applications]
+x-scheme-handler/jnlp=` + entryID + `
+x-scheme-handler/jnlps=other.desktop;` + entryID + `;
+text/html=firefox.desktop
+
+[Added Associations]
+application/x-java-jnlp-file=` + entryID + `;javaws.desktop;
+text/plain=` + entryID + `;
+`
+	want := `[Default Applications]
+x-scheme-handler/jnlps=other.desktop;
+text/html=firefox.desktop
+
+[Added Associations]
+application/x-java-jnlp-file=javaws.desktop;
+text/plain=` + entryID + `;
+`
+	filename := filepath.Join(dir, "mimeapps.list")
+	if err := ioutil.WriteFile(filename, []byte(mimeApps), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeMimeApps(entryID, urlHandlerMimeTypes); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("mimeapps.list = %q, want %q", data, want)
+	}
+}
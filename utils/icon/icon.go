@@ -0,0 +1,281 @@
+// Package icon converts application icons into formats required by desktop integration on each platform.
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	_ "image/gif"  // register GIF format
+	_ "image/jpeg" // register JPEG format
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/draw"
+)
+
+// maxICOSize is the largest width and height of images in ICO files
+const maxICOSize = 256
+
+// maxImageSize is the largest width and height of decoded icons, larger images are rejected
+// before they are decoded because their pixels could exhaust memory
+const maxImageSize = 1024
+
+// WindowsSizes are sizes of images stored in ICO files for Windows shortcuts
+var WindowsSizes = []int{16, 24, 32, 48, 64, 128, 256}
+
+// HicolorSizes are sizes of PNG files installed into hicolor icon theme on Linux
+var HicolorSizes = []int{16, 22, 24, 32, 48, 64, 128, 256}
+
+// Decode decodes an icon file in PNG, GIF, JPEG or ICO format.
+// For ICO files the largest image is returned.
+func Decode(filename string) (image.Image, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(filename)) == ".ico" || isICO(data) {
+		img, err := DecodeICO(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to decode icon %s", filepath.Base(filename))
+		}
+		return img, nil
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to decode icon %s", filepath.Base(filename))
+	}
+	return img, nil
+}
+
+// decodeImage decodes an image in a registered format if its size doesn't exceed maxImageSize
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxImageSize || config.Height > maxImageSize {
+		return nil, errors.Errorf("invalid image size %dx%d, icons may be at most %dx%d", config.Width, config.Height, maxImageSize, maxImageSize)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Resize scales img to a square size x size image.
+// The aspect ratio is preserved, the image is centered on a transparent background.
+func Resize(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = size * bounds.Dy() / bounds.Dx()
+	} else if bounds.Dy() > bounds.Dx() {
+		width = size * bounds.Dx() / bounds.Dy()
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-width)/2, (size-height)/2)
+	target := image.Rectangle{Min: offset, Max: offset.Add(image.Pt(width, height))}
+	draw.CatmullRom.Scale(dst, target, img, bounds, draw.Over, nil)
+	return dst
+}
+
+// WritePNG resizes img to size and saves it as PNG file
+func WritePNG(filename string, img image.Image, size int) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, Resize(img, size))
+}
+
+// WriteICO saves img as multi-resolution ICO file with images of sizes
+func WriteICO(filename string, img image.Image, sizes []int) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return EncodeICO(file, img, sizes)
+}
+
+type icoHeader struct {
+	Reserved uint16
+	Type     uint16
+	Count    uint16
+}
+
+type icoDirEntry struct {
+	Width       uint8
+	Height      uint8
+	ColorCount  uint8
+	Reserved    uint8
+	Planes      uint16
+	BitCount    uint16
+	BytesInRes  uint32
+	ImageOffset uint32
+}
+
+const (
+	icoHeaderSize   = 6
+	icoDirEntrySize = 16
+)
+
+// EncodeICO writes img as ICO with PNG compressed images of sizes, supported since Windows Vista
+func EncodeICO(writer io.Writer, img image.Image, sizes []int) error {
+	var images [][]byte
+	for _, size := range sizes {
+		var buffer bytes.Buffer
+		if err := png.Encode(&buffer, Resize(img, size)); err != nil {
+			return err
+		}
+		images = append(images, buffer.Bytes())
+	}
+	header := icoHeader{Type: 1, Count: uint16(len(sizes))}
+	if err := binary.Write(writer, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	offset := icoHeaderSize + icoDirEntrySize*len(sizes)
+	for i, size := range sizes {
+		entry := icoDirEntry{
+			Width:       uint8(size % 256), // 0 means 256
+			Height:      uint8(size % 256),
+			Planes:      1,
+			BitCount:    32,
+			BytesInRes:  uint32(len(images[i])),
+			ImageOffset: uint32(offset),
+		}
+		if err := binary.Write(writer, binary.LittleEndian, entry); err != nil {
+			return err
+		}
+		offset += len(images[i])
+	}
+	for _, data := range images {
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isICO(data []byte) bool {
+	return len(data) >= icoHeaderSize && bytes.Equal(data[:4], []byte{0, 0, 1, 0})
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// DecodeICO returns the largest image from ICO file.
+// PNG compressed images and uncompressed 24 and 32 bits per pixel bitmaps are supported.
+func DecodeICO(reader io.Reader) (image.Image, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var header icoHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Type != 1 || header.Count == 0 {
+		return nil, errors.New("not an ICO file")
+	}
+	var best *icoDirEntry
+	bestSize := 0
+	for i := 0; i < int(header.Count); i++ {
+		start := icoHeaderSize + i*icoDirEntrySize
+		if start+icoDirEntrySize > len(data) {
+			return nil, errors.New("truncated ICO directory")
+		}
+		var entry icoDirEntry
+		if err := binary.Read(bytes.NewReader(data[start:]), binary.LittleEndian, &entry); err != nil {
+			return nil, err
+		}
+		size := int(entry.Width)
+		if size == 0 {
+			size = 256
+		}
+		if best == nil || size > bestSize || (size == bestSize && entry.BitCount > best.BitCount) {
+			best = &entry
+			bestSize = size
+		}
+	}
+	end := int(best.ImageOffset) + int(best.BytesInRes)
+	if end > len(data) || end < int(best.ImageOffset) {
+		return nil, errors.New("truncated ICO image")
+	}
+	imageData := data[best.ImageOffset:end]
+	if bytes.HasPrefix(imageData, pngSignature) {
+		return decodeImage(imageData)
+	}
+	return decodeDIB(imageData)
+}
+
+type bitmapInfoHeader struct {
+	Size          uint32
+	Width         int32
+	Height        int32
+	Planes        uint16
+	BitCount      uint16
+	Compression   uint32
+	SizeImage     uint32
+	XPelsPerMeter int32
+	YPelsPerMeter int32
+	ClrUsed       uint32
+	ClrImportant  uint32
+}
+
+// decodeDIB decodes a device independent bitmap stored in ICO file,
+// its height is doubled because it includes AND mask
+func decodeDIB(data []byte) (image.Image, error) {
+	var header bitmapInfoHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Size < 40 || int64(header.Size) > int64(len(data)) {
+		return nil, errors.Errorf("invalid ICO bitmap header size %d", header.Size)
+	}
+	if header.Compression != 0 || (header.BitCount != 32 && header.BitCount != 24) {
+		return nil, errors.Errorf("unsupported ICO bitmap with %d bits per pixel and compression %d", header.BitCount, header.Compression)
+	}
+	width := int(header.Width)
+	height := int(header.Height) / 2
+	if width <= 0 || height <= 0 || width > maxICOSize || height > maxICOSize {
+		return nil, errors.Errorf("invalid ICO bitmap size %dx%d", header.Width, header.Height/2)
+	}
+	bytesPerPixel := int(header.BitCount) / 8
+	stride := (width*bytesPerPixel + 3) &^ 3
+	maskStride := ((width + 31) / 32) * 4
+	pixels := data[header.Size:]
+	if len(pixels) < stride*height {
+		return nil, errors.New("truncated ICO bitmap")
+	}
+	mask := pixels[stride*height:]
+	hasMask := len(mask) >= maskStride*height
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := pixels[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			pixel := row[x*bytesPerPixel:]
+			alpha := uint8(255)
+			if bytesPerPixel == 4 {
+				alpha = pixel[3]
+			} else if hasMask {
+				maskRow := mask[(height-1-y)*maskStride:]
+				if maskRow[x/8]&(0x80>>uint(x%8)) != 0 {
+					alpha = 0
+				}
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: pixel[2], G: pixel[1], B: pixel[0], A: alpha})
+		}
+	}
+	return img, nil
+}
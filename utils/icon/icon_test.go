@@ -0,0 +1,102 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_EncodeICO(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 32; y++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var buffer bytes.Buffer
+	if err := EncodeICO(&buffer, img, WindowsSizes); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeICO(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := decoded.Bounds().Size(); size != image.Pt(256, 256) {
+		t.Errorf("DecodeICO() returned image of size %v, want 256x256", size)
+	}
+	// the wide image is centered vertically on transparent background
+	if _, _, _, a := decoded.At(128, 10).RGBA(); a != 0 {
+		t.Errorf("DecodeICO() pixel at top is not transparent")
+	}
+	if r, _, _, a := decoded.At(128, 128).RGBA(); a == 0 || r == 0 {
+		t.Errorf("DecodeICO() pixel at center is not red")
+	}
+}
+
+func Test_DecodeICO_Malformed(t *testing.T) {
+	// ICO directory with one entry pointing to a bitmap at offset 22
+	ico := func(bitmap []byte) []byte {
+		data := []byte{0, 0, 1, 0, 1, 0, 16, 16, 0, 0, 1, 0, 32, 0}
+		data = append(data, byte(len(bitmap)), byte(len(bitmap)>>8), 0, 0, 22, 0, 0, 0)
+		return append(data, bitmap...)
+	}
+	header := func(size uint32, width, height int32) []byte {
+		var buffer bytes.Buffer
+		binary.Write(&buffer, binary.LittleEndian, &bitmapInfoHeader{Size: size, Width: width, Height: height, Planes: 1, BitCount: 32})
+		return buffer.Bytes()
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"header size beyond data", ico(header(0xffffffff, 16, 32))},
+		{"small header size", ico(header(4, 16, 32))},
+		{"huge size", ico(append(header(40, 0x7fffffff, 0x7ffffffe), make([]byte, 64)...))},
+		{"size above 256", ico(append(header(40, 512, 1024), make([]byte, 64)...))},
+		{"truncated pixels", ico(append(header(40, 16, 32), make([]byte, 64)...))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeICO(bytes.NewReader(tt.data)); err == nil {
+				t.Error("DecodeICO() succeeded")
+			}
+		})
+	}
+}
+
+func Test_Decode_HugePNG(t *testing.T) {
+	// 1x1 PNG with IHDR declaring 100000x100000 pixels
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewNRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	ihdr := data[8+4 : 8+4+4+13] // chunk type and data after the signature and length
+	binary.BigEndian.PutUint32(ihdr[4:], 100000)
+	binary.BigEndian.PutUint32(ihdr[8:], 100000)
+	binary.BigEndian.PutUint32(data[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+	dir, err := ioutil.TempDir("", "icon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "huge.png")
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(filename); err == nil {
+		t.Error("Decode() of huge PNG succeeded")
+	}
+	ico := []byte{0, 0, 1, 0, 1, 0, 0, 0, 0, 0, 1, 0, 32, 0}
+	ico = append(ico, byte(len(data)), byte(len(data)>>8), 0, 0, 22, 0, 0, 0)
+	if _, err := DecodeICO(bytes.NewReader(append(ico, data...))); err == nil {
+		t.Error("DecodeICO() of huge PNG succeeded")
+	}
+}
@@ -0,0 +1,41 @@
+package nativehost
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Browsers returns browsers supported on Linux, user directories are in $XDG_CONFIG_HOME or home directory
+func Browsers() []*Browser {
+	configDir, _ := os.UserConfigDir()
+	homeDir, _ := os.UserHomeDir()
+	userDir := func(base string, elem ...string) string {
+		if base == "" {
+			return ""
+		}
+		return filepath.Join(append([]string{base}, elem...)...)
+	}
+	return []*Browser{
+		{
+			Name:      "chrome",
+			UserDir:   userDir(configDir, "google-chrome", "NativeMessagingHosts"),
+			SystemDir: "/etc/opt/chrome/native-messaging-hosts",
+		},
+		{
+			Name:      "chromium",
+			UserDir:   userDir(configDir, "chromium", "NativeMessagingHosts"),
+			SystemDir: "/etc/chromium/native-messaging-hosts",
+		},
+		{
+			Name:      "brave",
+			UserDir:   userDir(configDir, "BraveSoftware", "Brave-Browser", "NativeMessagingHosts"),
+			SystemDir: "/etc/opt/brave.com/brave/native-messaging-hosts",
+		},
+		{
+			Name:      "firefox",
+			Firefox:   true,
+			UserDir:   userDir(homeDir, ".mozilla", "native-messaging-hosts"),
+			SystemDir: "/usr/lib/mozilla/native-messaging-hosts",
+		},
+	}
+}
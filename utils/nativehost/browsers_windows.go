@@ -0,0 +1,6 @@
+package nativehost
+
+// Browsers returns browsers supported on this platform, manifests are only managed on Linux
+func Browsers() []*Browser {
+	return nil
+}
@@ -0,0 +1,205 @@
+// Package nativehost installs manifests registering Open Web Launch as the native messaging host
+// of the browser extension. Browsers start the host only if its manifest allows the extension.
+package nativehost
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults of the published browser extension. The Chrome extension ID is the one of the Chrome Web Store page
+// linked from README.md. The host name and the Firefox add-on ID are not published, so they have no defaults.
+const (
+	DefaultDescription       = "Open Web Launch"
+	DefaultChromeExtensionID = "pmmlhpkdpbddohdbnjinopbkmlcnjnhc"
+)
+
+// Scope selects whether manifests are installed for the current user or for all users
+type Scope string
+
+const (
+	User   Scope = "user"
+	System Scope = "system"
+)
+
+// ErrNotInstalled is returned by Check if the manifest doesn't exist
+var ErrNotInstalled = errors.New("not installed")
+
+// ErrNoExtensionID is returned by Install and Check if the host has no extension ID for the browser
+var ErrNoExtensionID = errors.New("extension ID is not set")
+
+// namePattern is the format of host names required by browsers
+var namePattern = regexp.MustCompile(`^[a-z0-9_]+(\.[a-z0-9_]+)*$`)
+
+// Browser is a browser reading native messaging host manifests
+type Browser struct {
+	Name      string
+	Firefox   bool   // Firefox manifests list allowed extension IDs instead of origins
+	UserDir   string // Directory of manifests for the current user, empty if it is unknown
+	SystemDir string // Directory of manifests for all users
+}
+
+// Host describes the native messaging host
+type Host struct {
+	Name               string
+	Description        string
+	Path               string // Absolute path of the executable
+	ChromeExtensionID  string // ID of the extension for Chrome, Chromium and Brave
+	FirefoxExtensionID string
+}
+
+// manifest is the format of native messaging host manifests
+type manifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	AllowedOrigins    []string `json:"allowed_origins,omitempty"`
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+}
+
+// FindBrowser returns a supported browser by name or nil
+func FindBrowser(name string) *Browser {
+	for _, browser := range Browsers() {
+		if strings.EqualFold(browser.Name, name) {
+			return browser
+		}
+	}
+	return nil
+}
+
+// ManifestFile returns path of the manifest of host name in scope
+func (browser *Browser) ManifestFile(name string, scope Scope) (string, error) {
+	dir := browser.SystemDir
+	if scope == User {
+		dir = browser.UserDir
+	}
+	if dir == "" {
+		return "", errors.Errorf("directory of %s manifests for %s scope is unknown", browser.Name, scope)
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// Install writes the manifest of host for browser in scope and returns its path
+func Install(host *Host, browser *Browser, scope Scope) (string, error) {
+	if err := host.validate(browser); err != nil {
+		return "", err
+	}
+	filename, err := browser.ManifestFile(host.Name, scope)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(host.manifest(browser), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", errors.Wrapf(err, "unable to create directory for %s manifest", browser.Name)
+	}
+	if err := ioutil.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return "", errors.Wrapf(err, "unable to write %s manifest", browser.Name)
+	}
+	return filename, nil
+}
+
+// Uninstall removes the manifest of host name for browser in scope. It returns path of the manifest
+// and whether it existed.
+func Uninstall(name string, browser *Browser, scope Scope) (string, bool, error) {
+	filename, err := browser.ManifestFile(name, scope)
+	if err != nil {
+		return "", false, err
+	}
+	if err := os.Remove(filename); err != nil {
+		if os.IsNotExist(err) {
+			return filename, false, nil
+		}
+		return filename, false, errors.Wrapf(err, "unable to remove %s manifest", browser.Name)
+	}
+	return filename, true, nil
+}
+
+// Check verifies the manifest of host for browser in scope starts host and allows the extension.
+// It returns path of the manifest and ErrNotInstalled if it doesn't exist.
+func Check(host *Host, browser *Browser, scope Scope) (string, error) {
+	if host.extensionID(browser) == "" {
+		return "", ErrNoExtensionID
+	}
+	filename, err := browser.ManifestFile(host.Name, scope)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return filename, ErrNotInstalled
+		}
+		return filename, errors.Wrap(err, "unable to read manifest")
+	}
+	var installed manifest
+	if err := json.Unmarshal(data, &installed); err != nil {
+		return filename, errors.Wrap(err, "unable to parse manifest")
+	}
+	want := host.manifest(browser)
+	switch {
+	case installed.Name != want.Name:
+		return filename, errors.Errorf("name is %q instead of %q", installed.Name, want.Name)
+	case installed.Type != want.Type:
+		return filename, errors.Errorf("type is %q instead of %q", installed.Type, want.Type)
+	case installed.Path != want.Path:
+		return filename, errors.Errorf("path is %s instead of %s", installed.Path, want.Path)
+	case browser.Firefox && !contains(installed.AllowedExtensions, want.AllowedExtensions[0]):
+		return filename, errors.Errorf("extension %s is not allowed", want.AllowedExtensions[0])
+	case !browser.Firefox && !contains(installed.AllowedOrigins, want.AllowedOrigins[0]):
+		return filename, errors.Errorf("origin %s is not allowed", want.AllowedOrigins[0])
+	}
+	if info, err := os.Stat(installed.Path); err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		return filename, errors.Errorf("%s is not an executable", installed.Path)
+	}
+	return filename, nil
+}
+
+func (host *Host) validate(browser *Browser) error {
+	if host.extensionID(browser) == "" {
+		return ErrNoExtensionID
+	}
+	if !namePattern.MatchString(host.Name) {
+		return errors.Errorf("invalid host name %q, it may contain lower case letters, digits, dots and underscores", host.Name)
+	}
+	if !filepath.IsAbs(host.Path) {
+		return errors.Errorf("path of the host %s is not absolute", host.Path)
+	}
+	return nil
+}
+
+// extensionID returns ID of the extension allowed to start the host in browser
+func (host *Host) extensionID(browser *Browser) string {
+	if browser.Firefox {
+		return host.FirefoxExtensionID
+	}
+	return host.ChromeExtensionID
+}
+
+func (host *Host) manifest(browser *Browser) *manifest {
+	m := &manifest{Name: host.Name, Description: host.Description, Path: host.Path, Type: "stdio"}
+	if browser.Firefox {
+		m.AllowedExtensions = []string{host.FirefoxExtensionID}
+	} else {
+		m.AllowedOrigins = []string{"chrome-extension://" + host.ChromeExtensionID + "/"}
+	}
+	return m
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package nativehost
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_InstallCheckUninstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nativehost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := &Host{
+		Name:               "com.example.openweblaunch",
+		Description:        DefaultDescription,
+		Path:               executable,
+		ChromeExtensionID:  DefaultChromeExtensionID,
+		FirefoxExtensionID: "openweblaunch@example.com",
+	}
+	browsers := []*Browser{
+		{Name: "chrome", UserDir: filepath.Join(dir, "chrome"), SystemDir: filepath.Join(dir, "etc", "chrome")},
+		{Name: "firefox", Firefox: true, UserDir: filepath.Join(dir, "mozilla")},
+	}
+	for _, browser := range browsers {
+		t.Run(browser.Name, func(t *testing.T) {
+			if _, err := Check(host, browser, User); err != ErrNotInstalled {
+				t.Fatalf("Check() before install error = %v, want %v", err, ErrNotInstalled)
+			}
+			filename, err := Install(host, browser, User)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed := `"chrome-extension://pmmlhpkdpbddohdbnjinopbkmlcnjnhc/"`
+			if browser.Firefox {
+				allowed = `"openweblaunch@example.com"`
+			}
+			if !strings.Contains(string(data), allowed) {
+				t.Errorf("manifest %s doesn't allow %s", data, allowed)
+			}
+			if _, err := Check(host, browser, User); err != nil {
+				t.Errorf("Check() after install error = %v", err)
+			}
+			moved := *host
+			moved.Path = filepath.Join(dir, "openweblaunch")
+			if _, err := Check(&moved, browser, User); err == nil || !strings.Contains(err.Error(), "path is") {
+				t.Errorf("Check() with another executable error = %v, want path mismatch", err)
+			}
+			if _, removed, err := Uninstall(host.Name, browser, User); err != nil || !removed {
+				t.Errorf("Uninstall() = %v, %v, want removed", removed, err)
+			}
+			if _, removed, err := Uninstall(host.Name, browser, User); err != nil || removed {
+				t.Errorf("second Uninstall() = %v, %v, want not removed", removed, err)
+			}
+		})
+	}
+	if _, err := Install(host, browsers[1], System); err == nil {
+		t.Error("Install() without system directory succeeded")
+	}
+	invalid := *host
+	invalid.Name = "Open Web Launch"
+	if _, err := Install(&invalid, browsers[0], User); err == nil {
+		t.Error("Install() with invalid name succeeded")
+	}
+	withoutID := *host
+	withoutID.FirefoxExtensionID = ""
+	if _, err := Install(&withoutID, browsers[1], User); err != ErrNoExtensionID {
+		t.Errorf("Install() without Firefox add-on ID error = %v, want %v", err, ErrNoExtensionID)
+	}
+	if _, err := Check(&withoutID, browsers[1], User); err != ErrNoExtensionID {
+		t.Errorf("Check() without Firefox add-on ID error = %v, want %v", err, ErrNoExtensionID)
+	}
+}
@@ -0,0 +1,330 @@
+package pac
+
+import (
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/log"
+)
+
+var weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+var months = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// defineBuiltins declares functions available to proxy auto-config files and a few JavaScript globals
+func (script *Script) defineBuiltins(scope *scope) {
+	define := func(name string, call func(arguments []value) (value, error)) {
+		scope.variables[name] = &builtin{name: name, call: call}
+	}
+	stringArguments := func(call func(arguments []string) value) func([]value) (value, error) {
+		return func(arguments []value) (value, error) {
+			texts := make([]string, len(arguments))
+			for i, argument := range arguments {
+				texts[i] = toString(argument)
+			}
+			return call(texts), nil
+		}
+	}
+	define("isPlainHostName", stringArguments(func(arguments []string) value {
+		return !strings.Contains(stringArgument(arguments, 0), ".")
+	}))
+	define("dnsDomainIs", stringArguments(func(arguments []string) value {
+		return strings.HasSuffix(strings.ToLower(stringArgument(arguments, 0)), strings.ToLower(stringArgument(arguments, 1)))
+	}))
+	define("localHostOrDomainIs", stringArguments(func(arguments []string) value {
+		host, hostDomain := strings.ToLower(stringArgument(arguments, 0)), strings.ToLower(stringArgument(arguments, 1))
+		return host == hostDomain || (!strings.Contains(host, ".") && strings.HasPrefix(hostDomain, host+"."))
+	}))
+	define("dnsDomainLevels", stringArguments(func(arguments []string) value {
+		return float64(strings.Count(stringArgument(arguments, 0), "."))
+	}))
+	define("shExpMatch", stringArguments(func(arguments []string) value {
+		return shellExpressionMatch(stringArgument(arguments, 0), stringArgument(arguments, 1))
+	}))
+	define("isResolvable", stringArguments(func(arguments []string) value {
+		return script.resolve(stringArgument(arguments, 0)) != nil
+	}))
+	define("dnsResolve", stringArguments(func(arguments []string) value {
+		if ip := script.resolve(stringArgument(arguments, 0)); ip != nil {
+			return ip.String()
+		}
+		return nil
+	}))
+	define("isInNet", stringArguments(func(arguments []string) value {
+		ip := script.resolve(stringArgument(arguments, 0)).To4()
+		pattern := net.ParseIP(stringArgument(arguments, 1)).To4()
+		mask := net.ParseIP(stringArgument(arguments, 2)).To4()
+		if ip == nil || pattern == nil || mask == nil {
+			return false
+		}
+		return ip.Mask(net.IPMask(mask)).Equal(pattern.Mask(net.IPMask(mask)))
+	}))
+	define("myIpAddress", func(arguments []value) (value, error) {
+		return script.myIPAddress(), nil
+	})
+	define("convert_addr", stringArguments(func(arguments []string) value {
+		ip := net.ParseIP(stringArgument(arguments, 0)).To4()
+		if ip == nil {
+			return float64(0)
+		}
+		return float64(uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3]))
+	}))
+	define("weekdayRange", func(arguments []value) (value, error) {
+		now, arguments := script.timeArguments(arguments)
+		return weekdayRange(now, arguments)
+	})
+	define("dateRange", func(arguments []value) (value, error) {
+		now, arguments := script.timeArguments(arguments)
+		return dateRange(now, arguments)
+	})
+	define("timeRange", func(arguments []value) (value, error) {
+		now, arguments := script.timeArguments(arguments)
+		return timeRange(now, arguments)
+	})
+	define("alert", func(arguments []value) (value, error) {
+		log.Printf("proxy auto-config: %s", toString(argument(arguments, 0)))
+		return undefined, nil
+	})
+	define("Date", func(arguments []value) (value, error) {
+		return &dateValue{time: script.now()}, nil
+	})
+	define("String", func(arguments []value) (value, error) {
+		return toString(argument(arguments, 0)), nil
+	})
+	define("Number", func(arguments []value) (value, error) {
+		return toNumber(argument(arguments, 0)), nil
+	})
+	define("isNaN", func(arguments []value) (value, error) {
+		return math.IsNaN(toNumber(argument(arguments, 0))), nil
+	})
+	define("parseInt", func(arguments []value) (value, error) {
+		return parseInteger(toString(argument(arguments, 0)), toInteger(argument(arguments, 1))), nil
+	})
+	define("parseFloat", func(arguments []value) (value, error) {
+		s := strings.TrimSpace(toString(argument(arguments, 0)))
+		for end := len(s); end > 0; end-- {
+			if number, err := strconv.ParseFloat(s[:end], 64); err == nil {
+				return number, nil
+			}
+		}
+		return math.NaN(), nil
+	})
+}
+
+func stringArgument(arguments []string, index int) string {
+	if index < len(arguments) {
+		return arguments[index]
+	}
+	return "undefined"
+}
+
+// resolve returns IP of host preferring IPv4 or nil if host can't be resolved
+func (script *Script) resolve(host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+	ips, err := script.lookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return nil
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip
+		}
+	}
+	return ips[0]
+}
+
+// timeArguments returns the current time, in UTC if the last argument is "GMT", and the rest of arguments
+func (script *Script) timeArguments(arguments []value) (time.Time, []value) {
+	now := script.now()
+	if len(arguments) > 0 && toString(arguments[len(arguments)-1]) == "GMT" {
+		return now.UTC(), arguments[:len(arguments)-1]
+	}
+	return now, arguments
+}
+
+// shellExpressionMatch matches s against a shell pattern where * matches any sequence of characters and ? a single character
+func shellExpressionMatch(s, pattern string) bool {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+	matched, err := regexp.MatchString(builder.String(), s)
+	return err == nil && matched
+}
+
+func parseInteger(s string, radix int) value {
+	s = strings.TrimSpace(s)
+	sign := 1.0
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+	if (radix == 0 || radix == 16) && (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")) {
+		s, radix = s[2:], 16
+	}
+	if radix == 0 {
+		radix = 10
+	}
+	if radix < 2 || radix > 36 {
+		return math.NaN()
+	}
+	end := 0
+	for end < len(s) {
+		digit, err := strconv.ParseInt(s[end:end+1], radix, 64)
+		if err != nil || digit >= int64(radix) {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		return math.NaN()
+	}
+	number, err := strconv.ParseInt(s[:end], radix, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return sign * float64(number)
+}
+
+// inRange reports whether current is between start and end inclusive, ranges with start after end wrap around
+func inRange(current, start, end int) bool {
+	if start <= end {
+		return start <= current && current <= end
+	}
+	return current >= start || current <= end
+}
+
+func indexOf(names []string, name string) int {
+	for i, candidate := range names {
+		if candidate == strings.ToUpper(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// weekdayRange implements weekdayRange(wd1 [, wd2])
+func weekdayRange(now time.Time, arguments []value) (value, error) {
+	if len(arguments) == 0 || len(arguments) > 2 {
+		return nil, errors.New("invalid number of arguments")
+	}
+	start := indexOf(weekdays, toString(arguments[0]))
+	end := start
+	if len(arguments) == 2 {
+		end = indexOf(weekdays, toString(arguments[1]))
+	}
+	if start == -1 || end == -1 {
+		return nil, errors.New("invalid weekday")
+	}
+	return inRange(int(now.Weekday()), start, end), nil
+}
+
+// dateRange implements dateRange with days, months and years like dateRange(1, "JAN", 2020, 15, "FEB", 2020)
+func dateRange(now time.Time, arguments []value) (value, error) {
+	if len(arguments) == 1 {
+		arguments = append(arguments, arguments[0])
+	}
+	if len(arguments)%2 != 0 || len(arguments) > 6 {
+		return nil, errors.New("invalid number of arguments")
+	}
+	half := len(arguments) / 2
+	start, startFields, err := dateKey(arguments[:half])
+	if err != nil {
+		return nil, err
+	}
+	end, endFields, err := dateKey(arguments[half:])
+	if err != nil {
+		return nil, err
+	}
+	if startFields != endFields {
+		return nil, errors.New("range bounds have different fields")
+	}
+	current := 0
+	if strings.Contains(startFields, "y") {
+		current += now.Year() * 10000
+	}
+	if strings.Contains(startFields, "m") {
+		current += int(now.Month()) * 100
+	}
+	if strings.Contains(startFields, "d") {
+		current += now.Day()
+	}
+	return inRange(current, start, end), nil
+}
+
+// dateKey converts a date like 1, "JAN", 2020 to a comparable number and fields it consists of sorted as "ymd"
+func dateKey(arguments []value) (int, string, error) {
+	key := 0
+	var year, month, day bool
+	for _, argument := range arguments {
+		if name, ok := argument.(string); ok {
+			index := indexOf(months, name)
+			if index == -1 || month {
+				return 0, "", errors.Errorf("invalid month %q", name)
+			}
+			key += (index + 1) * 100
+			month = true
+			continue
+		}
+		number := toInteger(argument)
+		switch {
+		case number >= 1 && number <= 31 && !day:
+			key += number
+			day = true
+		case number > 31 && !year:
+			key += number * 10000
+			year = true
+		default:
+			return 0, "", errors.Errorf("invalid date %s", toString(argument))
+		}
+	}
+	fields := ""
+	if year {
+		fields += "y"
+	}
+	if month {
+		fields += "m"
+	}
+	if day {
+		fields += "d"
+	}
+	return key, fields, nil
+}
+
+// timeRange implements timeRange(hour), timeRange(h1, h2), timeRange(h1, m1, h2, m2) and timeRange(h1, m1, s1, h2, m2, s2)
+func timeRange(now time.Time, arguments []value) (value, error) {
+	numbers := make([]int, len(arguments))
+	for i, argument := range arguments {
+		numbers[i] = toInteger(argument)
+	}
+	current := now.Hour()*3600 + now.Minute()*60 + now.Second()
+	switch len(numbers) {
+	case 1:
+		return now.Hour() == numbers[0], nil
+	case 2:
+		return inRange(now.Hour(), numbers[0], numbers[1]), nil
+	case 4:
+		return inRange(current, numbers[0]*3600+numbers[1]*60, numbers[2]*3600+numbers[3]*60+59), nil
+	case 6:
+		return inRange(current, numbers[0]*3600+numbers[1]*60+numbers[2], numbers[3]*3600+numbers[4]*60+numbers[5]), nil
+	}
+	return nil, errors.New("invalid number of arguments")
+}
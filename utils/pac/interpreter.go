@@ -0,0 +1,511 @@
+package pac
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// maxSteps limits the number of evaluated nodes per call, so scripts with infinite loops are stopped
+const maxSteps = 1000000
+
+// maxCallDepth limits recursion of script functions
+const maxCallDepth = 200
+
+type scope struct {
+	variables map[string]value
+	parent    *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{variables: make(map[string]value), parent: parent}
+}
+
+func (scope *scope) lookup(name string) (*scope, bool) {
+	for current := scope; current != nil; current = current.parent {
+		if _, ok := current.variables[name]; ok {
+			return current, true
+		}
+	}
+	return nil, false
+}
+
+// completion is the way a statement finished
+type completion int
+
+const (
+	normalCompletion completion = iota
+	returnCompletion
+	breakCompletion
+	continueCompletion
+)
+
+type interpreter struct {
+	steps int
+	depth int
+}
+
+func (interpreter *interpreter) step() error {
+	interpreter.steps++
+	if interpreter.steps > maxSteps {
+		return errors.New("script takes too long")
+	}
+	return nil
+}
+
+// hoist declares functions and variables of body in scope before the body is executed
+func hoist(body []node, scope *scope) {
+	for _, statement := range body {
+		hoistStatement(statement, scope)
+	}
+}
+
+func hoistStatement(statement node, scope *scope) {
+	switch statement := statement.(type) {
+	case *functionDeclaration:
+		scope.variables[statement.function.name] = &function{literal: statement.function, scope: scope}
+	case *variableDeclaration:
+		for _, name := range statement.names {
+			if _, ok := scope.variables[name]; !ok {
+				scope.variables[name] = undefined
+			}
+		}
+	case *blockStatement:
+		hoist(statement.body, scope)
+	case *ifStatement:
+		hoistStatement(statement.consequent, scope)
+		if statement.alternate != nil {
+			hoistStatement(statement.alternate, scope)
+		}
+	case *whileStatement:
+		hoistStatement(statement.body, scope)
+	case *forStatement:
+		if statement.init != nil {
+			hoistStatement(statement.init, scope)
+		}
+		hoistStatement(statement.body, scope)
+	}
+}
+
+func (interpreter *interpreter) executeBody(body []node, scope *scope) (completion, value, error) {
+	for _, statement := range body {
+		completion, result, err := interpreter.execute(statement, scope)
+		if err != nil || completion != normalCompletion {
+			return completion, result, err
+		}
+	}
+	return normalCompletion, undefined, nil
+}
+
+func (interpreter *interpreter) execute(statement node, scope *scope) (completion, value, error) {
+	if err := interpreter.step(); err != nil {
+		return normalCompletion, nil, err
+	}
+	switch statement := statement.(type) {
+	case *emptyStatement, *functionDeclaration:
+		// functions are declared by hoist
+	case *expressionStatement:
+		_, err := interpreter.evaluate(statement.expression, scope)
+		return normalCompletion, undefined, err
+	case *variableDeclaration:
+		return normalCompletion, undefined, interpreter.declare(statement, scope)
+	case *blockStatement:
+		return interpreter.executeBody(statement.body, scope)
+	case *ifStatement:
+		condition, err := interpreter.evaluate(statement.condition, scope)
+		if err != nil {
+			return normalCompletion, nil, err
+		}
+		if toBoolean(condition) {
+			return interpreter.execute(statement.consequent, scope)
+		}
+		if statement.alternate != nil {
+			return interpreter.execute(statement.alternate, scope)
+		}
+	case *whileStatement:
+		for first := true; ; first = false {
+			if !(first && statement.doWhile) {
+				condition, err := interpreter.evaluate(statement.condition, scope)
+				if err != nil {
+					return normalCompletion, nil, err
+				}
+				if !toBoolean(condition) {
+					break
+				}
+			}
+			completion, result, err := interpreter.execute(statement.body, scope)
+			if err != nil || completion == returnCompletion {
+				return completion, result, err
+			}
+			if completion == breakCompletion {
+				break
+			}
+		}
+	case *forStatement:
+		if statement.init != nil {
+			if declaration, ok := statement.init.(*variableDeclaration); ok {
+				if err := interpreter.declare(declaration, scope); err != nil {
+					return normalCompletion, nil, err
+				}
+			} else if _, err := interpreter.evaluate(statement.init, scope); err != nil {
+				return normalCompletion, nil, err
+			}
+		}
+		for {
+			if statement.condition != nil {
+				condition, err := interpreter.evaluate(statement.condition, scope)
+				if err != nil {
+					return normalCompletion, nil, err
+				}
+				if !toBoolean(condition) {
+					break
+				}
+			}
+			completion, result, err := interpreter.execute(statement.body, scope)
+			if err != nil || completion == returnCompletion {
+				return completion, result, err
+			}
+			if completion == breakCompletion {
+				break
+			}
+			if statement.update != nil {
+				if _, err := interpreter.evaluate(statement.update, scope); err != nil {
+					return normalCompletion, nil, err
+				}
+			}
+		}
+	case *returnStatement:
+		if statement.value == nil {
+			return returnCompletion, undefined, nil
+		}
+		result, err := interpreter.evaluate(statement.value, scope)
+		return returnCompletion, result, err
+	case *breakStatement:
+		return breakCompletion, undefined, nil
+	case *continueStatement:
+		return continueCompletion, undefined, nil
+	default:
+		return normalCompletion, nil, errors.Errorf("unsupported statement %T", statement)
+	}
+	return normalCompletion, undefined, nil
+}
+
+func (interpreter *interpreter) declare(declaration *variableDeclaration, scope *scope) error {
+	for i, name := range declaration.names {
+		if declaration.values[i] == nil {
+			if _, ok := scope.variables[name]; !ok {
+				scope.variables[name] = undefined
+			}
+			continue
+		}
+		result, err := interpreter.evaluate(declaration.values[i], scope)
+		if err != nil {
+			return err
+		}
+		scope.variables[name] = result
+	}
+	return nil
+}
+
+func (interpreter *interpreter) evaluate(expression node, scope *scope) (value, error) {
+	if err := interpreter.step(); err != nil {
+		return nil, err
+	}
+	switch expression := expression.(type) {
+	case *literal:
+		return expression.value, nil
+	case *regexpLiteral:
+		return expression.regexp, nil
+	case *identifier:
+		if expression.name == "undefined" {
+			return undefined, nil
+		}
+		owner, ok := scope.lookup(expression.name)
+		if !ok {
+			return nil, errors.Errorf("%s is not defined", expression.name)
+		}
+		return owner.variables[expression.name], nil
+	case *arrayLiteral:
+		result := &array{}
+		for _, element := range expression.elements {
+			value, err := interpreter.evaluate(element, scope)
+			if err != nil {
+				return nil, err
+			}
+			result.elements = append(result.elements, value)
+		}
+		return result, nil
+	case *objectLiteral:
+		result := &object{properties: make(map[string]value)}
+		for i, key := range expression.keys {
+			value, err := interpreter.evaluate(expression.values[i], scope)
+			if err != nil {
+				return nil, err
+			}
+			result.properties[key] = value
+		}
+		return result, nil
+	case *functionLiteral:
+		return &function{literal: expression, scope: scope}, nil
+	case *sequenceExpression:
+		var result value = undefined
+		for _, item := range expression.expressions {
+			var err error
+			if result, err = interpreter.evaluate(item, scope); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case *unaryExpression:
+		if expression.operator == "typeof" {
+			if identifier, ok := expression.operand.(*identifier); ok {
+				if _, ok := scope.lookup(identifier.name); !ok {
+					return "undefined", nil
+				}
+			}
+		}
+		operand, err := interpreter.evaluate(expression.operand, scope)
+		if err != nil {
+			return nil, err
+		}
+		switch expression.operator {
+		case "!":
+			return !toBoolean(operand), nil
+		case "-":
+			return -toNumber(operand), nil
+		case "+":
+			return toNumber(operand), nil
+		case "typeof":
+			return typeOf(operand), nil
+		}
+	case *updateExpression:
+		old, err := interpreter.evaluate(expression.target, scope)
+		if err != nil {
+			return nil, err
+		}
+		oldNumber := toNumber(old)
+		newNumber := oldNumber + 1
+		if expression.operator == "--" {
+			newNumber = oldNumber - 1
+		}
+		if err := interpreter.assign(expression.target, newNumber, scope); err != nil {
+			return nil, err
+		}
+		if expression.prefix {
+			return newNumber, nil
+		}
+		return oldNumber, nil
+	case *binaryExpression:
+		left, err := interpreter.evaluate(expression.left, scope)
+		if err != nil {
+			return nil, err
+		}
+		right, err := interpreter.evaluate(expression.right, scope)
+		if err != nil {
+			return nil, err
+		}
+		return binaryOperation(expression.operator, left, right)
+	case *logicalExpression:
+		left, err := interpreter.evaluate(expression.left, scope)
+		if err != nil {
+			return nil, err
+		}
+		if toBoolean(left) == (expression.operator == "||") {
+			return left, nil
+		}
+		return interpreter.evaluate(expression.right, scope)
+	case *conditionalExpression:
+		condition, err := interpreter.evaluate(expression.condition, scope)
+		if err != nil {
+			return nil, err
+		}
+		if toBoolean(condition) {
+			return interpreter.evaluate(expression.consequent, scope)
+		}
+		return interpreter.evaluate(expression.alternate, scope)
+	case *assignmentExpression:
+		result, err := interpreter.evaluate(expression.value, scope)
+		if err != nil {
+			return nil, err
+		}
+		if expression.operator != "=" {
+			old, err := interpreter.evaluate(expression.target, scope)
+			if err != nil {
+				return nil, err
+			}
+			if result, err = binaryOperation(expression.operator[:1], old, result); err != nil {
+				return nil, err
+			}
+		}
+		return result, interpreter.assign(expression.target, result, scope)
+	case *memberExpression:
+		object, name, err := interpreter.evaluateMember(expression, scope)
+		if err != nil {
+			return nil, err
+		}
+		return getProperty(object, name)
+	case *callExpression:
+		callee, err := interpreter.evaluate(expression.callee, scope)
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := interpreter.evaluateArguments(expression.arguments, scope)
+		if err != nil {
+			return nil, err
+		}
+		result, err := interpreter.call(callee, arguments)
+		if err != nil {
+			if identifier, ok := expression.callee.(*identifier); ok {
+				return nil, errors.Wrap(err, identifier.name)
+			}
+		}
+		return result, err
+	case *newExpression:
+		callee, err := interpreter.evaluate(expression.callee, scope)
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := interpreter.evaluateArguments(expression.arguments, scope)
+		if err != nil {
+			return nil, err
+		}
+		constructor, ok := callee.(*builtin)
+		if !ok || constructor.name != "Date" || len(arguments) > 0 {
+			return nil, errors.New("only new Date() is supported")
+		}
+		return constructor.call(nil)
+	}
+	return nil, errors.Errorf("unsupported expression %T", expression)
+}
+
+func (interpreter *interpreter) evaluateArguments(expressions []node, scope *scope) ([]value, error) {
+	arguments := make([]value, len(expressions))
+	for i, expression := range expressions {
+		var err error
+		if arguments[i], err = interpreter.evaluate(expression, scope); err != nil {
+			return nil, err
+		}
+	}
+	return arguments, nil
+}
+
+func (interpreter *interpreter) evaluateMember(expression *memberExpression, scope *scope) (value, string, error) {
+	object, err := interpreter.evaluate(expression.object, scope)
+	if err != nil {
+		return nil, "", err
+	}
+	property, err := interpreter.evaluate(expression.property, scope)
+	if err != nil {
+		return nil, "", err
+	}
+	return object, toString(property), nil
+}
+
+func (interpreter *interpreter) assign(target node, result value, scope *scope) error {
+	switch target := target.(type) {
+	case *identifier:
+		owner, ok := scope.lookup(target.name)
+		if !ok {
+			// assignment to an undeclared variable creates a global one
+			owner = scope
+			for owner.parent != nil {
+				owner = owner.parent
+			}
+		}
+		owner.variables[target.name] = result
+		return nil
+	case *memberExpression:
+		object, name, err := interpreter.evaluateMember(target, scope)
+		if err != nil {
+			return err
+		}
+		return setProperty(object, name, result)
+	}
+	return errors.New("invalid assignment target")
+}
+
+func (interpreter *interpreter) call(callee value, arguments []value) (value, error) {
+	switch callee := callee.(type) {
+	case *builtin:
+		return callee.call(arguments)
+	case *function:
+		if interpreter.depth >= maxCallDepth {
+			return nil, errors.New("maximum call depth exceeded")
+		}
+		interpreter.depth++
+		defer func() { interpreter.depth-- }()
+		functionScope := newScope(callee.scope)
+		for i, param := range callee.literal.params {
+			functionScope.variables[param] = argument(arguments, i)
+		}
+		hoist(callee.literal.body, functionScope)
+		completion, result, err := interpreter.executeBody(callee.literal.body, functionScope)
+		if err != nil {
+			return nil, err
+		}
+		if completion != returnCompletion {
+			return undefined, nil
+		}
+		return result, nil
+	}
+	return nil, errors.Errorf("%s is not a function", typeOf(callee))
+}
+
+func binaryOperation(operator string, left, right value) (value, error) {
+	switch operator {
+	case "==":
+		return looseEquals(left, right), nil
+	case "!=":
+		return !looseEquals(left, right), nil
+	case "===":
+		return strictEquals(left, right), nil
+	case "!==":
+		return !strictEquals(left, right), nil
+	case "+":
+		if !isPrimitive(left) {
+			left = toString(left)
+		}
+		if !isPrimitive(right) {
+			right = toString(right)
+		}
+		_, leftIsString := left.(string)
+		_, rightIsString := right.(string)
+		if leftIsString || rightIsString {
+			return concatStrings(toString(left), toString(right))
+		}
+		return toNumber(left) + toNumber(right), nil
+	case "-":
+		return toNumber(left) - toNumber(right), nil
+	case "*":
+		return toNumber(left) * toNumber(right), nil
+	case "/":
+		return toNumber(left) / toNumber(right), nil
+	case "%":
+		return math.Mod(toNumber(left), toNumber(right)), nil
+	case "<", ">", "<=", ">=":
+		leftString, leftIsString := left.(string)
+		rightString, rightIsString := right.(string)
+		if leftIsString && rightIsString {
+			switch operator {
+			case "<":
+				return leftString < rightString, nil
+			case ">":
+				return leftString > rightString, nil
+			case "<=":
+				return leftString <= rightString, nil
+			}
+			return leftString >= rightString, nil
+		}
+		leftNumber, rightNumber := toNumber(left), toNumber(right)
+		switch operator {
+		case "<":
+			return leftNumber < rightNumber, nil
+		case ">":
+			return leftNumber > rightNumber, nil
+		case "<=":
+			return leftNumber <= rightNumber, nil
+		}
+		return leftNumber >= rightNumber, nil
+	}
+	return undefined, nil
+}
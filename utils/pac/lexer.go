@@ -0,0 +1,238 @@
+package pac
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdentifier
+	tokenPunctuator
+	tokenRegexp
+)
+
+type token struct {
+	kind   tokenKind
+	text   string  // Identifier name, punctuator, string value or regular expression pattern
+	number float64 // Value of number token
+	flags  string  // Flags of regular expression
+	line   int
+}
+
+// punctuators are sorted so longer ones are matched first
+var punctuators = []string{
+	"===", "!==",
+	"==", "!=", "<=", ">=", "&&", "||", "+=", "-=", "++", "--",
+	"(", ")", "{", "}", "[", "]", ";", ",", ".", "?", ":", "=", "<", ">", "+", "-", "*", "/", "%", "!",
+}
+
+// tokenize splits JavaScript source into tokens, comments and whitespace are skipped
+func tokenize(source string) ([]*token, error) {
+	var tokens []*token
+	line := 1
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(source[i:], "//"):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end == -1 {
+				return nil, errors.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(source[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			value, length, err := scanString(source[i:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			tokens = append(tokens, &token{kind: tokenString, text: value, line: line})
+			i += length
+		case isDigit(c) || (c == '.' && i+1 < len(source) && isDigit(source[i+1])):
+			start := i
+			if strings.HasPrefix(source[i:], "0x") || strings.HasPrefix(source[i:], "0X") {
+				i += 2
+				for i < len(source) && strings.IndexByte("0123456789abcdefABCDEF", source[i]) != -1 {
+					i++
+				}
+				value, err := strconv.ParseInt(source[start+2:i], 16, 64)
+				if err != nil {
+					return nil, errors.Errorf("line %d: invalid number %s", line, source[start:i])
+				}
+				tokens = append(tokens, &token{kind: tokenNumber, number: float64(value), text: source[start:i], line: line})
+				continue
+			}
+			for i < len(source) && (isDigit(source[i]) || source[i] == '.') {
+				i++
+			}
+			if i < len(source) && (source[i] == 'e' || source[i] == 'E') {
+				i++
+				if i < len(source) && (source[i] == '+' || source[i] == '-') {
+					i++
+				}
+				for i < len(source) && isDigit(source[i]) {
+					i++
+				}
+			}
+			value, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, errors.Errorf("line %d: invalid number %s", line, source[start:i])
+			}
+			tokens = append(tokens, &token{kind: tokenNumber, number: value, text: source[start:i], line: line})
+		case c == '/' && !endsOperand(tokens):
+			pattern, flags, length, err := scanRegexp(source[i:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			tokens = append(tokens, &token{kind: tokenRegexp, text: pattern, flags: flags, line: line})
+			i += length
+		case isIdentifierStart(rune(c)):
+			start := i
+			for i < len(source) && isIdentifierPart(rune(source[i])) {
+				i++
+			}
+			tokens = append(tokens, &token{kind: tokenIdentifier, text: source[start:i], line: line})
+		default:
+			matched := false
+			for _, punctuator := range punctuators {
+				if strings.HasPrefix(source[i:], punctuator) {
+					tokens = append(tokens, &token{kind: tokenPunctuator, text: punctuator, line: line})
+					i += len(punctuator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, errors.Errorf("line %d: unexpected character %q", line, c)
+			}
+		}
+	}
+	tokens = append(tokens, &token{kind: tokenEOF, line: line})
+	return tokens, nil
+}
+
+// scanString returns value of a quoted string at the beginning of source and its length in source
+func scanString(source string) (string, int, error) {
+	quote := source[0]
+	var builder strings.Builder
+	for i := 1; i < len(source); i++ {
+		c := source[i]
+		switch {
+		case c == quote:
+			return builder.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, errors.New("unterminated string")
+		case c == '\\' && i+1 < len(source):
+			i++
+			switch source[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case 'r':
+				builder.WriteByte('\r')
+			case 'b':
+				builder.WriteByte('\b')
+			case 'f':
+				builder.WriteByte('\f')
+			case 'v':
+				builder.WriteByte('\v')
+			case '0':
+				builder.WriteByte(0)
+			case 'x', 'u':
+				length := 2
+				if source[i] == 'u' {
+					length = 4
+				}
+				if i+length >= len(source) {
+					return "", 0, errors.New("invalid escape sequence")
+				}
+				code, err := strconv.ParseUint(source[i+1:i+1+length], 16, 32)
+				if err != nil {
+					return "", 0, errors.New("invalid escape sequence")
+				}
+				builder.WriteRune(rune(code))
+				i += length
+			case '\n':
+				// line continuation
+			default:
+				builder.WriteByte(source[i])
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+// endsOperand reports whether the last token can end an operand, so '/' after it is division
+func endsOperand(tokens []*token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	switch last.kind {
+	case tokenNumber, tokenString, tokenRegexp:
+		return true
+	case tokenIdentifier:
+		return last.text != "return" && last.text != "typeof"
+	case tokenPunctuator:
+		return last.text == ")" || last.text == "]" || last.text == "}" || last.text == "++" || last.text == "--"
+	}
+	return false
+}
+
+// scanRegexp returns pattern and flags of a regular expression literal at the beginning of source and its length
+func scanRegexp(source string) (string, string, int, error) {
+	inClass := false
+	for i := 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return "", "", 0, errors.New("unterminated regular expression")
+		case '/':
+			if inClass {
+				continue
+			}
+			end := i + 1
+			for end < len(source) && isIdentifierPart(rune(source[end])) {
+				end++
+			}
+			return source[1:i], source[i+1 : end], end, nil
+		}
+	}
+	return "", "", 0, errors.New("unterminated regular expression")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r)
+}
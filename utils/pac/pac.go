@@ -0,0 +1,111 @@
+// Package pac evaluates proxy auto-config files.
+// It implements the subset of JavaScript PAC files are usually written in
+// and the standard functions like isInNet, shExpMatch and dnsDomainIs.
+package pac
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Script is a parsed proxy auto-config file
+type Script struct {
+	mutex   sync.Mutex // Scripts can change their global variables, so calls are serialized
+	globals *scope
+
+	lookupIP    func(host string) ([]net.IP, error)
+	myIPAddress func() string
+	now         func() time.Time
+}
+
+// Parse parses source of a proxy auto-config file and runs its top level statements
+func Parse(source string) (*Script, error) {
+	program, err := parse(source)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse proxy auto-config")
+	}
+	script := &Script{lookupIP: net.LookupIP, myIPAddress: localIPAddress, now: time.Now}
+	script.globals = newScope(nil)
+	script.defineBuiltins(script.globals)
+	hoist(program, script.globals)
+	if _, _, err := (&interpreter{}).executeBody(program, script.globals); err != nil {
+		return nil, errors.Wrap(err, "unable to run proxy auto-config")
+	}
+	if _, ok := script.globals.variables["FindProxyForURL"].(*function); !ok {
+		return nil, errors.New("proxy auto-config doesn't define FindProxyForURL function")
+	}
+	return script, nil
+}
+
+// FindProxyForURL calls FindProxyForURL function of the script and returns its result like "PROXY proxy:8080; DIRECT"
+func (script *Script) FindProxyForURL(rawurl, host string) (string, error) {
+	script.mutex.Lock()
+	defer script.mutex.Unlock()
+	result, err := (&interpreter{}).call(script.globals.variables["FindProxyForURL"], []value{rawurl, host})
+	if err != nil {
+		return "", errors.Wrapf(err, "FindProxyForURL(%q, %q) failed", rawurl, host)
+	}
+	resultString, ok := result.(string)
+	if !ok {
+		return "", errors.Errorf("FindProxyForURL(%q, %q) returned %s instead of string", rawurl, host, toString(result))
+	}
+	return resultString, nil
+}
+
+// Proxy is an entry of FindProxyForURL result
+type Proxy struct {
+	Type    string // DIRECT, PROXY, HTTP, HTTPS, SOCKS, SOCKS4 or SOCKS5
+	Address string // host:port of the proxy, empty for DIRECT
+}
+
+// ParseResult parses result of FindProxyForURL like "PROXY proxy:8080; DIRECT"
+func ParseResult(result string) ([]*Proxy, error) {
+	var proxies []*Proxy
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		proxy := &Proxy{Type: strings.ToUpper(fields[0])}
+		switch proxy.Type {
+		case "DIRECT":
+			if len(fields) != 1 {
+				return nil, errors.Errorf("invalid proxy %q", strings.TrimSpace(entry))
+			}
+		case "PROXY", "HTTP", "HTTPS", "SOCKS", "SOCKS4", "SOCKS5":
+			if len(fields) != 2 {
+				return nil, errors.Errorf("invalid proxy %q", strings.TrimSpace(entry))
+			}
+			proxy.Address = fields[1]
+		default:
+			return nil, errors.Errorf("unknown proxy type %q", fields[0])
+		}
+		proxies = append(proxies, proxy)
+	}
+	if len(proxies) == 0 {
+		return nil, errors.Errorf("no proxies in %q", result)
+	}
+	return proxies, nil
+}
+
+// localIPAddress returns the address used for outgoing connections, no packets are sent to determine it
+func localIPAddress() string {
+	if conn, err := net.Dial("udp", "198.51.100.1:80"); err == nil {
+		defer conn.Close()
+		if address, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+			return address.IP.String()
+		}
+	}
+	if addresses, err := net.InterfaceAddrs(); err == nil {
+		for _, address := range addresses {
+			if ipNet, ok := address.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+				return ipNet.IP.String()
+			}
+		}
+	}
+	return "127.0.0.1"
+}
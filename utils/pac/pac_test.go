@@ -0,0 +1,179 @@
+package pac
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const testScript = `
+// comments and helper functions are allowed
+var proxy = "PROXY proxy.example.com:8080";
+var bypass = ["localhost", "*.local"];
+
+function isBypassed(host) {
+	for (var i = 0; i < bypass.length; i++) {
+		if (shExpMatch(host, bypass[i])) return true;
+	}
+	return false;
+}
+
+function FindProxyForURL(url, host) {
+	host = host.toLowerCase();
+	if (isPlainHostName(host) || isBypassed(host)) {
+		return "DIRECT";
+	}
+	if (dnsDomainIs(host, ".intranet.example.com") && isInNet(dnsResolve(host), "10.0.0.0", "255.0.0.0")) {
+		return "DIRECT";
+	}
+	if (/^ftp:/.test(url) || url.substring(0, 5) == "http:") {
+		return proxy + "; DIRECT";
+	}
+	if (weekdayRange("SAT", "SUN") || !timeRange(8, 17)) {
+		return "PROXY night.example.com:3128";
+	}
+	return host.indexOf("secure") === 0 ? "HTTPS secure-proxy.example.com:443" : proxy;
+}
+`
+
+func Test_FindProxyForURL(t *testing.T) {
+	script, err := Parse(testScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script.lookupIP = func(host string) ([]net.IP, error) {
+		if host == "app.intranet.example.com" {
+			return []net.IP{net.ParseIP("10.1.2.3")}, nil
+		}
+		return nil, errors.New("not found")
+	}
+	monday := time.Date(2020, time.March, 2, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		url  string
+		host string
+		now  time.Time
+		want string
+	}{
+		{"plain host name", "https://server/app.jnlp", "server", monday, "DIRECT"},
+		{"bypass list", "https://printer.local/app.jnlp", "PRINTER.local", monday, "DIRECT"},
+		{"intranet", "https://app.intranet.example.com/app.jnlp", "app.intranet.example.com", monday, "DIRECT"},
+		{"http", "http://www.example.com/app.jnlp", "www.example.com", monday, "PROXY proxy.example.com:8080; DIRECT"},
+		{"weekend", "https://www.example.com/app.jnlp", "www.example.com", monday.AddDate(0, 0, 5), "PROXY night.example.com:3128"},
+		{"night", "https://www.example.com/app.jnlp", "www.example.com", monday.Add(10 * time.Hour), "PROXY night.example.com:3128"},
+		{"conditional", "https://secure.example.com/app.jnlp", "secure.example.com", monday, "HTTPS secure-proxy.example.com:443"},
+		{"default", "https://www.example.com/app.jnlp", "www.example.com", monday, "PROXY proxy.example.com:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script.now = func() time.Time { return tt.now }
+			got, err := script.FindProxyForURL(tt.url, tt.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("FindProxyForURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{"valid", `function FindProxyForURL(url, host) { return "DIRECT" }`, false},
+		{"no function", `var x = 1;`, true},
+		{"syntax error", `function FindProxyForURL(url, host) { return "DIRECT"`, true},
+		{"unsupported regexp", `var re = /(?<=a)b/; function FindProxyForURL(url, host) { return "DIRECT" }`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_FindProxyForURL_infiniteLoop(t *testing.T) {
+	script, err := Parse(`function FindProxyForURL(url, host) { while (true) {} }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := script.FindProxyForURL("http://host/", "host"); err == nil {
+		t.Error("FindProxyForURL() expected error for infinite loop")
+	}
+}
+
+func Test_FindProxyForURL_strings(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{"replacements", `return "a-b-c".replace(/-/g, "[$&]") + "x.y".replace(".", ":");`, "a[-]b[-]cx:y", false},
+		{"cyclic array", `var a = [1, "b"]; a.push(a); return a.join("-") + "|" + [[1, 2], 3];`, "1-b-|1,2,3", false},
+		{"repeated addition", `var s = "x"; while (true) { s += s; } return s;`, "", true},
+		{"repeated concat", `var s = "x"; while (true) { s = s.concat(s, s); } return s;`, "", true},
+		{"repeated join", `var s = "x"; while (true) { s = [s, s].join(s); } return s;`, "", true},
+		{"repeated array to string", `var s = "x"; while (true) { s = [s, s] + ""; } return s;`, "", true},
+		{"repeated replace", `var s = "xx"; while (true) { s = s.replace(/x/g, "$&$&$&"); } return s;`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := Parse("function FindProxyForURL(url, host) { " + tt.body + " }")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := script.FindProxyForURL("http://host/", "host")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindProxyForURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "string is too long") {
+				t.Errorf("FindProxyForURL() error = %v, want string is too long", err)
+			}
+			if got != tt.want {
+				t.Errorf("FindProxyForURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		want    []Proxy
+		wantErr bool
+	}{
+		{"direct", "DIRECT", []Proxy{{Type: "DIRECT"}}, false},
+		{"fallback", "PROXY a:8080;  socks5 b:1080 ; DIRECT;", []Proxy{{"PROXY", "a:8080"}, {"SOCKS5", "b:1080"}, {Type: "DIRECT"}}, false},
+		{"missing address", "PROXY", nil, true},
+		{"unknown type", "FTP a:21", nil, true},
+		{"empty", " ", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResult(tt.result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseResult() returned %d proxies, want %d", len(got), len(tt.want))
+			}
+			for i, proxy := range got {
+				if *proxy != tt.want[i] {
+					t.Errorf("ParseResult()[%d] = %+v, want %+v", i, *proxy, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,806 @@
+package pac
+
+import (
+	"github.com/pkg/errors"
+)
+
+type node interface{}
+
+// Statements
+
+type functionDeclaration struct {
+	function *functionLiteral
+}
+
+type variableDeclaration struct {
+	names  []string
+	values []node // nil for declarations without initializer
+}
+
+type expressionStatement struct {
+	expression node
+}
+
+type blockStatement struct {
+	body []node
+}
+
+type ifStatement struct {
+	condition  node
+	consequent node
+	alternate  node
+}
+
+type whileStatement struct {
+	condition node
+	body      node
+	doWhile   bool
+}
+
+type forStatement struct {
+	init      node
+	condition node
+	update    node
+	body      node
+}
+
+type returnStatement struct {
+	value node
+}
+
+type breakStatement struct{}
+
+type continueStatement struct{}
+
+type emptyStatement struct{}
+
+// Expressions
+
+type literal struct {
+	value value
+}
+
+type regexpLiteral struct {
+	regexp *regexpValue
+}
+
+type identifier struct {
+	name string
+}
+
+type arrayLiteral struct {
+	elements []node
+}
+
+type objectLiteral struct {
+	keys   []string
+	values []node
+}
+
+type functionLiteral struct {
+	name   string
+	params []string
+	body   []node
+}
+
+type unaryExpression struct {
+	operator string
+	operand  node
+}
+
+type updateExpression struct {
+	operator string // ++ or --
+	prefix   bool
+	target   node
+}
+
+type binaryExpression struct {
+	operator    string
+	left, right node
+}
+
+type logicalExpression struct {
+	operator    string // && or ||
+	left, right node
+}
+
+type conditionalExpression struct {
+	condition, consequent, alternate node
+}
+
+type assignmentExpression struct {
+	operator string // =, += or -=
+	target   node
+	value    node
+}
+
+type memberExpression struct {
+	object   node
+	property node // *literal with string value for obj.name
+}
+
+type callExpression struct {
+	callee    node
+	arguments []node
+}
+
+type newExpression struct {
+	callee    node
+	arguments []node
+}
+
+type sequenceExpression struct {
+	expressions []node
+}
+
+// parser is a recursive descent parser of the JavaScript subset used in proxy auto-config files
+type parser struct {
+	tokens   []*token
+	position int
+}
+
+func parse(source string) ([]node, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	parser := &parser{tokens: tokens}
+	var program []node
+	for !parser.at(tokenEOF, "") {
+		statement, err := parser.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		program = append(program, statement)
+	}
+	return program, nil
+}
+
+func (parser *parser) peek() *token {
+	return parser.tokens[parser.position]
+}
+
+func (parser *parser) next() *token {
+	token := parser.tokens[parser.position]
+	if token.kind != tokenEOF {
+		parser.position++
+	}
+	return token
+}
+
+// at reports whether the current token has kind and text, empty text matches any token of kind
+func (parser *parser) at(kind tokenKind, text string) bool {
+	token := parser.peek()
+	return token.kind == kind && (text == "" || token.text == text)
+}
+
+func (parser *parser) atPunctuator(text string) bool {
+	return parser.at(tokenPunctuator, text)
+}
+
+func (parser *parser) atKeyword(text string) bool {
+	return parser.at(tokenIdentifier, text)
+}
+
+func (parser *parser) accept(text string) bool {
+	if parser.atPunctuator(text) {
+		parser.next()
+		return true
+	}
+	return false
+}
+
+func (parser *parser) expect(text string) error {
+	if !parser.accept(text) {
+		return parser.unexpected()
+	}
+	return nil
+}
+
+func (parser *parser) expectIdentifier() (string, error) {
+	if !parser.at(tokenIdentifier, "") || isReserved(parser.peek().text) {
+		return "", parser.unexpected()
+	}
+	return parser.next().text, nil
+}
+
+func (parser *parser) unexpected() error {
+	token := parser.peek()
+	switch token.kind {
+	case tokenEOF:
+		return errors.Errorf("line %d: unexpected end of script", token.line)
+	case tokenString:
+		return errors.Errorf("line %d: unexpected string %q", token.line, token.text)
+	}
+	return errors.Errorf("line %d: unexpected token %s", token.line, token.text)
+}
+
+// consumeSemicolon skips an optional semicolon, automatic semicolon insertion is approximated by making it optional
+func (parser *parser) consumeSemicolon() {
+	parser.accept(";")
+}
+
+func isReserved(name string) bool {
+	switch name {
+	case "var", "let", "const", "function", "if", "else", "while", "do", "for", "return", "break", "continue",
+		"true", "false", "null", "typeof", "new", "in":
+		return true
+	}
+	return false
+}
+
+func (parser *parser) parseStatement() (node, error) {
+	switch {
+	case parser.atPunctuator("{"):
+		return parser.parseBlock()
+	case parser.atPunctuator(";"):
+		parser.next()
+		return &emptyStatement{}, nil
+	case parser.atKeyword("function"):
+		parser.next()
+		function, err := parser.parseFunction(true)
+		if err != nil {
+			return nil, err
+		}
+		return &functionDeclaration{function: function}, nil
+	case parser.atKeyword("var"), parser.atKeyword("let"), parser.atKeyword("const"):
+		declaration, err := parser.parseVariableDeclaration()
+		if err != nil {
+			return nil, err
+		}
+		parser.consumeSemicolon()
+		return declaration, nil
+	case parser.atKeyword("if"):
+		return parser.parseIf()
+	case parser.atKeyword("while"):
+		parser.next()
+		condition, err := parser.parseParenthesized()
+		if err != nil {
+			return nil, err
+		}
+		body, err := parser.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		return &whileStatement{condition: condition, body: body}, nil
+	case parser.atKeyword("do"):
+		parser.next()
+		body, err := parser.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.atKeyword("while") {
+			return nil, parser.unexpected()
+		}
+		parser.next()
+		condition, err := parser.parseParenthesized()
+		if err != nil {
+			return nil, err
+		}
+		parser.consumeSemicolon()
+		return &whileStatement{condition: condition, body: body, doWhile: true}, nil
+	case parser.atKeyword("for"):
+		return parser.parseFor()
+	case parser.atKeyword("return"):
+		token := parser.next()
+		statement := &returnStatement{}
+		if !parser.atPunctuator(";") && !parser.atPunctuator("}") && !parser.at(tokenEOF, "") && parser.peek().line == token.line {
+			value, err := parser.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			statement.value = value
+		}
+		parser.consumeSemicolon()
+		return statement, nil
+	case parser.atKeyword("break"):
+		parser.next()
+		parser.consumeSemicolon()
+		return &breakStatement{}, nil
+	case parser.atKeyword("continue"):
+		parser.next()
+		parser.consumeSemicolon()
+		return &continueStatement{}, nil
+	}
+	expression, err := parser.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	parser.consumeSemicolon()
+	return &expressionStatement{expression: expression}, nil
+}
+
+func (parser *parser) parseBlock() (*blockStatement, error) {
+	if err := parser.expect("{"); err != nil {
+		return nil, err
+	}
+	block := &blockStatement{}
+	for !parser.accept("}") {
+		if parser.at(tokenEOF, "") {
+			return nil, parser.unexpected()
+		}
+		statement, err := parser.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		block.body = append(block.body, statement)
+	}
+	return block, nil
+}
+
+// parseFunction parses parameters and body of a function after the function keyword
+func (parser *parser) parseFunction(requireName bool) (*functionLiteral, error) {
+	function := &functionLiteral{}
+	if requireName || parser.at(tokenIdentifier, "") {
+		name, err := parser.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		function.name = name
+	}
+	if err := parser.expect("("); err != nil {
+		return nil, err
+	}
+	for !parser.accept(")") {
+		if len(function.params) > 0 {
+			if err := parser.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		param, err := parser.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		function.params = append(function.params, param)
+	}
+	body, err := parser.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	function.body = body.body
+	return function, nil
+}
+
+func (parser *parser) parseVariableDeclaration() (*variableDeclaration, error) {
+	parser.next()
+	declaration := &variableDeclaration{}
+	for {
+		name, err := parser.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		var value node
+		if parser.accept("=") {
+			if value, err = parser.parseAssignment(); err != nil {
+				return nil, err
+			}
+		}
+		declaration.names = append(declaration.names, name)
+		declaration.values = append(declaration.values, value)
+		if !parser.accept(",") {
+			return declaration, nil
+		}
+	}
+}
+
+func (parser *parser) parseIf() (node, error) {
+	parser.next()
+	condition, err := parser.parseParenthesized()
+	if err != nil {
+		return nil, err
+	}
+	consequent, err := parser.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	statement := &ifStatement{condition: condition, consequent: consequent}
+	if parser.atKeyword("else") {
+		parser.next()
+		if statement.alternate, err = parser.parseStatement(); err != nil {
+			return nil, err
+		}
+	}
+	return statement, nil
+}
+
+func (parser *parser) parseFor() (node, error) {
+	parser.next()
+	if err := parser.expect("("); err != nil {
+		return nil, err
+	}
+	statement := &forStatement{}
+	var err error
+	if !parser.atPunctuator(";") {
+		if parser.atKeyword("var") || parser.atKeyword("let") || parser.atKeyword("const") {
+			statement.init, err = parser.parseVariableDeclaration()
+		} else {
+			statement.init, err = parser.parseExpression()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.expect(";"); err != nil {
+		return nil, err
+	}
+	if !parser.atPunctuator(";") {
+		if statement.condition, err = parser.parseExpression(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.expect(";"); err != nil {
+		return nil, err
+	}
+	if !parser.atPunctuator(")") {
+		if statement.update, err = parser.parseExpression(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.expect(")"); err != nil {
+		return nil, err
+	}
+	if statement.body, err = parser.parseStatement(); err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+func (parser *parser) parseParenthesized() (node, error) {
+	if err := parser.expect("("); err != nil {
+		return nil, err
+	}
+	expression, err := parser.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.expect(")"); err != nil {
+		return nil, err
+	}
+	return expression, nil
+}
+
+func (parser *parser) parseExpression() (node, error) {
+	expression, err := parser.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+	if !parser.atPunctuator(",") {
+		return expression, nil
+	}
+	sequence := &sequenceExpression{expressions: []node{expression}}
+	for parser.accept(",") {
+		expression, err := parser.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		sequence.expressions = append(sequence.expressions, expression)
+	}
+	return sequence, nil
+}
+
+func (parser *parser) parseAssignment() (node, error) {
+	target, err := parser.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+	for _, operator := range []string{"=", "+=", "-="} {
+		if parser.atPunctuator(operator) {
+			if !isAssignable(target) {
+				return nil, errors.Errorf("line %d: invalid assignment target", parser.peek().line)
+			}
+			parser.next()
+			value, err := parser.parseAssignment()
+			if err != nil {
+				return nil, err
+			}
+			return &assignmentExpression{operator: operator, target: target, value: value}, nil
+		}
+	}
+	return target, nil
+}
+
+func isAssignable(target node) bool {
+	switch target.(type) {
+	case *identifier, *memberExpression:
+		return true
+	}
+	return false
+}
+
+func (parser *parser) parseConditional() (node, error) {
+	condition, err := parser.parseLogical("||")
+	if err != nil {
+		return nil, err
+	}
+	if !parser.accept("?") {
+		return condition, nil
+	}
+	consequent, err := parser.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.expect(":"); err != nil {
+		return nil, err
+	}
+	alternate, err := parser.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalExpression{condition: condition, consequent: consequent, alternate: alternate}, nil
+}
+
+func (parser *parser) parseLogical(operator string) (node, error) {
+	parseOperand := parser.parseEquality
+	if operator == "||" {
+		parseOperand = func() (node, error) { return parser.parseLogical("&&") }
+	}
+	left, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for parser.accept(operator) {
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+// binaryLevels are binary operators from the lowest to the highest precedence
+var binaryLevels = [][]string{
+	{"==", "!=", "===", "!=="},
+	{"<", ">", "<=", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (parser *parser) parseEquality() (node, error) {
+	return parser.parseBinary(0)
+}
+
+func (parser *parser) parseBinary(level int) (node, error) {
+	if level == len(binaryLevels) {
+		return parser.parseUnary()
+	}
+	left, err := parser.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := ""
+		for _, candidate := range binaryLevels[level] {
+			if parser.atPunctuator(candidate) {
+				operator = candidate
+				break
+			}
+		}
+		if operator == "" {
+			return left, nil
+		}
+		parser.next()
+		right, err := parser.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpression{operator: operator, left: left, right: right}
+	}
+}
+
+func (parser *parser) parseUnary() (node, error) {
+	for _, operator := range []string{"!", "-", "+"} {
+		if parser.accept(operator) {
+			operand, err := parser.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &unaryExpression{operator: operator, operand: operand}, nil
+		}
+	}
+	if parser.atKeyword("typeof") {
+		parser.next()
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpression{operator: "typeof", operand: operand}, nil
+	}
+	for _, operator := range []string{"++", "--"} {
+		if parser.accept(operator) {
+			target, err := parser.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			if !isAssignable(target) {
+				return nil, errors.Errorf("line %d: invalid %s operand", parser.peek().line, operator)
+			}
+			return &updateExpression{operator: operator, prefix: true, target: target}, nil
+		}
+	}
+	return parser.parsePostfix()
+}
+
+func (parser *parser) parsePostfix() (node, error) {
+	line := parser.peek().line
+	expression, err := parser.parseCall()
+	if err != nil {
+		return nil, err
+	}
+	for _, operator := range []string{"++", "--"} {
+		if parser.atPunctuator(operator) && parser.peek().line == line && isAssignable(expression) {
+			parser.next()
+			return &updateExpression{operator: operator, target: expression}, nil
+		}
+	}
+	return expression, nil
+}
+
+func (parser *parser) parseCall() (node, error) {
+	var expression node
+	var err error
+	if parser.atKeyword("new") {
+		parser.next()
+		callee, err := parser.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		newExpression := &newExpression{callee: callee}
+		if parser.atPunctuator("(") {
+			if newExpression.arguments, err = parser.parseArguments(); err != nil {
+				return nil, err
+			}
+		}
+		expression = newExpression
+	} else if expression, err = parser.parsePrimary(); err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case parser.accept("."):
+			if !parser.at(tokenIdentifier, "") {
+				return nil, parser.unexpected()
+			}
+			name := parser.next().text
+			expression = &memberExpression{object: expression, property: &literal{value: name}}
+		case parser.accept("["):
+			property, err := parser.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := parser.expect("]"); err != nil {
+				return nil, err
+			}
+			expression = &memberExpression{object: expression, property: property}
+		case parser.atPunctuator("("):
+			arguments, err := parser.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			expression = &callExpression{callee: expression, arguments: arguments}
+		default:
+			return expression, nil
+		}
+	}
+}
+
+func (parser *parser) parseArguments() ([]node, error) {
+	if err := parser.expect("("); err != nil {
+		return nil, err
+	}
+	var arguments []node
+	for !parser.accept(")") {
+		if len(arguments) > 0 {
+			if err := parser.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		argument, err := parser.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+	return arguments, nil
+}
+
+func (parser *parser) parsePrimary() (node, error) {
+	token := parser.peek()
+	switch token.kind {
+	case tokenNumber:
+		parser.next()
+		return &literal{value: token.number}, nil
+	case tokenString:
+		parser.next()
+		return &literal{value: token.text}, nil
+	case tokenRegexp:
+		parser.next()
+		re, err := compileRegexp(token.text, token.flags)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", token.line)
+		}
+		return &regexpLiteral{regexp: re}, nil
+	case tokenIdentifier:
+		switch token.text {
+		case "true":
+			parser.next()
+			return &literal{value: true}, nil
+		case "false":
+			parser.next()
+			return &literal{value: false}, nil
+		case "null":
+			parser.next()
+			return &literal{value: nil}, nil
+		case "function":
+			parser.next()
+			return parser.parseFunction(false)
+		}
+		name, err := parser.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		return &identifier{name: name}, nil
+	case tokenPunctuator:
+		switch token.text {
+		case "(":
+			return parser.parseParenthesized()
+		case "[":
+			return parser.parseArrayLiteral()
+		case "{":
+			return parser.parseObjectLiteral()
+		}
+	}
+	return nil, parser.unexpected()
+}
+
+func (parser *parser) parseArrayLiteral() (node, error) {
+	parser.next()
+	array := &arrayLiteral{}
+	for !parser.accept("]") {
+		if len(array.elements) > 0 {
+			if err := parser.expect(","); err != nil {
+				return nil, err
+			}
+			if parser.accept("]") {
+				break
+			}
+		}
+		element, err := parser.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		array.elements = append(array.elements, element)
+	}
+	return array, nil
+}
+
+func (parser *parser) parseObjectLiteral() (node, error) {
+	parser.next()
+	object := &objectLiteral{}
+	for !parser.accept("}") {
+		if len(object.keys) > 0 {
+			if err := parser.expect(","); err != nil {
+				return nil, err
+			}
+			if parser.accept("}") {
+				break
+			}
+		}
+		key := parser.peek()
+		if key.kind != tokenIdentifier && key.kind != tokenString && key.kind != tokenNumber {
+			return nil, parser.unexpected()
+		}
+		parser.next()
+		name := key.text
+		if err := parser.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := parser.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		object.keys = append(object.keys, name)
+		object.values = append(object.values, value)
+	}
+	return object, nil
+}
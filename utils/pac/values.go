@@ -0,0 +1,693 @@
+package pac
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// value is a JavaScript value: undefinedValue, nil for null, bool, float64, string,
+// *array, *object, *function, *builtin, *regexpValue or *dateValue
+type value interface{}
+
+type undefinedValue struct{}
+
+var undefined value = undefinedValue{}
+
+type array struct {
+	elements []value
+}
+
+type object struct {
+	properties map[string]value
+}
+
+// function is a function defined in the script
+type function struct {
+	literal *functionLiteral
+	scope   *scope
+}
+
+// builtin is a function implemented in Go
+type builtin struct {
+	name string
+	call func(arguments []value) (value, error)
+}
+
+type regexpValue struct {
+	regexp *regexp.Regexp
+	source string
+	flags  string
+}
+
+type dateValue struct {
+	time time.Time
+}
+
+func argument(arguments []value, index int) value {
+	if index < len(arguments) {
+		return arguments[index]
+	}
+	return undefined
+}
+
+func toBoolean(v value) bool {
+	switch v := v.(type) {
+	case undefinedValue, nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func toNumber(v value) float64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		return v
+	case string:
+		return stringToNumber(v)
+	case *array:
+		return stringToNumber(toString(v))
+	case *dateValue:
+		return float64(v.time.UnixNano() / int64(time.Millisecond))
+	}
+	return math.NaN()
+}
+
+func stringToNumber(s string) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		if number, err := strconv.ParseUint(s[2:], 16, 64); err == nil {
+			return float64(number)
+		}
+		return math.NaN()
+	}
+	switch s {
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return number
+}
+
+// toInteger converts v to an integer truncating towards zero, NaN is converted to 0
+func toInteger(v value) int {
+	number := toNumber(v)
+	switch {
+	case math.IsNaN(number):
+		return 0
+	case number > math.MaxInt32:
+		return math.MaxInt32
+	case number < math.MinInt32:
+		return math.MinInt32
+	}
+	return int(number)
+}
+
+func toString(v value) string {
+	switch v := v.(type) {
+	case undefinedValue:
+		return "undefined"
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return numberToString(v)
+	case string:
+		return v
+	case *array:
+		// too long result is cut after maxStringLength, so strings built of it are rejected
+		result, _ := joinArray(v, ",", nil)
+		return result
+	case *object:
+		return "[object Object]"
+	case *function:
+		return "function " + v.literal.name + "() { [code] }"
+	case *builtin:
+		return "function " + v.name + "() { [native code] }"
+	case *regexpValue:
+		return "/" + v.source + "/" + v.flags
+	case *dateValue:
+		return v.time.Format("Mon Jan 02 2006 15:04:05 GMT-0700")
+	}
+	return ""
+}
+
+func numberToString(number float64) string {
+	switch {
+	case math.IsNaN(number):
+		return "NaN"
+	case math.IsInf(number, 1):
+		return "Infinity"
+	case math.IsInf(number, -1):
+		return "-Infinity"
+	case number == math.Trunc(number) && math.Abs(number) < 1e21:
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+func typeOf(v value) string {
+	switch v.(type) {
+	case undefinedValue:
+		return "undefined"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *function, *builtin:
+		return "function"
+	}
+	return "object"
+}
+
+func isPrimitive(v value) bool {
+	switch v.(type) {
+	case undefinedValue, nil, bool, float64, string:
+		return true
+	}
+	return false
+}
+
+func strictEquals(a, b value) bool {
+	if a, ok := a.(float64); ok {
+		b, ok := b.(float64)
+		return ok && a == b
+	}
+	return a == b
+}
+
+func looseEquals(a, b value) bool {
+	if typeOf(a) == typeOf(b) && (a == nil) == (b == nil) {
+		return strictEquals(a, b)
+	}
+	isNullish := func(v value) bool { return v == nil || v == undefined }
+	if isNullish(a) || isNullish(b) {
+		return isNullish(a) && isNullish(b)
+	}
+	switch {
+	case typeOf(a) == "number" && typeOf(b) == "string":
+		return a.(float64) == stringToNumber(b.(string))
+	case typeOf(a) == "string" && typeOf(b) == "number":
+		return stringToNumber(a.(string)) == b.(float64)
+	case typeOf(a) == "boolean":
+		return looseEquals(toNumber(a), b)
+	case typeOf(b) == "boolean":
+		return looseEquals(a, toNumber(b))
+	case !isPrimitive(a) && isPrimitive(b):
+		return looseEquals(toString(a), b)
+	case isPrimitive(a) && !isPrimitive(b):
+		return looseEquals(a, toString(b))
+	}
+	return false
+}
+
+// compileRegexp converts a JavaScript regular expression to Go, only i, m, s and g flags are supported
+func compileRegexp(pattern, flags string) (*regexpValue, error) {
+	goFlags := ""
+	for _, flag := range flags {
+		switch flag {
+		case 'i', 'm', 's':
+			goFlags += string(flag)
+		case 'g':
+		default:
+			return nil, errors.Errorf("unsupported regular expression flag %q", flag)
+		}
+	}
+	source := pattern
+	if goFlags != "" {
+		source = "(?" + goFlags + ")" + pattern
+	}
+	compiled, err := regexp.Compile(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unsupported regular expression /%s/", pattern)
+	}
+	return &regexpValue{regexp: compiled, source: pattern, flags: flags}, nil
+}
+
+func (re *regexpValue) isGlobal() bool {
+	return strings.Contains(re.flags, "g")
+}
+
+// match returns the first match with groups or null
+func (re *regexpValue) match(s string) value {
+	groups := re.regexp.FindStringSubmatchIndex(s)
+	if groups == nil {
+		return nil
+	}
+	result := &array{}
+	for i := 0; i < len(groups); i += 2 {
+		if groups[i] < 0 {
+			result.elements = append(result.elements, undefined)
+			continue
+		}
+		result.elements = append(result.elements, s[groups[i]:groups[i+1]])
+	}
+	return result
+}
+
+func newMethod(name string, call func(arguments []value) (value, error)) *builtin {
+	return &builtin{name: name, call: call}
+}
+
+// getProperty returns property name of v, methods are returned bound to v
+func getProperty(v value, name string) (value, error) {
+	switch v := v.(type) {
+	case undefinedValue, nil:
+		return nil, errors.Errorf("cannot read property %q of %s", name, toString(v))
+	case string:
+		return stringProperty(v, name), nil
+	case *array:
+		return arrayProperty(v, name), nil
+	case *object:
+		if property, ok := v.properties[name]; ok {
+			return property, nil
+		}
+	case *regexpValue:
+		return regexpProperty(v, name), nil
+	case *dateValue:
+		return dateProperty(v, name), nil
+	}
+	return undefined, nil
+}
+
+func setProperty(target value, name string, v value) error {
+	switch target := target.(type) {
+	case *array:
+		index, err := strconv.Atoi(name)
+		if err != nil || index < 0 {
+			return errors.Errorf("unsupported array property %q", name)
+		}
+		if index > maxArrayLength {
+			return errors.New("array is too long")
+		}
+		for len(target.elements) <= index {
+			target.elements = append(target.elements, undefined)
+		}
+		target.elements[index] = v
+		return nil
+	case *object:
+		target.properties[name] = v
+		return nil
+	}
+	return errors.Errorf("cannot set property %q of %s", name, typeOf(target))
+}
+
+// maxArrayLength limits memory used by scripts
+const maxArrayLength = 100000
+
+// maxStringLength limits memory used by scripts
+const maxStringLength = 1 << 20
+
+func concatStrings(left, right string) (string, error) {
+	if len(left)+len(right) > maxStringLength {
+		return "", errors.New("string is too long")
+	}
+	return left + right, nil
+}
+
+// joinArray converts elements of a to strings and joins them with separator,
+// nested arrays already being joined (in seen) are converted to empty strings like in browsers
+func joinArray(a *array, separator string, seen map[*array]bool) (string, error) {
+	if seen == nil {
+		seen = make(map[*array]bool)
+	}
+	seen[a] = true
+	defer delete(seen, a)
+	var builder strings.Builder
+	for i, element := range a.elements {
+		if i > 0 {
+			builder.WriteString(separator)
+		}
+		switch element := element.(type) {
+		case nil, undefinedValue:
+		case *array:
+			if seen[element] {
+				break
+			}
+			part, err := joinArray(element, ",", seen)
+			builder.WriteString(part)
+			if err != nil {
+				return builder.String(), err
+			}
+		default:
+			builder.WriteString(toString(element))
+		}
+		if builder.Len() > maxStringLength {
+			return builder.String(), errors.New("string is too long")
+		}
+	}
+	return builder.String(), nil
+}
+
+func stringProperty(s string, name string) value {
+	if index, err := strconv.Atoi(name); err == nil {
+		if index >= 0 && index < len(s) {
+			return s[index : index+1]
+		}
+		return undefined
+	}
+	switch name {
+	case "length":
+		return float64(len(s))
+	case "charAt":
+		return newMethod(name, func(arguments []value) (value, error) {
+			index := toInteger(argument(arguments, 0))
+			if index < 0 || index >= len(s) {
+				return "", nil
+			}
+			return s[index : index+1], nil
+		})
+	case "charCodeAt":
+		return newMethod(name, func(arguments []value) (value, error) {
+			index := toInteger(argument(arguments, 0))
+			if index < 0 || index >= len(s) {
+				return math.NaN(), nil
+			}
+			return float64(s[index]), nil
+		})
+	case "indexOf":
+		return newMethod(name, func(arguments []value) (value, error) {
+			from := clamp(toInteger(argument(arguments, 1)), 0, len(s))
+			index := strings.Index(s[from:], toString(argument(arguments, 0)))
+			if index == -1 {
+				return float64(-1), nil
+			}
+			return float64(from + index), nil
+		})
+	case "lastIndexOf":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return float64(strings.LastIndex(s, toString(argument(arguments, 0)))), nil
+		})
+	case "includes":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return strings.Contains(s, toString(argument(arguments, 0))), nil
+		})
+	case "startsWith":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return strings.HasPrefix(s, toString(argument(arguments, 0))), nil
+		})
+	case "endsWith":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return strings.HasSuffix(s, toString(argument(arguments, 0))), nil
+		})
+	case "substring":
+		return newMethod(name, func(arguments []value) (value, error) {
+			start := clamp(toInteger(argument(arguments, 0)), 0, len(s))
+			end := len(s)
+			if argument(arguments, 1) != undefined {
+				end = clamp(toInteger(arguments[1]), 0, len(s))
+			}
+			if start > end {
+				start, end = end, start
+			}
+			return s[start:end], nil
+		})
+	case "substr":
+		return newMethod(name, func(arguments []value) (value, error) {
+			start := relativeIndex(toInteger(argument(arguments, 0)), len(s))
+			length := len(s) - start
+			if argument(arguments, 1) != undefined {
+				length = clamp(toInteger(arguments[1]), 0, len(s)-start)
+			}
+			return s[start : start+length], nil
+		})
+	case "slice":
+		return newMethod(name, func(arguments []value) (value, error) {
+			start := relativeIndex(toInteger(argument(arguments, 0)), len(s))
+			end := len(s)
+			if argument(arguments, 1) != undefined {
+				end = relativeIndex(toInteger(arguments[1]), len(s))
+			}
+			if start >= end {
+				return "", nil
+			}
+			return s[start:end], nil
+		})
+	case "toLowerCase":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return strings.ToLower(s), nil
+		})
+	case "toUpperCase":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return strings.ToUpper(s), nil
+		})
+	case "trim":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return strings.TrimSpace(s), nil
+		})
+	case "toString":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return s, nil
+		})
+	case "concat":
+		return newMethod(name, func(arguments []value) (value, error) {
+			result := s
+			for _, argument := range arguments {
+				var err error
+				if result, err = concatStrings(result, toString(argument)); err != nil {
+					return nil, err
+				}
+			}
+			return result, nil
+		})
+	case "split":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return splitString(s, argument(arguments, 0), argument(arguments, 1)), nil
+		})
+	case "replace":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return replaceString(s, argument(arguments, 0), toString(argument(arguments, 1)))
+		})
+	case "match":
+		return newMethod(name, func(arguments []value) (value, error) {
+			re, err := toRegexp(argument(arguments, 0))
+			if err != nil {
+				return nil, err
+			}
+			if !re.isGlobal() {
+				return re.match(s), nil
+			}
+			matches := re.regexp.FindAllString(s, -1)
+			if matches == nil {
+				return nil, nil
+			}
+			result := &array{}
+			for _, match := range matches {
+				result.elements = append(result.elements, match)
+			}
+			return result, nil
+		})
+	case "search":
+		return newMethod(name, func(arguments []value) (value, error) {
+			re, err := toRegexp(argument(arguments, 0))
+			if err != nil {
+				return nil, err
+			}
+			location := re.regexp.FindStringIndex(s)
+			if location == nil {
+				return float64(-1), nil
+			}
+			return float64(location[0]), nil
+		})
+	}
+	return undefined
+}
+
+func clamp(index, min, max int) int {
+	if index < min {
+		return min
+	}
+	if index > max {
+		return max
+	}
+	return index
+}
+
+// relativeIndex converts negative index counting from the end to a position in a sequence of length
+func relativeIndex(index, length int) int {
+	if index < 0 {
+		index += length
+	}
+	return clamp(index, 0, length)
+}
+
+func toRegexp(v value) (*regexpValue, error) {
+	if re, ok := v.(*regexpValue); ok {
+		return re, nil
+	}
+	return compileRegexp(regexp.QuoteMeta(toString(v)), "")
+}
+
+func splitString(s string, separator value, limit value) *array {
+	var parts []string
+	switch separator := separator.(type) {
+	case undefinedValue:
+		parts = []string{s}
+	case *regexpValue:
+		parts = separator.regexp.Split(s, -1)
+	default:
+		parts = strings.Split(s, toString(separator))
+	}
+	if limit != undefined {
+		parts = parts[:clamp(toInteger(limit), 0, len(parts))]
+	}
+	result := &array{}
+	for _, part := range parts {
+		result.elements = append(result.elements, part)
+	}
+	return result
+}
+
+func replaceString(s string, pattern value, replacement string) (string, error) {
+	re, ok := pattern.(*regexpValue)
+	if !ok {
+		if len(s)+len(replacement) > maxStringLength {
+			return "", errors.New("string is too long")
+		}
+		return strings.Replace(s, toString(pattern), replacement, 1), nil
+	}
+	template := strings.Replace(replacement, "$&", "${0}", -1)
+	n := 1
+	if re.isGlobal() {
+		n = -1
+	}
+	var result []byte
+	last := 0
+	for _, location := range re.regexp.FindAllStringSubmatchIndex(s, n) {
+		result = append(result, s[last:location[0]]...)
+		result = re.regexp.ExpandString(result, template, s, location)
+		last = location[1]
+		if len(result)+len(s)-last > maxStringLength {
+			return "", errors.New("string is too long")
+		}
+	}
+	return string(append(result, s[last:]...)), nil
+}
+
+func arrayProperty(a *array, name string) value {
+	if index, err := strconv.Atoi(name); err == nil {
+		if index >= 0 && index < len(a.elements) {
+			return a.elements[index]
+		}
+		return undefined
+	}
+	switch name {
+	case "length":
+		return float64(len(a.elements))
+	case "indexOf":
+		return newMethod(name, func(arguments []value) (value, error) {
+			for i, element := range a.elements {
+				if strictEquals(element, argument(arguments, 0)) {
+					return float64(i), nil
+				}
+			}
+			return float64(-1), nil
+		})
+	case "includes":
+		return newMethod(name, func(arguments []value) (value, error) {
+			for _, element := range a.elements {
+				if strictEquals(element, argument(arguments, 0)) {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+	case "join":
+		return newMethod(name, func(arguments []value) (value, error) {
+			separator := ","
+			if argument(arguments, 0) != undefined {
+				separator = toString(arguments[0])
+			}
+			result, err := joinArray(a, separator, nil)
+			if err != nil {
+				return nil, err
+			}
+			return result, nil
+		})
+	case "push":
+		return newMethod(name, func(arguments []value) (value, error) {
+			if len(a.elements)+len(arguments) > maxArrayLength {
+				return nil, errors.New("array is too long")
+			}
+			a.elements = append(a.elements, arguments...)
+			return float64(len(a.elements)), nil
+		})
+	}
+	return undefined
+}
+
+func regexpProperty(re *regexpValue, name string) value {
+	switch name {
+	case "source":
+		return re.source
+	case "test":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return re.regexp.MatchString(toString(argument(arguments, 0))), nil
+		})
+	case "exec":
+		return newMethod(name, func(arguments []value) (value, error) {
+			return re.match(toString(argument(arguments, 0))), nil
+		})
+	}
+	return undefined
+}
+
+func dateProperty(date *dateValue, name string) value {
+	t := date.time
+	if strings.HasPrefix(name, "getUTC") {
+		t = t.UTC()
+		name = "get" + strings.TrimPrefix(name, "getUTC")
+	}
+	var getter func() int
+	switch name {
+	case "getDay":
+		getter = func() int { return int(t.Weekday()) }
+	case "getDate":
+		getter = t.Day
+	case "getMonth":
+		getter = func() int { return int(t.Month()) - 1 }
+	case "getFullYear":
+		getter = t.Year
+	case "getHours":
+		getter = t.Hour
+	case "getMinutes":
+		getter = t.Minute
+	case "getSeconds":
+		getter = t.Second
+	case "getTime":
+		getter = func() int { return int(t.UnixNano() / int64(time.Millisecond)) }
+	default:
+		return undefined
+	}
+	return newMethod(name, func(arguments []value) (value, error) {
+		return float64(getter()), nil
+	})
+}
@@ -68,20 +68,50 @@ func CreateProductWorkDir(productWorkDir string) error {
 	return nil
 }
 
+// Executable returns absolute path of the running executable,
+// falls back to os.Args[0] if the path can't be determined
+func Executable() string {
+	if executable, err := os.Executable(); err == nil {
+		return executable
+	}
+	return os.Args[0]
+}
+
 func OpenOrCreateProductLogFile(productLogFile string) (*os.File, error) {
 	return os.OpenFile(productLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
 
 }
 
 type AppInfo struct {
-	Title           string
-	UninstallString string
-	Icon            string
-	Version         string
-	URL             string
-	Publisher       string
+	Title            string   `json:"title"`
+	UninstallString  string   `json:"-"`
+	UninstallCommand []string `json:"uninstallCommand"` // Executable and arguments of UninstallString
+	Icon             string   `json:"icon,omitempty"`
+	Version          string   `json:"version,omitempty"`
+	URL              string   `json:"url,omitempty"`
+	Publisher        string   `json:"publisher,omitempty"`
 }
 
+// JNLPFile returns the original JNLP file passed to the uninstall command of the app or an empty string
+func (app *AppInfo) JNLPFile() string {
+	if len(app.UninstallCommand) == 0 {
+		return ""
+	}
+	return app.UninstallCommand[len(app.UninstallCommand)-1]
+}
+
+// FileAssociation describes documents which should be opened with an app
+type FileAssociation struct {
+	MimeType    string
+	Extensions  []string
+	Description string
+	Icon        string
+}
+
+// DocumentArgument is a placeholder in arguments of RegisterFileAssociations
+// which is replaced with a path of the document being opened
+const DocumentArgument = "%1"
+
 type ErrorWithExtraLine struct {
 	err       error
 	extraLine string
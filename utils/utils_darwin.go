@@ -52,10 +52,26 @@ func RemoveDesktopShortcut(title string) error {
 	return nil
 }
 
+func RemoveStartMenuShortcut(folder, title string) error {
+	return nil
+}
+
+func ConvertShortcutIcon(iconSrc, name, dstDir string) (string, error) {
+	return "", nil
+}
+
 func RemoveStartMenuFolder(folder string) error {
 	return nil
 }
 
+func RegisterFileAssociations(src, title string, associations []*FileAssociation, openArguments, printArguments []string) error {
+	return nil
+}
+
+func UnregisterFileAssociations(title string, associations []*FileAssociation) error {
+	return nil
+}
+
 func ShowUsage(productTitle, productVersion, text string) {
 	fmt.Fprintf(os.Stderr, text)
 }
@@ -68,11 +84,27 @@ func InstallApp(app *AppInfo) error {
 	return nil
 }
 
-func UninstallApp(title string) error {
+func UninstallApp(title, jnlpFile string) error {
 	return nil
 }
 
+func LoadInstalledApps() ([]*AppInfo, error) {
+	return nil, nil
+}
+
 func OpenTextFile(filename string) error {
 	cmd := exec.Command("Open", "-t", filename)
 	return cmd.Start()
 }
+
+func RegisterURLHandler(src string) error {
+	return errors.Errorf("RegisterURLHandler not implemented for platform %s", runtime.GOOS)
+}
+
+func UnregisterURLHandler() error {
+	return errors.Errorf("UnregisterURLHandler not implemented for platform %s", runtime.GOOS)
+}
+
+func IsURLHandlerRegistered() bool {
+	return false
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 
 	"github.com/pkg/errors"
@@ -41,19 +43,134 @@ func CallLibrary(path string, funcName string, arg string) (err error) {
 }
 
 func CreateDesktopShortcut(src, title, description, iconSrc string, arguments ...string) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to create a desktop shortcut for %v with title %s", arguments, title)
+		}
+	}()
+	desktopFolder, err := GetDesktopFolder()
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(desktopFolder); os.IsNotExist(err) {
+		// there is no desktop, e.g. on a server
+		return nil
+	}
+	entry := &DesktopEntry{
+		Name:    title,
+		Comment: description,
+		Exec:    append([]string{src}, arguments...),
+		Icon:    iconSrc,
+	}
+	filename := filepath.Join(desktopFolder, getDesktopShortcutFilename(title))
+	if err = entry.Write(filename); err != nil {
+		return err
+	}
+	markDesktopEntryAsTrusted(filename)
 	return nil
 }
 
 func CreateStartMenuShortcut(src, folder, title, description, iconSrc string, arguments ...string) error {
-	return nil
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to create a menu entry for %v with title %s", arguments, title)
+		}
+	}()
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return err
+	}
+	entry := &DesktopEntry{
+		Name:       title,
+		Comment:    description,
+		Exec:       append([]string{src}, arguments...),
+		Icon:       iconSrc,
+		Categories: []string{"Utility", "Java"},
+	}
+	if len(splitSubmenu(folder)) > 0 {
+		if err = writeSubmenu(folder); err != nil {
+			return err
+		}
+		entry.Categories = []string{submenuCategory(folder)}
+	}
+	err = entry.Write(filepath.Join(applicationsDir, desktopEntryID(folder, title)+".desktop"))
+	return err
 }
 
 func RemoveDesktopShortcut(title string) error {
-	return nil
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to remove desktop shortcut %s ", title)
+		}
+	}()
+	desktopFolder, err := GetDesktopFolder()
+	if err != nil {
+		return err
+	}
+	if err = removeIfExists(filepath.Join(desktopFolder, getDesktopShortcutFilename(title))); err != nil {
+		return err
+	}
+	err = removeHicolorIcons(title)
+	return err
+}
+
+// RemoveStartMenuShortcut removes a menu entry created by CreateStartMenuShortcut
+func RemoveStartMenuShortcut(folder, title string) error {
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to remove menu entry %s", title)
+		}
+	}()
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return err
+	}
+	if err = removeIfExists(filepath.Join(applicationsDir, desktopEntryID(folder, title)+".desktop")); err != nil {
+		return err
+	}
+	err = removeHicolorIcons(title)
+	return err
+}
+
+// ConvertShortcutIcon installs iconSrc into user's hicolor icon theme in all sizes
+// and returns the icon name to be used for shortcuts
+func ConvertShortcutIcon(iconSrc, name, dstDir string) (string, error) {
+	return installHicolorIcons(iconSrc, name)
 }
 
 func RemoveStartMenuFolder(folder string) error {
-	return nil
+	var err error
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to remove menu folder %s", folder)
+		}
+	}()
+	if len(splitSubmenu(folder)) == 0 {
+		return nil
+	}
+	applicationsDir, err := getApplicationsDir()
+	if err != nil {
+		return err
+	}
+	entries, err := filepath.Glob(filepath.Join(applicationsDir, desktopEntryID(folder)+"-*.desktop"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = removeIfExists(entry); err != nil {
+			return err
+		}
+	}
+	err = removeSubmenu(folder)
+	return err
+}
+
+func getDesktopShortcutFilename(title string) string {
+	return strings.Replace(title, "/", "-", -1) + ".desktop"
 }
 
 func ShowUsage(productTitle, productVersion, text string) {
@@ -64,14 +181,6 @@ func ShowFatalError(text string) {
 	fmt.Fprintf(os.Stderr, text)
 }
 
-func InstallApp(app *AppInfo) error {
-	return nil
-}
-
-func UninstallApp(title string) error {
-	return nil
-}
-
 func OpenTextFile(filename string) error {
 	cmd := exec.Command("xdg-open", filename)
 	return cmd.Start()
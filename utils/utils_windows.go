@@ -18,6 +18,7 @@ import (
 	"golang.org/x/sys/windows/registry"
 
 	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/icon"
 )
 
 func HideWindow(cmd *exec.Cmd) {
@@ -305,6 +306,41 @@ func RemoveDesktopShortcut(title string) error {
 	return os.Remove(link)
 }
 
+func RemoveStartMenuShortcut(folder, title string) error {
+	var err error
+	var programsDir string
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "unable to remove Start Menu shortcut %s", title)
+		}
+	}()
+	programsDir, err = getProgramsStartMenuDir()
+	if err != nil {
+		return err
+	}
+	link := filepath.Join(programsDir, folder, title+".lnk")
+	if _, err = os.Stat(link); os.IsNotExist(err) {
+		return nil
+	}
+	return os.Remove(link)
+}
+
+// ConvertShortcutIcon converts iconSrc into a multi-resolution ICO file in dstDir if it is not an ICO file already
+func ConvertShortcutIcon(iconSrc, name, dstDir string) (string, error) {
+	if strings.ToLower(filepath.Ext(iconSrc)) == ".ico" {
+		return iconSrc, nil
+	}
+	img, err := icon.Decode(iconSrc)
+	if err != nil {
+		return "", err
+	}
+	iconFile := filepath.Join(dstDir, strings.TrimSuffix(filepath.Base(iconSrc), filepath.Ext(iconSrc))+".ico")
+	if err := icon.WriteICO(iconFile, img, icon.WindowsSizes); err != nil {
+		return "", errors.Wrapf(err, "unable to convert icon %s", filepath.Base(iconSrc))
+	}
+	return iconFile, nil
+}
+
 func RemoveStartMenuFolder(folder string) error {
 	var err error
 	var programsDir string
@@ -394,14 +430,69 @@ func InstallApp(app *AppInfo) error {
 	return nil
 }
 
-func UninstallApp(title string) error {
+// UninstallApp removes the uninstall entry of the app with title, entries are identified by titles on Windows
+func UninstallApp(title, jnlpFile string) error {
 	if err := registry.DeleteKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Uninstall\`+title); err != nil {
 		return err
 	}
 	return nil
 }
 
+// LoadInstalledApps returns apps installed by InstallApp
+func LoadInstalledApps() ([]*AppInfo, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Uninstall`, registry.ENUMERATE_SUB_KEYS)
+	if err == registry.ErrNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read installed apps")
+	}
+	defer key.Close()
+	titles, err := key.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read installed apps")
+	}
+	var apps []*AppInfo
+	for _, title := range titles {
+		appKey, err := registry.OpenKey(key, title, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		uninstallString, _, err := appKey.GetStringValue("UninstallString")
+		appKey.Close()
+		if err != nil {
+			continue
+		}
+		// UninstallString of InstallApp is "<executable>" -uninstall -gui "<original JNLP file>"
+		const uninstallArguments = ` -uninstall -gui "`
+		i := strings.Index(uninstallString, uninstallArguments)
+		if i < 0 || !strings.HasSuffix(uninstallString, `"`) {
+			continue
+		}
+		executable := strings.Trim(uninstallString[:i], `"`)
+		jnlpFile := strings.TrimSuffix(uninstallString[i+len(uninstallArguments):], `"`)
+		apps = append(apps, &AppInfo{
+			Title:            title,
+			UninstallString:  uninstallString,
+			UninstallCommand: []string{executable, "-uninstall", "-gui", jnlpFile},
+		})
+	}
+	return apps, nil
+}
+
 func OpenTextFile(filename string) error {
 	cmd := exec.Command("notepad.exe", filename)
 	return cmd.Start()
 }
+
+func RegisterURLHandler(src string) error {
+	return errors.Errorf("RegisterURLHandler not implemented for platform %s", runtime.GOOS)
+}
+
+func UnregisterURLHandler() error {
+	return errors.Errorf("UnregisterURLHandler not implemented for platform %s", runtime.GOOS)
+}
+
+func IsURLHandlerRegistered() bool {
+	return false
+}
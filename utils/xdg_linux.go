@@ -0,0 +1,371 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rocketsoftware/open-web-launch/utils/icon"
+)
+
+// desktopEntryPrefix is a prefix for names of desktop entries, directories and menus created by the launcher
+const desktopEntryPrefix = "openweblaunch"
+
+// DesktopEntry is a freedesktop.org desktop entry
+type DesktopEntry struct {
+	Name       string
+	Comment    string
+	Exec       []string // Executable and its arguments, quoted when the entry is written except field codes
+	ExecSuffix string   // Field codes like %f or %u appended to Exec unquoted
+	Icon       string
+	Categories []string
+	MimeTypes  []string
+	NoDisplay  bool
+}
+
+// String returns content of .desktop file for the entry
+func (entry *DesktopEntry) String() string {
+	var builder strings.Builder
+	builder.WriteString("[Desktop Entry]\n")
+	builder.WriteString("Type=Application\n")
+	builder.WriteString("Version=1.0\n")
+	builder.WriteString("Name=" + escapeDesktopEntryValue(entry.Name) + "\n")
+	if entry.Comment != "" {
+		builder.WriteString("Comment=" + escapeDesktopEntryValue(entry.Comment) + "\n")
+	}
+	var execArgs []string
+	for _, arg := range entry.Exec {
+		if isDesktopExecFieldCode(arg) {
+			execArgs = append(execArgs, arg)
+			continue
+		}
+		execArgs = append(execArgs, quoteDesktopExecArgument(arg))
+	}
+	exec := strings.Join(execArgs, " ")
+	if entry.ExecSuffix != "" {
+		exec += " " + entry.ExecSuffix
+	}
+	builder.WriteString("Exec=" + escapeDesktopEntryValue(exec) + "\n")
+	if entry.Icon != "" {
+		builder.WriteString("Icon=" + escapeDesktopEntryValue(entry.Icon) + "\n")
+	}
+	builder.WriteString("Terminal=false\n")
+	if len(entry.Categories) > 0 {
+		builder.WriteString("Categories=" + escapeDesktopEntryList(entry.Categories) + "\n")
+	}
+	if len(entry.MimeTypes) > 0 {
+		builder.WriteString("MimeType=" + escapeDesktopEntryList(entry.MimeTypes) + "\n")
+	}
+	if entry.NoDisplay {
+		builder.WriteString("NoDisplay=true\n")
+	}
+	return builder.String()
+}
+
+// Write saves the entry into filename
+func (entry *DesktopEntry) Write(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, []byte(entry.String()), 0755); err != nil {
+		return errors.Wrapf(err, "unable to write desktop entry %s", filename)
+	}
+	return nil
+}
+
+// escapeDesktopEntryValue escapes a value of string type according to Desktop Entry Specification
+func escapeDesktopEntryValue(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return replacer.Replace(value)
+}
+
+// escapeDesktopEntryList returns values of a key with multiple values separated and terminated by semicolons
+func escapeDesktopEntryList(values []string) string {
+	var builder strings.Builder
+	for _, value := range values {
+		builder.WriteString(strings.Replace(escapeDesktopEntryValue(value), ";", `\;`, -1) + ";")
+	}
+	return builder.String()
+}
+
+var reservedExecCharacters = regexp.MustCompile("[\\s\"'\\\\><~|&;$*?#()`]")
+
+// quoteDesktopExecArgument quotes an argument of Exec key according to Desktop Entry Specification
+func quoteDesktopExecArgument(arg string) string {
+	arg = strings.Replace(arg, "%", "%%", -1)
+	if arg != "" && !reservedExecCharacters.MatchString(arg) {
+		return arg
+	}
+	replacer := strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`)
+	return `"` + replacer.Replace(arg) + `"`
+}
+
+// isDesktopExecFieldCode reports whether arg is a field code like %f which is expanded by the desktop
+func isDesktopExecFieldCode(arg string) bool {
+	switch arg {
+	case "%f", "%F", "%u", "%U":
+		return true
+	}
+	return false
+}
+
+// desktopEntryID converts parts like submenu and title into a name which is safe for desktop entry files
+func desktopEntryID(parts ...string) string {
+	nonAlphanumeric := regexp.MustCompile("[^a-z0-9]+")
+	id := desktopEntryPrefix
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		slug := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(part), "-"), "-")
+		id += "-" + slug
+	}
+	return id
+}
+
+// getDataHome returns $XDG_DATA_HOME or ~/.local/share
+func getDataHome() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return dataHome, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// getApplicationsDir returns directory for user's desktop entries
+func getApplicationsDir() (string, error) {
+	dataHome, err := getDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, "applications"), nil
+}
+
+// getDesktopDirectoriesDir returns directory for user's .directory files describing submenus
+func getDesktopDirectoriesDir() (string, error) {
+	dataHome, err := getDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, "desktop-directories"), nil
+}
+
+// getConfigHome returns $XDG_CONFIG_HOME or ~/.config
+func getConfigHome() (string, error) {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return configHome, nil
+	}
+	return os.UserConfigDir()
+}
+
+// getMergedMenusDir returns directory for user's menu files merged into the applications menu
+func getMergedMenusDir() (string, error) {
+	configHome, err := getConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, "menus", "applications-merged"), nil
+}
+
+// GetDesktopFolder returns user's desktop directory using xdg-user-dirs configuration
+func GetDesktopFolder() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if desktopDir := os.Getenv("XDG_DESKTOP_DIR"); desktopDir != "" {
+		return desktopDir, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err == nil {
+		if desktopDir, err := readUserDir(filepath.Join(configDir, "user-dirs.dirs"), "XDG_DESKTOP_DIR", home); err == nil {
+			return desktopDir, nil
+		}
+	}
+	return filepath.Join(home, "Desktop"), nil
+}
+
+// readUserDir reads directory with name from user-dirs.dirs file
+func readUserDir(filename, name, home string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, name+"=") {
+			continue
+		}
+		value := strings.Trim(strings.TrimPrefix(line, name+"="), `"`)
+		value = strings.Replace(value, "$HOME", home, 1)
+		return value, nil
+	}
+	return "", errors.Errorf("%s not found in %s", name, filename)
+}
+
+// splitSubmenu splits submenu like "Vendor/App" into separate menu names
+func splitSubmenu(submenu string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(submenu, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// submenuCategory returns a private category used for putting desktop entries into submenu
+func submenuCategory(submenu string) string {
+	return "X-" + desktopEntryID(splitSubmenu(submenu)...)
+}
+
+// writeSubmenu creates .directory files and a merged .menu file
+// so desktop entries with submenuCategory(submenu) are shown in submenu of the applications menu
+func writeSubmenu(submenu string) error {
+	names := splitSubmenu(submenu)
+	directoriesDir, err := getDesktopDirectoriesDir()
+	if err != nil {
+		return err
+	}
+	menusDir, err := getMergedMenusDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directoriesDir, 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(menusDir, 0755); err != nil {
+		return err
+	}
+	var menu strings.Builder
+	menu.WriteString(`<!DOCTYPE Menu PUBLIC "-//freedesktop//DTD Menu 1.0//EN" "http://www.freedesktop.org/standards/menu-spec/menu-1.0.dtd">` + "\n")
+	menu.WriteString("<Menu>\n  <Name>Applications</Name>\n")
+	for i, name := range names {
+		directoryID := desktopEntryID(names[:i+1]...)
+		directory := "[Desktop Entry]\nType=Directory\nName=" + escapeDesktopEntryValue(name) + "\n"
+		if err := ioutil.WriteFile(filepath.Join(directoriesDir, directoryID+".directory"), []byte(directory), 0644); err != nil {
+			return err
+		}
+		indent := strings.Repeat("  ", i+1)
+		menu.WriteString(indent + "<Menu>\n")
+		menu.WriteString(fmt.Sprintf("%s  <Name>%s</Name>\n", indent, escapeXML(name)))
+		menu.WriteString(fmt.Sprintf("%s  <Directory>%s.directory</Directory>\n", indent, directoryID))
+	}
+	menu.WriteString(fmt.Sprintf("%s<Include>\n%s  <Category>%s</Category>\n%s</Include>\n",
+		strings.Repeat("  ", len(names)+1), strings.Repeat("  ", len(names)+1), submenuCategory(submenu), strings.Repeat("  ", len(names)+1)))
+	for i := len(names) - 1; i >= 0; i-- {
+		menu.WriteString(strings.Repeat("  ", i+1) + "</Menu>\n")
+	}
+	menu.WriteString("</Menu>\n")
+	menuFile := filepath.Join(menusDir, desktopEntryID(names...)+".menu")
+	return ioutil.WriteFile(menuFile, []byte(menu.String()), 0644)
+}
+
+// removeSubmenu removes files created by writeSubmenu
+func removeSubmenu(submenu string) error {
+	names := splitSubmenu(submenu)
+	if len(names) == 0 {
+		return nil
+	}
+	menusDir, err := getMergedMenusDir()
+	if err != nil {
+		return err
+	}
+	if err := removeIfExists(filepath.Join(menusDir, desktopEntryID(names...)+".menu")); err != nil {
+		return err
+	}
+	directoriesDir, err := getDesktopDirectoriesDir()
+	if err != nil {
+		return err
+	}
+	// parent directories may be shared with other submenus, only the innermost one is removed
+	return removeIfExists(filepath.Join(directoriesDir, desktopEntryID(names...)+".directory"))
+}
+
+// getHicolorIconsDir returns directory of user's hicolor icon theme
+func getHicolorIconsDir() (string, error) {
+	dataHome, err := getDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, "icons", "hicolor"), nil
+}
+
+// installHicolorIcons installs iconSrc resized to icon.HicolorSizes into user's hicolor icon theme
+// and returns the icon name to be used in desktop entries
+func installHicolorIcons(iconSrc, name string) (string, error) {
+	img, err := icon.Decode(iconSrc)
+	if err != nil {
+		return "", err
+	}
+	iconsDir, err := getHicolorIconsDir()
+	if err != nil {
+		return "", err
+	}
+	iconName := desktopEntryID(name)
+	for _, size := range icon.HicolorSizes {
+		sizeDir := fmt.Sprintf("%dx%d", size, size)
+		if err := icon.WritePNG(filepath.Join(iconsDir, sizeDir, "apps", iconName+".png"), img, size); err != nil {
+			return "", errors.Wrapf(err, "unable to install icon %s", filepath.Base(iconSrc))
+		}
+	}
+	updateIconCache(iconsDir)
+	return iconName, nil
+}
+
+// removeHicolorIcons removes icons installed by installHicolorIcons
+func removeHicolorIcons(name string) error {
+	iconsDir, err := getHicolorIconsDir()
+	if err != nil {
+		return err
+	}
+	iconName := desktopEntryID(name)
+	for _, size := range icon.HicolorSizes {
+		sizeDir := fmt.Sprintf("%dx%d", size, size)
+		if err := removeIfExists(filepath.Join(iconsDir, sizeDir, "apps", iconName+".png")); err != nil {
+			return err
+		}
+	}
+	updateIconCache(iconsDir)
+	return nil
+}
+
+// updateIconCache refreshes icon theme cache if the theme has one, otherwise new icons may not be shown
+func updateIconCache(iconsDir string) {
+	if _, err := os.Stat(filepath.Join(iconsDir, "icon-theme.cache")); err != nil {
+		return
+	}
+	if gtkUpdateIconCache, err := exec.LookPath("gtk-update-icon-cache"); err == nil {
+		exec.Command(gtkUpdateIconCache, "-f", "-t", iconsDir).Run()
+	}
+}
+
+func escapeXML(s string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	return replacer.Replace(s)
+}
+
+func removeIfExists(filename string) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// markDesktopEntryAsTrusted allows launching the entry from GNOME desktop without confirmation
+func markDesktopEntryAsTrusted(filename string) {
+	if gio, err := exec.LookPath("gio"); err == nil {
+		exec.Command(gio, "set", filename, "metadata::trusted", "true").Run()
+	}
+}
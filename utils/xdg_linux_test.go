@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_quoteDesktopExecArgument(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"/usr/bin/openweblaunch", "/usr/bin/openweblaunch"},
+		{"/home/user/.config/Rocket Software/app.jnlp", `"/home/user/.config/Rocket Software/app.jnlp"`},
+		{`$HOME`, `"\$HOME"`},
+		{"100%", "100%%"},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			if got := quoteDesktopExecArgument(tt.arg); got != tt.want {
+				t.Errorf("quoteDesktopExecArgument() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_DesktopEntry(t *testing.T) {
+	entry := &DesktopEntry{
+		Name:       "My App",
+		Exec:       []string{"/opt/owl/openweblaunch", `C:\app.jnlp`},
+		ExecSuffix: "%f",
+		Categories: []string{submenuCategory("Vendor/My App")},
+	}
+	got := entry.String()
+	for _, want := range []string{
+		"Name=My App\n",
+		`Exec=/opt/owl/openweblaunch "C:\\\\app.jnlp" %f` + "\n",
+		"Categories=X-openweblaunch-vendor-my-app;\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DesktopEntry.String() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func Test_DesktopEntry_MimeTypes(t *testing.T) {
+	entry := &DesktopEntry{
+		Name:      "My App",
+		Exec:      []string{"/opt/owl/openweblaunch"},
+		MimeTypes: []string{"application/x-my-app", "a/b\nExec=/bin/sh;x"},
+	}
+	got := entry.String()
+	if strings.Count(got, "\nExec=") != 1 {
+		t.Errorf("DesktopEntry.String() = %q, want one Exec key", got)
+	}
+	if want := `MimeType=application/x-my-app;a/b\nExec=/bin/sh\;x;` + "\n"; !strings.Contains(got, want) {
+		t.Errorf("DesktopEntry.String() = %q, want it to contain %q", got, want)
+	}
+}
@@ -2,6 +2,11 @@ package verifier
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
 	"io/ioutil"
 	"os/exec"
 	"path"
@@ -83,3 +88,87 @@ func getFileContent(file *zip.File) ([]byte, error) {
 	defer fileReader.Close()
 	return ioutil.ReadAll(fileReader)
 }
+
+// GetJARSignerFingerprint returns SHA-256 fingerprint of the certificate used for signing jar
+func GetJARSignerFingerprint(jar string) (string, error) {
+	signature, err := GetJARCertificate(jar)
+	if err != nil {
+		return "", err
+	}
+	return GetCertificateFingerprint(signature)
+}
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// GetCertificateFingerprint returns SHA-256 fingerprint of the signer certificate
+// found in PKCS#7 signature block (META-INF/*.RSA or META-INF/*.DSA) in the same format as keytool does,
+// e.g. 3B:4A:...:0F
+func GetCertificateFingerprint(signature []byte) (string, error) {
+	var contentInfo pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(signature, &contentInfo); err != nil {
+		return "", errors.Wrap(err, "unable to parse signature block")
+	}
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return "", errors.Wrap(err, "unable to parse signed data")
+	}
+	certificates, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to parse certificates")
+	}
+	signer := findLeafCertificate(certificates)
+	if signer == nil {
+		return "", errors.New("no certificates found in signature block")
+	}
+	return formatFingerprint(sha256.Sum256(signer.Raw)), nil
+}
+
+// findLeafCertificate returns the certificate which is not an issuer of any other certificate in the chain
+func findLeafCertificate(certificates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range certificates {
+		isIssuer := false
+		for _, certificate := range certificates {
+			if certificate != candidate && bytes.Equal(certificate.RawIssuer, candidate.RawSubject) {
+				isIssuer = true
+				break
+			}
+		}
+		if !isIssuer {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func formatFingerprint(sum [sha256.Size]byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// NormalizeFingerprint converts fingerprint to the format returned by GetCertificateFingerprint
+// so fingerprints copied from different tools can be compared
+func NormalizeFingerprint(fingerprint string) string {
+	hex := strings.ToUpper(fingerprint)
+	hex = strings.NewReplacer(":", "", " ", "", "-", "").Replace(hex)
+	hex = strings.TrimPrefix(hex, "SHA256")
+	var parts []string
+	for i := 0; i+2 <= len(hex); i += 2 {
+		parts = append(parts, hex[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}